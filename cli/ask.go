@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	OutputFormatTable = "table"
+	OutputFormatJson  = "json"
+)
+
+type askResult struct {
+	Dashboards []grafana.Dashboard `json:"dashboards"`
+}
+
+/*
+RunAsk 命令行模式，在本地进程中完成 Grafana 看板列表和大模型匹配，并打印匹配的看板。
+只需要设置 Grafana 和大模型相关的环境变量，不需要如流机器人的配置，例如：
+
+	grafana-copilot ask "kafka lag for payments"
+	grafana-copilot ask -format json "kafka lag for payments" | jq -r '.dashboards[].url'
+*/
+func RunAsk(args []string, stdout, stderr io.Writer) (exitCode int) {
+	flagSet := flag.NewFlagSet("ask", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	var format string
+	var timeout time.Duration
+	flagSet.StringVar(&format, "format", OutputFormatTable, "The output format, table or json")
+	flagSet.DurationVar(&timeout, "timeout", time.Second*60, "The timeout of the dashboard matching")
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: grafana-copilot ask [options] <question>\n\nOptions:\n")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	question := strings.TrimSpace(strings.Join(flagSet.Args(), " "))
+	if question == "" {
+		flagSet.Usage()
+		return 2
+	}
+	if format != OutputFormatTable && format != OutputFormatJson {
+		_, _ = fmt.Fprintf(stderr, "unsupported format %q\n", format)
+		return 2
+	}
+	// only the grafana and llm envs are required
+	conf.MustParseCopilotConfigFromEnvs()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	dashboards, err := chatbot.MatchDashboards(ctx, question)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "match dashboards err: %v\n", err)
+		return 1
+	}
+	if format == OutputFormatJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(&askResult{Dashboards: dashboards}); err != nil {
+			_, _ = fmt.Fprintf(stderr, "encode result err: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(dashboards) == 0 {
+		_, _ = fmt.Fprintln(stderr, "没有找到匹配的仪表盘，请尝试其他问题")
+		return 1
+	}
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "UID\tTITLE\tURL\tREASON")
+	for _, dashboard := range dashboards {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", dashboard.Key(), dashboard.Title, dashboard.URL, dashboard.Reason)
+	}
+	_ = writer.Flush()
+	return 0
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 var AppConfig *Config
@@ -24,30 +26,299 @@ type Config struct {
 	OpenAIAPIKey                string `json:"OPENAI_API_KEY"`
 	OpenAIAPIBase               string `json:"OPENAI_API_BASE"`
 	OpenAIModel                 string `json:"OPENAI_MODEL"`
+	// Slack adapter is enabled only when SlackSigningSecret is set
+	SlackSigningSecret string `json:"SLACK_SIGNING_SECRET"`
+	SlackBotToken      string `json:"SLACK_BOT_TOKEN"`
+	// SlackAPIBase is default to https://slack.com/api, override it to test against a fake slack web api
+	SlackAPIBase string `json:"SLACK_API_BASE"`
+	// Feishu adapter is enabled only when FeishuAppId is set
+	FeishuAppId             string `json:"FEISHU_APP_ID"`
+	FeishuAppSecret         string `json:"FEISHU_APP_SECRET"`
+	FeishuVerificationToken string `json:"FEISHU_VERIFICATION_TOKEN"`
+	// FeishuEncryptKey is optional, events are sent in plain text if not set in the developer console
+	FeishuEncryptKey string `json:"FEISHU_ENCRYPT_KEY"`
+	// FeishuAPIBase is default to https://open.feishu.cn, use https://open.larksuite.com for lark
+	FeishuAPIBase string `json:"FEISHU_API_BASE"`
+	// DingTalk adapter is enabled only when DingtalkRobotAppSecret is set
+	DingtalkRobotAppSecret string `json:"DINGTALK_ROBOT_APP_SECRET"`
+	// WeCom adapter is enabled only when WecomRobotToken is set
+	WecomRobotToken          string `json:"WECOM_ROBOT_TOKEN"`
+	WecomRobotEncodingAESKey string `json:"WECOM_ROBOT_ENCODING_AES_KEY"`
+	// Teams adapter is enabled only when TeamsAppId is set
+	TeamsAppId       string `json:"TEAMS_APP_ID"`
+	TeamsAppPassword string `json:"TEAMS_APP_PASSWORD"`
+	// TeamsJWKSURL and TeamsTokenURL can be pointed to a local stand-in in tests
+	TeamsJWKSURL  string `json:"TEAMS_JWKS_URL"`
+	TeamsTokenURL string `json:"TEAMS_TOKEN_URL"`
+	// TeamsServiceHosts is a comma separated list of the bot connector service hosts which the replies are
+	// sent to, the leading `*.` matches any subdomain
+	TeamsServiceHosts string `json:"TEAMS_SERVICE_HOSTS"`
+	// Mattermost adapter is enabled only when MattermostCommandToken is set
+	MattermostCommandToken string `json:"MATTERMOST_COMMAND_TOKEN"`
+	// Rocket.Chat adapter is enabled only when RocketchatWebhookToken is set
+	RocketchatWebhookToken string `json:"ROCKETCHAT_WEBHOOK_TOKEN"`
+	// RocketchatIncomingWebhookURL is used to post the deferred replies
+	RocketchatIncomingWebhookURL string `json:"ROCKETCHAT_INCOMING_WEBHOOK_URL"`
+	// CopilotAPIKeys is a comma separated list of keys to access the query api, the api is enabled only when set
+	CopilotAPIKeys string `json:"COPILOT_API_KEYS"`
+	// CopilotPromptsDir is the directory of the prompt templates, default to `prompts` in the working directory
+	CopilotPromptsDir string `json:"COPILOT_PROMPTS_DIR"`
+	// OpenAIEmbeddingModel enables the embedding based retrieval of the dashboards when set
+	OpenAIEmbeddingModel string `json:"OPENAI_EMBEDDING_MODEL"`
+	// CopilotIndexPath is the file to persist the dashboard vector index
+	CopilotIndexPath string `json:"COPILOT_INDEX_PATH"`
+	// CopilotRetrievalTopK is the number of candidates retrieved from the index and sent to the llm
+	CopilotRetrievalTopK int `json:"COPILOT_RETRIEVAL_TOP_K,string"`
+	// CopilotIndexRefreshInterval is the interval in seconds to rebuild the index incrementally
+	CopilotIndexRefreshInterval int `json:"COPILOT_INDEX_REFRESH_INTERVAL,string"`
+	// CopilotSearchMode is `llm` by default, `lexical` matches the dashboards by keywords without any llm
+	CopilotSearchMode string `json:"COPILOT_SEARCH_MODE"`
+	// CopilotLLMTimeout is the timeout in seconds of each llm call, the keyword matching is used if it times out.
+	// The messages without slash command share one timeout across the intent classification and the matching.
+	CopilotLLMTimeout int `json:"COPILOT_LLM_TIMEOUT,string"`
+	// CopilotCatalogRefreshInterval is the interval in seconds to refresh the dashboard catalog cache of
+	// the http server, 0 disables the cache and the dashboards are listed for every user message
+	CopilotCatalogRefreshInterval int `json:"COPILOT_CATALOG_REFRESH_INTERVAL,string"`
+	// GrafanaInstanceNames is a comma separated list of the grafana instances, e.g. prod,staging. When set,
+	// each instance is configured by GRAFANA_<NAME>_HOST, GRAFANA_<NAME>_TOKEN, GRAFANA_<NAME>_BASE_URL,
+	// GRAFANA_<NAME>_ALIASES and GRAFANA_<NAME>_ORGS instead of GRAFANA_HOST, GRAFANA_TOKEN, GRAFANA_BASE_URL
+	// and GRAFANA_ORGS.
+	GrafanaInstanceNames string `json:"GRAFANA_INSTANCES"`
+	// GrafanaInstances is parsed from the envs of the single or the named grafana instances
+	GrafanaInstances []GrafanaInstance `json:"-"`
+	// GrafanaWebhookToken enables the grafana alert webhook when set, it should be configured as the
+	// authorization credentials or the basic auth password of the grafana webhook contact point
+	GrafanaWebhookToken string `json:"GRAFANA_WEBHOOK_TOKEN"`
+	// InfoflowAlertGroupIds is a comma separated list of the infoflow groups to post the alert notifications
+	InfoflowAlertGroupIds string `json:"INFOFLOW_ALERT_GROUP_IDS"`
+	// CopilotRenderWidth and CopilotRenderHeight are the size in pixels of the rendered panel snapshots
+	CopilotRenderWidth  int `json:"COPILOT_RENDER_WIDTH,string"`
+	CopilotRenderHeight int `json:"COPILOT_RENDER_HEIGHT,string"`
 }
 
+// GrafanaInstance is one of the grafana backends, the name is empty for the single instance
+type GrafanaInstance struct {
+	Name    string
+	Host    string
+	BaseURL string
+	Token   string
+	// Aliases are the other words to mention the instance in the user input, e.g. 生产 for prod
+	Aliases []string
+	// Orgs are the orgs to search, only the default org of the token is searched if empty
+	Orgs []GrafanaOrg
+}
+
+// GrafanaOrg is an org of the grafana instance, the instance token is used with the
+// X-Grafana-Org-Id header to switch to the org if the org token is empty.
+type GrafanaOrg struct {
+	Id    int
+	Token string
+}
+
+const (
+	SearchModeLLM     = "llm"
+	SearchModeLexical = "lexical"
+)
+
+// IsLexicalMode checks whether the llm is disabled, e.g. in the air-gapped deployments
+func (c *Config) IsLexicalMode() bool {
+	return c.CopilotSearchMode == SearchModeLexical
+}
+
+// GetAPIKeys returns the non-empty keys of CopilotAPIKeys
+func (c *Config) GetAPIKeys() (apiKeys []string) {
+	for _, apiKey := range strings.Split(c.CopilotAPIKeys, ",") {
+		if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	return
+}
+
+// GetTeamsServiceHosts returns the allowed hosts of the bot connector service urls
+func (c *Config) GetTeamsServiceHosts() []string {
+	return splitList(c.TeamsServiceHosts)
+}
+
+// GetInfoflowAlertGroupIds returns the infoflow groups to post the alert notifications
+func (c *Config) GetInfoflowAlertGroupIds() (groupIds []int) {
+	for _, item := range splitList(c.InfoflowAlertGroupIds) {
+		if groupId, err := strconv.Atoi(item); err == nil {
+			groupIds = append(groupIds, groupId)
+		}
+	}
+	return
+}
+
+// MustParseConfigFromEnvs parses the config of the http server, the infoflow robot envs are required
 func MustParseConfigFromEnvs() {
-	appConfigMap := make(map[string]string)
-	ensureEnv(&appConfigMap, "GRAFANA_HOST")
-	ensureEnv(&appConfigMap, "GRAFANA_TOKEN")
+	appConfigMap := parseCopilotEnvs()
 	ensureEnv(&appConfigMap, "INFOFLOW_ROBOT_WEBHOOK_ADDRESS")
 	ensureEnv(&appConfigMap, "INFOFLOW_ROBOT_TOKEN")
 	ensureEnv(&appConfigMap, "INFOFLOW_ROBOT_ENCODING_AES_KEY")
-	ensureEnv(&appConfigMap, "OPENAI_API_KEY")
-	ensureEnv(&appConfigMap, "OPENAI_API_BASE")
-	ensureEnv(&appConfigMap, "OPENAI_MODEL")
-	// check grafana base url
-	if os.Getenv("GRAFANA_BASE_URL") != "" {
-		appConfigMap["GRAFANA_BASE_URL"] = os.Getenv("GRAFANA_BASE_URL")
+	// optional chat adapters
+	optionalEnv(&appConfigMap, "SLACK_SIGNING_SECRET", "")
+	if appConfigMap["SLACK_SIGNING_SECRET"] != "" {
+		ensureEnv(&appConfigMap, "SLACK_BOT_TOKEN")
+		optionalEnv(&appConfigMap, "SLACK_API_BASE", "https://slack.com/api")
+	}
+	optionalEnv(&appConfigMap, "FEISHU_APP_ID", "")
+	if appConfigMap["FEISHU_APP_ID"] != "" {
+		ensureEnv(&appConfigMap, "FEISHU_APP_SECRET")
+		ensureEnv(&appConfigMap, "FEISHU_VERIFICATION_TOKEN")
+		optionalEnv(&appConfigMap, "FEISHU_ENCRYPT_KEY", "")
+		optionalEnv(&appConfigMap, "FEISHU_API_BASE", "https://open.feishu.cn")
+	}
+	optionalEnv(&appConfigMap, "DINGTALK_ROBOT_APP_SECRET", "")
+	optionalEnv(&appConfigMap, "WECOM_ROBOT_TOKEN", "")
+	if appConfigMap["WECOM_ROBOT_TOKEN"] != "" {
+		ensureEnv(&appConfigMap, "WECOM_ROBOT_ENCODING_AES_KEY")
+	}
+	optionalEnv(&appConfigMap, "TEAMS_APP_ID", "")
+	if appConfigMap["TEAMS_APP_ID"] != "" {
+		ensureEnv(&appConfigMap, "TEAMS_APP_PASSWORD")
+		optionalEnv(&appConfigMap, "TEAMS_JWKS_URL", "https://login.botframework.com/v1/.well-known/keys")
+		optionalEnv(&appConfigMap, "TEAMS_TOKEN_URL", "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token")
+		optionalEnv(&appConfigMap, "TEAMS_SERVICE_HOSTS", "smba.trafficmanager.net,smba.infra.gcc.teams.microsoft.com,*.botframework.com")
+	}
+	optionalEnv(&appConfigMap, "MATTERMOST_COMMAND_TOKEN", "")
+	optionalEnv(&appConfigMap, "ROCKETCHAT_WEBHOOK_TOKEN", "")
+	if appConfigMap["ROCKETCHAT_WEBHOOK_TOKEN"] != "" {
+		ensureEnv(&appConfigMap, "ROCKETCHAT_INCOMING_WEBHOOK_URL")
+	}
+	optionalEnv(&appConfigMap, "COPILOT_API_KEYS", "")
+	optionalIntEnv(&appConfigMap, "COPILOT_CATALOG_REFRESH_INTERVAL", 300)
+	optionalEnv(&appConfigMap, "GRAFANA_WEBHOOK_TOKEN", "")
+	if appConfigMap["GRAFANA_WEBHOOK_TOKEN"] != "" {
+		ensureEnv(&appConfigMap, "INFOFLOW_ALERT_GROUP_IDS")
+		for _, groupId := range splitList(appConfigMap["INFOFLOW_ALERT_GROUP_IDS"]) {
+			if _, err := strconv.Atoi(groupId); err != nil {
+				panic("Environment variable `INFOFLOW_ALERT_GROUP_IDS` should be a list of group ids")
+			}
+		}
+	}
+	optionalIntEnv(&appConfigMap, "COPILOT_RENDER_WIDTH", 1000)
+	optionalIntEnv(&appConfigMap, "COPILOT_RENDER_HEIGHT", 500)
+	setAppConfig(appConfigMap)
+}
+
+// MustParseCopilotConfigFromEnvs parses only the grafana and llm config, which is used by
+// the command line client without any chat robot.
+func MustParseCopilotConfigFromEnvs() {
+	setAppConfig(parseCopilotEnvs())
+}
+
+func parseCopilotEnvs() map[string]string {
+	appConfigMap := make(map[string]string)
+	optionalEnv(&appConfigMap, "GRAFANA_INSTANCES", "")
+	if appConfigMap["GRAFANA_INSTANCES"] == "" {
+		ensureEnv(&appConfigMap, "GRAFANA_HOST")
+		ensureEnv(&appConfigMap, "GRAFANA_TOKEN")
+		optionalEnv(&appConfigMap, "GRAFANA_BASE_URL", appConfigMap["GRAFANA_HOST"])
+		parseGrafanaOrgEnvs(&appConfigMap, getGrafanaInstanceEnvPrefix(""))
 	} else {
-		appConfigMap["GRAFANA_BASE_URL"] = appConfigMap["GRAFANA_HOST"]
+		for _, name := range splitList(appConfigMap["GRAFANA_INSTANCES"]) {
+			envPrefix := getGrafanaInstanceEnvPrefix(name)
+			ensureEnv(&appConfigMap, envPrefix+"_HOST")
+			ensureEnv(&appConfigMap, envPrefix+"_TOKEN")
+			optionalEnv(&appConfigMap, envPrefix+"_BASE_URL", appConfigMap[envPrefix+"_HOST"])
+			optionalEnv(&appConfigMap, envPrefix+"_ALIASES", "")
+			parseGrafanaOrgEnvs(&appConfigMap, envPrefix)
+		}
+	}
+	optionalEnv(&appConfigMap, "COPILOT_SEARCH_MODE", SearchModeLLM)
+	switch appConfigMap["COPILOT_SEARCH_MODE"] {
+	case SearchModeLLM:
+		ensureEnv(&appConfigMap, "OPENAI_API_KEY")
+		ensureEnv(&appConfigMap, "OPENAI_API_BASE")
+		ensureEnv(&appConfigMap, "OPENAI_MODEL")
+		optionalEnv(&appConfigMap, "OPENAI_EMBEDDING_MODEL", "")
+		optionalIntEnv(&appConfigMap, "COPILOT_LLM_TIMEOUT", 30)
+	case SearchModeLexical:
+		// no llm is needed in the air-gapped deployments
+	default:
+		panic(fmt.Sprintf("Environment variable `COPILOT_SEARCH_MODE` should be `%s` or `%s`", SearchModeLLM, SearchModeLexical))
 	}
+	optionalEnv(&appConfigMap, "COPILOT_PROMPTS_DIR", "prompts")
+	optionalEnv(&appConfigMap, "COPILOT_INDEX_PATH", "data/dashboard_index.json")
+	optionalIntEnv(&appConfigMap, "COPILOT_RETRIEVAL_TOP_K", 50)
+	optionalIntEnv(&appConfigMap, "COPILOT_INDEX_REFRESH_INTERVAL", 3600)
+	return appConfigMap
+}
+
+func setAppConfig(appConfigMap map[string]string) {
 	appConfigData, _ := json.Marshal(appConfigMap)
 	var res Config
 	_ = json.Unmarshal(appConfigData, &res)
+	res.GrafanaInstances = parseGrafanaInstances(appConfigMap)
 	AppConfig = &res
 }
 
+func parseGrafanaInstances(appConfigMap map[string]string) (instances []GrafanaInstance) {
+	if appConfigMap["GRAFANA_INSTANCES"] == "" {
+		return []GrafanaInstance{{
+			Host:    appConfigMap["GRAFANA_HOST"],
+			BaseURL: appConfigMap["GRAFANA_BASE_URL"],
+			Token:   appConfigMap["GRAFANA_TOKEN"],
+			Orgs:    parseGrafanaOrgs(appConfigMap, getGrafanaInstanceEnvPrefix("")),
+		}}
+	}
+	for _, name := range splitList(appConfigMap["GRAFANA_INSTANCES"]) {
+		envPrefix := getGrafanaInstanceEnvPrefix(name)
+		instances = append(instances, GrafanaInstance{
+			Name:    name,
+			Host:    appConfigMap[envPrefix+"_HOST"],
+			BaseURL: appConfigMap[envPrefix+"_BASE_URL"],
+			Token:   appConfigMap[envPrefix+"_TOKEN"],
+			Aliases: splitList(appConfigMap[envPrefix+"_ALIASES"]),
+			Orgs:    parseGrafanaOrgs(appConfigMap, envPrefix),
+		})
+	}
+	return
+}
+
+// parseGrafanaOrgEnvs checks the org ids and reads the optional token of each org, e.g. GRAFANA_ORGS=1,2
+// and GRAFANA_ORG_2_TOKEN, since a service account token only sees the org which it belongs to.
+func parseGrafanaOrgEnvs(appConfigMap *map[string]string, envPrefix string) {
+	optionalEnv(appConfigMap, envPrefix+"_ORGS", "")
+	for _, orgId := range splitList((*appConfigMap)[envPrefix+"_ORGS"]) {
+		if _, err := strconv.Atoi(orgId); err != nil {
+			panic(fmt.Sprintf("Environment variable `%s_ORGS` should be a list of org ids", envPrefix))
+		}
+		optionalEnv(appConfigMap, fmt.Sprintf("%s_ORG_%s_TOKEN", envPrefix, orgId), "")
+	}
+}
+
+func parseGrafanaOrgs(appConfigMap map[string]string, envPrefix string) (orgs []GrafanaOrg) {
+	for _, orgId := range splitList(appConfigMap[envPrefix+"_ORGS"]) {
+		id, _ := strconv.Atoi(orgId)
+		orgs = append(orgs, GrafanaOrg{
+			Id:    id,
+			Token: appConfigMap[fmt.Sprintf("%s_ORG_%s_TOKEN", envPrefix, orgId)],
+		})
+	}
+	return
+}
+
+// getGrafanaInstanceEnvPrefix returns the env prefix of the instance, e.g. GRAFANA_US_EAST for us-east,
+// and GRAFANA for the single instance
+func getGrafanaInstanceEnvPrefix(name string) string {
+	if name == "" {
+		return "GRAFANA"
+	}
+	return fmt.Sprintf("GRAFANA_%s", strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+}
+
+// splitList splits the comma separated list and drops the empty items
+func splitList(value string) (items []string) {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
 func ensureEnv(appConfigMap *map[string]string, key string) {
 	value := os.Getenv(key)
 	if value == "" {
@@ -55,3 +326,21 @@ func ensureEnv(appConfigMap *map[string]string, key string) {
 	}
 	(*appConfigMap)[key] = value
 }
+
+func optionalEnv(appConfigMap *map[string]string, key, defaultValue string) {
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+	(*appConfigMap)[key] = value
+}
+
+func optionalIntEnv(appConfigMap *map[string]string, key string, defaultValue int) {
+	value := os.Getenv(key)
+	if value == "" {
+		value = strconv.Itoa(defaultValue)
+	} else if _, err := strconv.Atoi(value); err != nil {
+		panic(fmt.Sprintf("Environment variable `%s` should be an integer", key))
+	}
+	(*appConfigMap)[key] = value
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"net/http"
+)
+
+type CatalogRefreshResponse struct {
+	grafana.CatalogStatus
+	Changes grafana.CatalogChanges `json:"changes"`
+}
+
+/*
+ManageCatalog 看板目录缓存管理接口，使用与查询接口相同的 api key 鉴权。
+查看缓存状态：GET /api/v1/admin/catalog，响应体：{"size": 4000, "version": 3, "refreshedAt": "...", "ageSeconds": 120, "lastChanges": {...}}
+强制刷新缓存：POST /api/v1/admin/catalog，响应体在缓存状态的基础上增加本次刷新的变化 {"changes": {"added": [...], "removed": [...], "renamed": [...]}}
+*/
+func ManageCatalog(resp http.ResponseWriter, req *http.Request) {
+	if !checkAPIKey(req) {
+		writeJSON(resp, http.StatusUnauthorized, &ErrorResponse{Error: "invalid api key"})
+		return
+	}
+	catalog := grafana.GetCatalog()
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(resp, http.StatusOK, catalog.Status())
+	case http.MethodPost:
+		changes, err := catalog.Refresh()
+		if err != nil {
+			slog.Error(fmt.Sprintf("refresh dashboard catalog err: %v", err))
+			writeJSON(resp, http.StatusBadGateway, &ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(resp, http.StatusOK, &CatalogRefreshResponse{
+			CatalogStatus: catalog.Status(),
+			Changes:       changes,
+		})
+	default:
+		writeJSON(resp, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+	}
+}
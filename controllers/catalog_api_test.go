@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const testCopilotAPIKey = "test-api-key"
+
+// fakeGrafanaSearch serves the dashboards of the first search page, all the requests fail if the dashboards are nil.
+// The server is shared by the whole package because the grafana clients are created once with the first config.
+var fakeGrafanaSearch struct {
+	once       sync.Once
+	lock       sync.Mutex
+	server     *httptest.Server
+	dashboards []grafana.Dashboard
+}
+
+func setFakeGrafanaDashboards(dashboards []grafana.Dashboard) {
+	fakeGrafanaSearch.once.Do(func() {
+		fakeGrafanaSearch.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fakeGrafanaSearch.lock.Lock()
+			defer fakeGrafanaSearch.lock.Unlock()
+			if fakeGrafanaSearch.dashboards == nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dashboardList := make([]grafana.Dashboard, 0)
+			if r.URL.Query().Get("page") == "1" {
+				dashboardList = fakeGrafanaSearch.dashboards
+			}
+			_ = json.NewEncoder(w).Encode(dashboardList)
+		}))
+	})
+	fakeGrafanaSearch.lock.Lock()
+	fakeGrafanaSearch.dashboards = dashboards
+	fakeGrafanaSearch.lock.Unlock()
+	conf.AppConfig = &conf.Config{
+		CopilotAPIKeys:   testCopilotAPIKey,
+		GrafanaInstances: []conf.GrafanaInstance{{Host: fakeGrafanaSearch.server.URL}},
+	}
+}
+
+func newCatalogRequest(method, apiKey string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/admin/catalog", nil)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	return req
+}
+
+func TestManageCatalogAuth(t *testing.T) {
+	setFakeGrafanaDashboards(nil)
+	testCases := []struct {
+		name       string
+		method     string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "missing api key", method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "wrong api key", method: http.MethodPost, apiKey: "other-key", wantStatus: http.StatusUnauthorized},
+		{name: "method not allowed", method: http.MethodDelete, apiKey: testCopilotAPIKey, wantStatus: http.StatusMethodNotAllowed},
+		{name: "status", method: http.MethodGet, apiKey: testCopilotAPIKey, wantStatus: http.StatusOK},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			ManageCatalog(resp, newCatalogRequest(testCase.method, testCase.apiKey))
+			if resp.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+		})
+	}
+}
+
+func TestManageCatalogRefresh(t *testing.T) {
+	setFakeGrafanaDashboards(nil)
+	resp := httptest.NewRecorder()
+	ManageCatalog(resp, newCatalogRequest(http.MethodPost, testCopilotAPIKey))
+	if resp.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d when grafana fails", resp.Code, http.StatusBadGateway)
+	}
+
+	setFakeGrafanaDashboards([]grafana.Dashboard{
+		{Uid: "order", Title: "Order", URL: "/d/order/order"},
+		{Uid: "pay", Title: "Pay", URL: "/d/pay/pay"},
+	})
+	resp = httptest.NewRecorder()
+	ManageCatalog(resp, newCatalogRequest(http.MethodPost, testCopilotAPIKey))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+	}
+	var refreshResp CatalogRefreshResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &refreshResp); err != nil {
+		t.Fatalf("parse response err: %v", err)
+	}
+	if refreshResp.Size != 2 || !reflect.DeepEqual(refreshResp.Changes.Added, []string{"order", "pay"}) {
+		t.Fatalf("response = %+v, want the added dashboards", refreshResp)
+	}
+	version := refreshResp.Version
+
+	setFakeGrafanaDashboards([]grafana.Dashboard{{Uid: "order", Title: "Orders", URL: "/d/order/orders"}})
+	resp = httptest.NewRecorder()
+	ManageCatalog(resp, newCatalogRequest(http.MethodPost, testCopilotAPIKey))
+	refreshResp = CatalogRefreshResponse{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &refreshResp); err != nil {
+		t.Fatalf("parse response err: %v", err)
+	}
+	wantChanges := grafana.CatalogChanges{Removed: []string{"pay"}, Renamed: []string{"order"}}
+	if refreshResp.Version != version+1 || refreshResp.Size != 1 || !reflect.DeepEqual(refreshResp.Changes, wantChanges) {
+		t.Errorf("response = %+v, want version %d and changes %+v", refreshResp, version+1, wantChanges)
+	}
+
+	resp = httptest.NewRecorder()
+	ManageCatalog(resp, newCatalogRequest(http.MethodGet, testCopilotAPIKey))
+	var status grafana.CatalogStatus
+	if err := json.Unmarshal(resp.Body.Bytes(), &status); err != nil {
+		t.Fatalf("parse response err: %v", err)
+	}
+	if status.Version != version+1 || status.Size != 1 || !reflect.DeepEqual(status.LastChanges, wantChanges) {
+		t.Errorf("status = %+v, want the last refresh", status)
+	}
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/dingtalk"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+/*
+ReceiveDingtalkRobotMessage 钉钉机器人消息回调接口，参考文档：https://open.dingtalk.com/document/orgapp/receive-message
+请求头中的 timestamp 和 sign 需要使用机器人的 AppSecret 进行 HmacSHA256 签名校验，同一个签名只能使用一次，
+消息内容通过 body 传递，回复消息通过 body 中的 sessionWebhook 发送。
+由于签名不覆盖 body，sessionWebhook 必须是钉钉域名下未过期的 https 地址。
+*/
+func ReceiveDingtalkRobotMessage(resp http.ResponseWriter, req *http.Request) {
+	timestamp := req.Header.Get("timestamp")
+	signature := req.Header.Get("sign")
+	err := dingtalk.VerifyDingtalkSignature(timestamp, signature, conf.AppConfig.DingtalkRobotAppSecret, time.Now())
+	if err != nil {
+		slog.Error(fmt.Sprintf("verify dingtalk signature err: %v", err))
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	msgBodyBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("read body err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	slog.Debug(fmt.Sprintf("src message: %s", string(msgBodyBytes)))
+	var callbackBody dingtalk.CallbackBody
+	err = json.Unmarshal(msgBodyBytes, &callbackBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse src message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	err = dingtalk.VerifySessionWebhook(callbackBody.SessionWebhook, callbackBody.SessionWebhookExpiredTime, time.Now())
+	if err != nil {
+		slog.Error(fmt.Sprintf("verify session webhook err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if callbackBody.MsgType == dingtalk.MessageTypeText {
+		userMessage := dingtalk.NewUserMessage(&callbackBody, dingtalk.NewClient(&dingtalk.Config{}))
+		// run the async process and notify user when finished
+		go chatbot.HandleUserInput(userMessage)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
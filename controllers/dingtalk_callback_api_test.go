@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/dingtalk"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiveDingtalkRobotMessage(t *testing.T) {
+	conf.AppConfig = &conf.Config{DingtalkRobotAppSecret: "test-app-secret"}
+	expiredTime := time.Now().Add(time.Hour).UnixMilli()
+	// the picture message is not handled so that no reply is sent
+	createBody := func(sessionWebhook string, expiredTime int64) string {
+		return fmt.Sprintf(`{"msgtype":"picture","senderStaffId":"manager","sessionWebhook":%q,"sessionWebhookExpiredTime":%d}`,
+			sessionWebhook, expiredTime)
+	}
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "dingtalk session webhook", body: createBody("https://oapi.dingtalk.com/robot/sendBySession?session=xxx", expiredTime), wantStatus: http.StatusOK},
+		{name: "other session webhook", body: createBody("http://127.0.0.1:8080/internal", expiredTime), wantStatus: http.StatusBadRequest},
+		{name: "expired session webhook", body: createBody("https://oapi.dingtalk.com/robot/sendBySession?session=xxx", time.Now().Add(-time.Minute).UnixMilli()), wantStatus: http.StatusBadRequest},
+	}
+	for index, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// each request is signed with a different timestamp because the signature can be used only once
+			timestamp := strconv.FormatInt(time.Now().Add(-time.Duration(index)*time.Minute).UnixMilli(), 10)
+			req := httptest.NewRequest(http.MethodPost, "/api/chatbot/dingtalk-robot-callback", strings.NewReader(testCase.body))
+			req.Header.Set("timestamp", timestamp)
+			req.Header.Set("sign", dingtalk.CalcDingtalkSignature(timestamp, conf.AppConfig.DingtalkRobotAppSecret))
+			resp := httptest.NewRecorder()
+			ReceiveDingtalkRobotMessage(resp, req)
+			if resp.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+			// the replayed request is rejected
+			req = httptest.NewRequest(http.MethodPost, "/api/chatbot/dingtalk-robot-callback", strings.NewReader(testCase.body))
+			req.Header.Set("timestamp", timestamp)
+			req.Header.Set("sign", dingtalk.CalcDingtalkSignature(timestamp, conf.AppConfig.DingtalkRobotAppSecret))
+			resp = httptest.NewRecorder()
+			ReceiveDingtalkRobotMessage(resp, req)
+			if resp.Code != http.StatusUnauthorized {
+				t.Errorf("replayed status = %d, want %d", resp.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/feishu"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var feishuClient *feishu.Client
+var feishuClientOnce sync.Once
+
+/*
+ReceiveFeishuMessage 飞书机器人事件回调接口，参考文档：https://open.feishu.cn/document/server-docs/event-subscription-guide/overview
+此接口会接收到两种类型的请求，一种是 url_verification 验证请求，另一种是 im.message.receive_v1 消息事件请求。
+1. 如果配置了 Encrypt Key，请求体必须为 {"encrypt": "..."}，需要通过 aes 解密后使用，明文请求会被拒绝；
+2. 如果配置了 Encrypt Key，除 url_verification 验证请求外都必须携带 X-Lark-Signature 签名，且 X-Lark-Request-Timestamp 与当前时间相差不能超过 5 分钟；
+3. 解密后的请求体中的 token 需要和 Verification Token 一致；
+*/
+func ReceiveFeishuMessage(resp http.ResponseWriter, req *http.Request) {
+	reqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("read body err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	encryptKey := conf.AppConfig.FeishuEncryptKey
+	signature := req.Header.Get("X-Lark-Signature")
+	if encryptKey != "" && signature != "" {
+		timestamp := req.Header.Get("X-Lark-Request-Timestamp")
+		nonce := req.Header.Get("X-Lark-Request-Nonce")
+		if err = feishu.VerifyFeishuSignature(timestamp, nonce, encryptKey, reqBody, signature, time.Now()); err != nil {
+			slog.Error(fmt.Sprintf("verify signature err: %v", err))
+			resp.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	srcMsgBytes := reqBody
+	if encryptKey != "" {
+		// the plain text body is rejected once the encrypt key is set
+		var encryptedBody feishu.EncryptedBody
+		if err = json.Unmarshal(reqBody, &encryptedBody); err != nil || encryptedBody.Encrypt == "" {
+			slog.Error("encrypted body required")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		srcMsgBytes, err = feishu.DecryptFeishuMessage(encryptedBody.Encrypt, encryptKey)
+		if err != nil {
+			slog.Error(fmt.Sprintf("decrypt message err: %v", err))
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	slog.Debug(fmt.Sprintf("src message: %s", string(srcMsgBytes)))
+	var callbackBody feishu.CallbackBody
+	err = json.Unmarshal(srcMsgBytes, &callbackBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse src message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(callbackBody.GetToken()), []byte(conf.AppConfig.FeishuVerificationToken)) != 1 {
+		slog.Error("verification token not match")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// only the url_verification request is sent without the signature
+	if encryptKey != "" && signature == "" && callbackBody.Type != feishu.EventTypeURLVerification {
+		slog.Error("signature required")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// 回调地址验证请求，原样返回 challenge
+	if callbackBody.Type == feishu.EventTypeURLVerification {
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(resp).Encode(map[string]string{"challenge": callbackBody.Challenge})
+		return
+	}
+	if callbackBody.Header.EventType == feishu.EventTypeMessageReceive {
+		userMessage := feishu.NewUserMessage(&callbackBody.Event, getFeishuClient())
+		// run the async process and notify user when finished
+		go chatbot.HandleUserInput(userMessage)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+// getFeishuClient returns the shared client so that the tenant access token is cached
+func getFeishuClient() *feishu.Client {
+	feishuClientOnce.Do(func() {
+		feishuClient = feishu.NewClient(&feishu.Config{
+			APIBase:   conf.AppConfig.FeishuAPIBase,
+			AppId:     conf.AppConfig.FeishuAppId,
+			AppSecret: conf.AppConfig.FeishuAppSecret,
+		})
+	})
+	return feishuClient
+}
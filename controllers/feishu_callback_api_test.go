@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/feishu"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testFeishuEncryptKey        = "test-encrypt-key"
+	testFeishuVerificationToken = "test-verification-token"
+)
+
+func encryptFeishuBody(t *testing.T, plainText string) string {
+	key := sha256.Sum256([]byte(testFeishuEncryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(plainText)
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(data, []byte(strings.Repeat(string(rune(padding)), padding))...)
+	iv := []byte("0123456789abcdef")
+	encryptedBytes := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encryptedBytes, data)
+	body, _ := json.Marshal(feishu.EncryptedBody{Encrypt: base64.StdEncoding.EncodeToString(append(iv, encryptedBytes...))})
+	return string(body)
+}
+
+func newFeishuRequest(body string, signed bool, signature string, timestamp time.Time) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/chatbot/feishu-callback", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req.Header.Set("X-Lark-Request-Timestamp", ts)
+		req.Header.Set("X-Lark-Request-Nonce", "nonce")
+		if signature == "" {
+			signature = feishu.CalcFeishuSignature(ts, "nonce", testFeishuEncryptKey, []byte(body))
+		}
+		req.Header.Set("X-Lark-Signature", signature)
+	}
+	return req
+}
+
+func TestReceiveFeishuMessage(t *testing.T) {
+	conf.AppConfig = &conf.Config{
+		FeishuAppId:             "cli_test",
+		FeishuVerificationToken: testFeishuVerificationToken,
+		FeishuEncryptKey:        testFeishuEncryptKey,
+	}
+	// the event type is not handled so that no reply is sent
+	event := `{"schema":"2.0","header":{"event_type":"im.chat.updated_v1","token":"` + testFeishuVerificationToken + `"},"event":{}}`
+	verification := `{"challenge":"ajls384kdjx98XX","token":"` + testFeishuVerificationToken + `","type":"url_verification"}`
+	testCases := []struct {
+		name      string
+		body      string
+		signed    bool
+		signature string
+		// timestamp is the request time offset from now
+		timestamp  time.Duration
+		wantStatus int
+	}{
+		{name: "signed encrypted event", body: encryptFeishuBody(t, event), signed: true, wantStatus: http.StatusOK},
+		{name: "unsigned encrypted event", body: encryptFeishuBody(t, event), wantStatus: http.StatusUnauthorized},
+		{name: "wrong signature", body: encryptFeishuBody(t, event), signed: true, signature: strings.Repeat("0", 64), wantStatus: http.StatusUnauthorized},
+		{name: "replayed request", body: encryptFeishuBody(t, event), signed: true, timestamp: -feishu.MaxRequestAge - time.Minute,
+			wantStatus: http.StatusUnauthorized},
+		{name: "plain text event", body: event, signed: true, wantStatus: http.StatusBadRequest},
+		{name: "unsigned plain text event", body: event, wantStatus: http.StatusBadRequest},
+		{name: "unsigned encrypted url verification", body: encryptFeishuBody(t, verification), wantStatus: http.StatusOK},
+		{name: "wrong verification token", body: encryptFeishuBody(t, strings.Replace(verification, testFeishuVerificationToken, "other", 1)), wantStatus: http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			ReceiveFeishuMessage(resp, newFeishuRequest(testCase.body, testCase.signed, testCase.signature, time.Now().Add(testCase.timestamp)))
+			if resp.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/infoflow"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxWebhookBodySize limits the body of the grafana alert notification
+	maxWebhookBodySize = 4 << 20
+	// maxPendingNotifications limits the notifications being enriched and sent concurrently
+	maxPendingNotifications = 8
+)
+
+// notificationLimiter bounds the background notifications, since each one matches the dashboards by the llm
+var notificationLimiter = make(chan struct{}, maxPendingNotifications)
+
+/*
+ReceiveGrafanaWebhook Grafana 告警通知接口，接收 Grafana webhook 类型联络点（contact point）的告警通知，
+参考文档：https://grafana.com/docs/grafana/latest/alerting/configure-notifications/manage-contact-points/integrations/webhook-notifier/
+请求：POST /api/v1/alerts/grafana-webhook，联络点的 Authorization Header 凭证或 Basic Auth 密码设置为 GRAFANA_WEBHOOK_TOKEN。
+告警通知会附带匹配到的相关看板，发送到 INFOFLOW_ALERT_GROUP_IDS 配置的如流群，也可以通过 ?groupIds=1,2 指定联络点对应的群。
+请求体超过 4MB 时返回 413；同时处理的告警通知过多时返回 503，由 Grafana 稍后重试。
+*/
+func ReceiveGrafanaWebhook(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeJSON(resp, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if !checkWebhookToken(req) {
+		writeJSON(resp, http.StatusUnauthorized, &ErrorResponse{Error: "invalid webhook token"})
+		return
+	}
+	groupIds := conf.AppConfig.GetInfoflowAlertGroupIds()
+	if groupIdsParam := req.URL.Query().Get("groupIds"); groupIdsParam != "" {
+		groupIds = nil
+		for _, item := range strings.Split(groupIdsParam, ",") {
+			groupId, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil {
+				writeJSON(resp, http.StatusBadRequest, &ErrorResponse{Error: fmt.Sprintf("invalid group id %s", item)})
+				return
+			}
+			groupIds = append(groupIds, groupId)
+		}
+	}
+	var payload grafana.WebhookPayload
+	if err := json.NewDecoder(http.MaxBytesReader(resp, req.Body, maxWebhookBodySize)).Decode(&payload); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSON(resp, http.StatusRequestEntityTooLarge, &ErrorResponse{Error: "request body too large"})
+			return
+		}
+		writeJSON(resp, http.StatusBadRequest, &ErrorResponse{Error: fmt.Sprintf("invalid request body, %s", err.Error())})
+		return
+	}
+	slog.Info(fmt.Sprintf("receive grafana alert notification, status: %s, alerts: %d, group key: %s",
+		payload.Status, len(payload.Alerts), payload.GroupKey))
+	select {
+	case notificationLimiter <- struct{}{}:
+	default:
+		slog.Error(fmt.Sprintf("too many pending alert notifications, reject the group key: %s", payload.GroupKey))
+		writeJSON(resp, http.StatusServiceUnavailable, &ErrorResponse{Error: "too many pending notifications"})
+		return
+	}
+	// reply grafana immediately since the dashboard matching is slow, grafana retries the timeout notifications
+	go func() {
+		defer func() {
+			<-notificationLimiter
+		}()
+		notifyGrafanaAlerts(&payload, groupIds)
+	}()
+	resp.WriteHeader(http.StatusOK)
+}
+
+func notifyGrafanaAlerts(payload *grafana.WebhookPayload, groupIds []int) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	chatbot.EnrichWebhookAlerts(ctx, payload)
+	client := infoflow.NewClient(&infoflow.Config{
+		WebhookAddress: conf.AppConfig.InfoflowRobotWebhookAddress,
+	})
+	_, err := client.SendMessage(infoflow.NewAlertNotificationMessage(groupIds, payload, chatbot.MaxNotifiedAlerts))
+	if err != nil {
+		slog.Error(fmt.Sprintf("send alert notification to infoflow error: %v", err))
+	}
+}
+
+// checkWebhookToken checks the token in the Authorization header or the basic auth password in constant time
+func checkWebhookToken(req *http.Request) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, _ = req.BasicAuth()
+	}
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(conf.AppConfig.GrafanaWebhookToken)) == 1
+}
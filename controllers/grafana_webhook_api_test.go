@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"github.com/jemygraw/grafana-copilot/conf"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReceiveGrafanaWebhook(t *testing.T) {
+	conf.AppConfig = &conf.Config{GrafanaWebhookToken: "webhook-token"}
+	testCases := []struct {
+		name string
+		// pending is the number of the notifications being processed
+		pending    int
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid token", token: "other", body: `{}`, wantStatus: http.StatusUnauthorized},
+		{name: "invalid body", token: "webhook-token", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "body too large", token: "webhook-token",
+			body:       `{"title": "` + strings.Repeat("a", maxWebhookBodySize) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "too many pending notifications", pending: maxPendingNotifications, token: "webhook-token",
+			body: `{"status": "resolved", "alerts": []}`, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for index := 0; index < testCase.pending; index++ {
+				notificationLimiter <- struct{}{}
+			}
+			defer func() {
+				for index := 0; index < testCase.pending; index++ {
+					<-notificationLimiter
+				}
+			}()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts/grafana-webhook", strings.NewReader(testCase.body))
+			req.Header.Set("Authorization", "Bearer "+testCase.token)
+			recorder := httptest.NewRecorder()
+			ReceiveGrafanaWebhook(recorder, req)
+			if recorder.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", recorder.Code, testCase.wantStatus, recorder.Body.String())
+			}
+		})
+	}
+}
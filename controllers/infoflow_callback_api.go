@@ -90,5 +90,8 @@ func handleMessage(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 	// run the async process and notify user when finished
-	go chatbot.HandleUserInput(&callbackBody)
+	client := infoflow.NewClient(&infoflow.Config{
+		WebhookAddress: conf.AppConfig.InfoflowRobotWebhookAddress,
+	})
+	go chatbot.HandleUserInput(infoflow.NewUserMessage(&callbackBody, client))
 }
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/mattermost"
+	"log/slog"
+	"net/http"
+)
+
+/*
+ReceiveMattermostCommand Mattermost 斜杠命令回调接口，参考文档：https://developers.mattermost.com/integrate/slash-commands/custom/
+参数通过 www-form-urlencoded 的 body 传递，其中的 token 需要和命令配置的 token 一致。
+由于大模型的调用较慢，此接口立即返回一个仅自己可见的提示，结果通过 response_url 延迟发送到频道中。
+*/
+func ReceiveMattermostCommand(resp http.ResponseWriter, req *http.Request) {
+	_ = req.ParseForm()
+	slashCommand := mattermost.SlashCommand{
+		Token:       req.Form.Get("token"),
+		TeamId:      req.Form.Get("team_id"),
+		TeamDomain:  req.Form.Get("team_domain"),
+		ChannelId:   req.Form.Get("channel_id"),
+		ChannelName: req.Form.Get("channel_name"),
+		UserId:      req.Form.Get("user_id"),
+		UserName:    req.Form.Get("user_name"),
+		Command:     req.Form.Get("command"),
+		Text:        req.Form.Get("text"),
+		ResponseURL: req.Form.Get("response_url"),
+		TriggerId:   req.Form.Get("trigger_id"),
+	}
+	if !mattermost.VerifyCommandToken(slashCommand.Token, conf.AppConfig.MattermostCommandToken) {
+		slog.Error("command token not match")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	slog.Debug(fmt.Sprintf("mattermost slash command: %s %s", slashCommand.Command, slashCommand.Text))
+	// run the async process and notify user when finished
+	go chatbot.HandleUserInput(mattermost.NewUserMessage(&slashCommand, mattermost.NewClient(&mattermost.Config{})))
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&mattermost.CommandResponse{
+		ResponseType: mattermost.ResponseTypeEphemeral,
+		Text:         "正在处理您的请求，请稍候...",
+	})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/mattermost"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testMattermostCommandToken = "test-command-token"
+
+func newMattermostRequest(token, responseURL string) *http.Request {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("command", "/help")
+	form.Set("user_id", "rbe4jx4fdbbfjd8shy6nbghqyr")
+	form.Set("user_name", "alice")
+	form.Set("channel_id", "fdsafdsafdsa")
+	form.Set("response_url", responseURL)
+	req := httptest.NewRequest(http.MethodPost, "/api/chatbot/mattermost-callback", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestReceiveMattermostCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "wrong token", token: "other-token", wantStatus: http.StatusUnauthorized},
+		{name: "missing token", token: "", wantStatus: http.StatusUnauthorized},
+		{name: "valid token", token: testMattermostCommandToken, wantStatus: http.StatusOK},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fake := newFakeWebhook(t, "ok")
+			conf.AppConfig = &conf.Config{MattermostCommandToken: testMattermostCommandToken}
+			resp := httptest.NewRecorder()
+			ReceiveMattermostCommand(resp, newMattermostRequest(testCase.token, fake.server.URL+"/hooks/commands/abcd"))
+			if resp.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+			if testCase.wantStatus != http.StatusOK {
+				fake.expectNoRequest(t)
+				return
+			}
+			// the immediate response is only visible to the user
+			var immediate mattermost.CommandResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &immediate); err != nil {
+				t.Fatalf("parse immediate response err: %v", err)
+			}
+			if immediate.ResponseType != mattermost.ResponseTypeEphemeral || immediate.Text == "" {
+				t.Errorf("unexpected immediate response %+v", immediate)
+			}
+			// the result is posted to the response_url later
+			req, body := fake.waitRequest(t)
+			if req.URL.Path != "/hooks/commands/abcd" {
+				t.Fatalf("reply posted to %s, want the response_url", req.URL.Path)
+			}
+			var deferred mattermost.CommandResponse
+			if err := json.Unmarshal(body, &deferred); err != nil {
+				t.Fatalf("parse deferred reply err: %v", err)
+			}
+			if deferred.ResponseType != mattermost.ResponseTypeInChannel || !strings.Contains(deferred.Text, "@alice") {
+				t.Errorf("unexpected deferred reply %+v", deferred)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// queryTimeout is the max time to wait for the dashboard matching, llm calls are slow
+const queryTimeout = time.Second * 60
+
+type QueryRequest struct {
+	Question string `json:"question"`
+}
+
+type QueryResponse struct {
+	Dashboards []grafana.Dashboard `json:"dashboards"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+/*
+QueryDashboards 看板查询接口，供脚本、运维手册和内部门户直接调用，无需经过聊天机器人。
+请求：POST /api/v1/query，请求头 Authorization: Bearer <api key> 或 X-API-Key: <api key>
+请求体：{"question": "kafka lag for payments"}
+响应体：{"dashboards": [{"uid": "...", "title": "...", "url": "...", "reason": "..."}]}
+*/
+func QueryDashboards(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeJSON(resp, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if !checkAPIKey(req) {
+		writeJSON(resp, http.StatusUnauthorized, &ErrorResponse{Error: "invalid api key"})
+		return
+	}
+	var queryReq QueryRequest
+	if err := json.NewDecoder(req.Body).Decode(&queryReq); err != nil {
+		writeJSON(resp, http.StatusBadRequest, &ErrorResponse{Error: fmt.Sprintf("invalid request body, %s", err.Error())})
+		return
+	}
+	question := strings.TrimSpace(queryReq.Question)
+	if question == "" {
+		writeJSON(resp, http.StatusBadRequest, &ErrorResponse{Error: "question is required"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), queryTimeout)
+	defer cancel()
+	dashboards, err := chatbot.MatchDashboards(ctx, question)
+	if err != nil {
+		slog.Error(fmt.Sprintf("match dashboards err: %v", err))
+		writeJSON(resp, http.StatusInternalServerError, &ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(resp, http.StatusOK, &QueryResponse{Dashboards: dashboards})
+}
+
+// checkAPIKey checks the api key in the Authorization or X-API-Key header in constant time
+func checkAPIKey(req *http.Request) bool {
+	apiKey := req.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey, _ = strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	}
+	if apiKey == "" {
+		return false
+	}
+	for _, validKey := range conf.AppConfig.GetAPIKeys() {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(validKey)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(resp http.ResponseWriter, statusCode int, body any) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(statusCode)
+	_ = json.NewEncoder(resp).Encode(body)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/rocketchat"
+	"log/slog"
+	"net/http"
+)
+
+/*
+ReceiveRocketchatMessage Rocket.Chat 出站 Webhook 回调接口，参考文档：https://docs.rocket.chat/use-rocket.chat/workspace-administration/integrations
+消息内容通过 json body 传递，其中的 token 需要和出站 Webhook 配置的 token 一致。
+由于大模型的调用较慢，此接口立即返回空响应，结果通过入站 Webhook 延迟发送到频道中。
+*/
+func ReceiveRocketchatMessage(resp http.ResponseWriter, req *http.Request) {
+	var callbackBody rocketchat.CallbackBody
+	err := json.NewDecoder(req.Body).Decode(&callbackBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse src message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !rocketchat.VerifyWebhookToken(callbackBody.Token, conf.AppConfig.RocketchatWebhookToken) {
+		slog.Error("webhook token not match")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// ignore the messages sent by bots, including the replies of the copilot itself
+	if !callbackBody.IsBot() {
+		client := rocketchat.NewClient(&rocketchat.Config{
+			IncomingWebhookURL: conf.AppConfig.RocketchatIncomingWebhookURL,
+		})
+		userMessage := rocketchat.NewUserMessage(&callbackBody, client)
+		// run the async process and notify user when finished
+		go chatbot.HandleUserInput(userMessage)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/rocketchat"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRocketchatWebhookToken = "test-webhook-token"
+
+// fakeWebhook records the replies posted to the incoming webhook or the response_url
+type fakeWebhook struct {
+	server   *httptest.Server
+	requests chan *http.Request
+	bodies   chan []byte
+}
+
+func newFakeWebhook(t *testing.T, response string) *fakeWebhook {
+	fake := &fakeWebhook{requests: make(chan *http.Request, 10), bodies: make(chan []byte, 10)}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fake.requests <- r
+		fake.bodies <- body
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// waitRequest waits for the async reply sent to the fake webhook
+func (f *fakeWebhook) waitRequest(t *testing.T) (*http.Request, []byte) {
+	select {
+	case req := <-f.requests:
+		return req, <-f.bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received by the fake webhook")
+		return nil, nil
+	}
+}
+
+// expectNoRequest checks that nothing is posted to the fake webhook in a while
+func (f *fakeWebhook) expectNoRequest(t *testing.T) {
+	select {
+	case req := <-f.requests:
+		t.Errorf("unexpected request %s", req.URL.Path)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func newFakeRocketchatWebhook(t *testing.T) *fakeWebhook {
+	fake := newFakeWebhook(t, `{"success":true}`)
+	conf.AppConfig = &conf.Config{
+		RocketchatWebhookToken:       testRocketchatWebhookToken,
+		RocketchatIncomingWebhookURL: fake.server.URL + "/hooks/abc/def",
+	}
+	return fake
+}
+
+func newRocketchatRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/chatbot/rocketchat-callback", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestReceiveRocketchatMessage(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantReply  bool
+	}{
+		{name: "wrong token", body: `{"token":"other-token","bot":false,"user_name":"alice","text":"/help"}`,
+			wantStatus: http.StatusUnauthorized},
+		{name: "missing token", body: `{"bot":false,"user_name":"alice","text":"/help"}`, wantStatus: http.StatusUnauthorized},
+		{name: "malformed body", body: `{"token":`, wantStatus: http.StatusBadRequest},
+		{name: "bot message", body: `{"token":"` + testRocketchatWebhookToken + `","bot":{"i":"JcJb5oFnBW7HKxXvP"},"user_name":"copilot","text":"/help"}`,
+			wantStatus: http.StatusOK},
+		{name: "user message", body: `{"token":"` + testRocketchatWebhookToken + `","bot":false,"user_name":"alice","text":"/help"}`,
+			wantStatus: http.StatusOK, wantReply: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fake := newFakeRocketchatWebhook(t)
+			resp := httptest.NewRecorder()
+			ReceiveRocketchatMessage(resp, newRocketchatRequest(testCase.body))
+			if resp.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+			if !testCase.wantReply {
+				fake.expectNoRequest(t)
+				return
+			}
+			req, body := fake.waitRequest(t)
+			if req.URL.Path != "/hooks/abc/def" {
+				t.Fatalf("reply posted to %s, want the incoming webhook", req.URL.Path)
+			}
+			var message rocketchat.Message
+			if err := json.Unmarshal(body, &message); err != nil {
+				t.Fatalf("parse reply err: %v", err)
+			}
+			if !strings.Contains(message.Text, "@alice") {
+				t.Errorf("unexpected reply %+v", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/slack"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+ReceiveSlackMessage Slack 机器人回调接口，同时作为 Events API 和斜杠命令的请求地址。
+参考文档：https://api.slack.com/apis/connections/events-api 和 https://api.slack.com/interactivity/slash-commands
+1. 所有请求都需要校验 X-Slack-Signature 签名和 X-Slack-Request-Timestamp 时间窗口；
+2. 斜杠命令请求的 content-type 是 www-form-urlencoded，参数通过 body 传递；
+3. Events API 请求的 content-type 是 application/json，包括 url_verification 验证请求和 event_callback 事件请求；
+*/
+func ReceiveSlackMessage(resp http.ResponseWriter, req *http.Request) {
+	reqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("read body err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	timestamp := req.Header.Get("X-Slack-Request-Timestamp")
+	signature := req.Header.Get("X-Slack-Signature")
+	err = slack.VerifySlackSignature(timestamp, signature, reqBody, conf.AppConfig.SlackSigningSecret, time.Now())
+	if err != nil {
+		slog.Error(fmt.Sprintf("verify slack signature err: %v", err))
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	contentType := req.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		handleSlackSlashCommand(resp, reqBody)
+	} else {
+		handleSlackEvent(resp, req, reqBody)
+	}
+}
+
+// handleSlackSlashCommand 处理斜杠命令请求，需要在 3 秒内响应，所以结果通过 response_url 延迟发送，
+// 这样在机器人未加入的频道和私聊中也能收到回复。
+func handleSlackSlashCommand(resp http.ResponseWriter, reqBody []byte) {
+	form, err := url.ParseQuery(string(reqBody))
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse slash command err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	slashCommand := slack.SlashCommand{
+		Command:     form.Get("command"),
+		Text:        form.Get("text"),
+		UserId:      form.Get("user_id"),
+		ChannelId:   form.Get("channel_id"),
+		TeamId:      form.Get("team_id"),
+		ResponseURL: form.Get("response_url"),
+		TriggerId:   form.Get("trigger_id"),
+	}
+	slog.Debug(fmt.Sprintf("slack slash command: %s %s", slashCommand.Command, slashCommand.Text))
+	// run the async process and notify user when finished
+	go chatbot.HandleUserInput(slack.NewSlashCommandUserMessage(&slashCommand, newSlackClient()))
+	resp.WriteHeader(http.StatusOK)
+}
+
+// handleSlackEvent 处理 Events API 请求。
+// url_verification 请求需要原样返回 challenge 参数，app_mention 事件会被当做用户输入处理。
+func handleSlackEvent(resp http.ResponseWriter, req *http.Request, reqBody []byte) {
+	var eventBody slack.EventBody
+	err := json.Unmarshal(reqBody, &eventBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse slack event err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch eventBody.Type {
+	case slack.EventTypeURLVerification:
+		resp.Header().Set("Content-Type", "text/plain")
+		resp.WriteHeader(http.StatusOK)
+		_, _ = resp.Write([]byte(eventBody.Challenge))
+	case slack.EventTypeEventCallback:
+		// slack retries the event if not acknowledged in 3 seconds, ignore the retries to avoid duplicate replies
+		if req.Header.Get("X-Slack-Retry-Num") != "" {
+			resp.WriteHeader(http.StatusOK)
+			return
+		}
+		event := eventBody.Event
+		if event.Type == slack.EventTypeAppMention && event.BotId == "" {
+			userMessage := slack.NewMentionUserMessage(&event, newSlackClient())
+			// run the async process and notify user when finished
+			go chatbot.HandleUserInput(userMessage)
+		}
+		resp.WriteHeader(http.StatusOK)
+	default:
+		slog.Debug(fmt.Sprintf("ignore slack event type: %s", eventBody.Type))
+		resp.WriteHeader(http.StatusOK)
+	}
+}
+
+func newSlackClient() *slack.Client {
+	return slack.NewClient(&slack.Config{
+		APIBase:  conf.AppConfig.SlackAPIBase,
+		BotToken: conf.AppConfig.SlackBotToken,
+	})
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/slack"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSlackSigningSecret = "test-signing-secret"
+
+// fakeSlackAPI records the chat.postMessage calls and the posts to the response_url
+type fakeSlackAPI struct {
+	server   *httptest.Server
+	requests chan *http.Request
+	bodies   chan []byte
+}
+
+func newFakeSlackAPI(t *testing.T) *fakeSlackAPI {
+	fake := &fakeSlackAPI{requests: make(chan *http.Request, 10), bodies: make(chan []byte, 10)}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fake.requests <- r
+		fake.bodies <- body
+		if r.URL.Path == "/chat.postMessage" {
+			_, _ = w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(fake.server.Close)
+	conf.AppConfig = &conf.Config{
+		SlackSigningSecret: testSlackSigningSecret,
+		SlackBotToken:      "xoxb-test",
+		SlackAPIBase:       fake.server.URL,
+	}
+	return fake
+}
+
+// waitRequest waits for the async reply sent to the fake slack api
+func (f *fakeSlackAPI) waitRequest(t *testing.T) (*http.Request, []byte) {
+	select {
+	case req := <-f.requests:
+		return req, <-f.bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received by the fake slack api")
+		return nil, nil
+	}
+}
+
+func newSlackRequest(body, contentType string, timestamp time.Time, signingSecret string) *http.Request {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/api/chatbot/slack-callback", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", slack.CalcSlackSignature(ts, []byte(body), signingSecret))
+	return req
+}
+
+func TestReceiveSlackMessageSignature(t *testing.T) {
+	newFakeSlackAPI(t)
+	body := `{"type":"url_verification","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`
+	testCases := []struct {
+		name          string
+		timestamp     time.Time
+		signingSecret string
+		wantStatus    int
+	}{
+		{name: "valid signature", timestamp: time.Now(), signingSecret: testSlackSigningSecret, wantStatus: http.StatusOK},
+		{name: "wrong signing secret", timestamp: time.Now(), signingSecret: "other-secret", wantStatus: http.StatusUnauthorized},
+		{name: "replayed request", timestamp: time.Now().Add(-slack.MaxRequestAge - time.Minute), signingSecret: testSlackSigningSecret, wantStatus: http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			ReceiveSlackMessage(resp, newSlackRequest(body, "application/json", testCase.timestamp, testCase.signingSecret))
+			if resp.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReceiveSlackMessageURLVerification(t *testing.T) {
+	newFakeSlackAPI(t)
+	challenge := "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"
+	body := `{"token":"Jhj5dZrVaK7ZwHHjRyZWjbDl","type":"url_verification","challenge":"` + challenge + `"}`
+	resp := httptest.NewRecorder()
+	ReceiveSlackMessage(resp, newSlackRequest(body, "application/json", time.Now(), testSlackSigningSecret))
+	if resp.Code != http.StatusOK || resp.Body.String() != challenge {
+		t.Errorf("response = %d %q, want the challenge", resp.Code, resp.Body.String())
+	}
+}
+
+func TestReceiveSlackSlashCommand(t *testing.T) {
+	fake := newFakeSlackAPI(t)
+	form := url.Values{}
+	form.Set("command", "/help")
+	form.Set("user_id", "U2147483697")
+	form.Set("channel_id", "D0123")
+	form.Set("response_url", fake.server.URL+"/commands/T1/1234/abcd")
+	resp := httptest.NewRecorder()
+	ReceiveSlackMessage(resp, newSlackRequest(form.Encode(), "application/x-www-form-urlencoded", time.Now(), testSlackSigningSecret))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+	}
+	req, body := fake.waitRequest(t)
+	if req.URL.Path != "/commands/T1/1234/abcd" {
+		t.Fatalf("reply posted to %s, want the response_url", req.URL.Path)
+	}
+	var message slack.ResponseMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatalf("parse reply err: %v", err)
+	}
+	if message.ResponseType != slack.ResponseTypeInChannel || !strings.Contains(message.Text, "<@U2147483697>") {
+		t.Errorf("unexpected reply %+v", message)
+	}
+}
+
+func TestReceiveSlackAppMention(t *testing.T) {
+	fake := newFakeSlackAPI(t)
+	body := `{"type":"event_callback","event":{"type":"app_mention","user":"U1","text":"<@U0BOT> /help","ts":"1.2","channel":"C1"}}`
+	resp := httptest.NewRecorder()
+	ReceiveSlackMessage(resp, newSlackRequest(body, "application/json", time.Now(), testSlackSigningSecret))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+	}
+	req, reqBody := fake.waitRequest(t)
+	if req.URL.Path != "/chat.postMessage" || req.Header.Get("Authorization") != "Bearer xoxb-test" {
+		t.Fatalf("unexpected reply request %s %s", req.URL.Path, req.Header.Get("Authorization"))
+	}
+	var message slack.PostMessageRequest
+	if err := json.Unmarshal(reqBody, &message); err != nil {
+		t.Fatalf("parse reply err: %v", err)
+	}
+	if message.Channel != "C1" || message.ThreadTs != "1.2" {
+		t.Errorf("unexpected reply %+v", message)
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/teams"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var teamsClient *teams.Client
+var teamsTokenValidator *teams.TokenValidator
+var teamsOnce sync.Once
+
+/*
+ReceiveTeamsActivity Microsoft Teams 机器人消息接口，参考文档：https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-authentication
+请求头 Authorization 中的 JWT 需要通过 JWKS 中的公钥校验签名，并校验 iss、aud、exp 等字段，
+Activity 中的 serviceUrl 必须和 JWT 中的 serviceurl 一致，并且属于 TEAMS_SERVICE_HOSTS 中的域名，
+消息内容通过 body 中的 Activity 传递，结果以 Adaptive Card 的形式回复到原会话中。
+*/
+func ReceiveTeamsActivity(resp http.ResponseWriter, req *http.Request) {
+	initTeams()
+	claims, err := teamsTokenValidator.ValidateAuthorization(req.Header.Get("Authorization"), time.Now())
+	if err != nil {
+		slog.Error(fmt.Sprintf("validate teams token err: %v", err))
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var activity teams.Activity
+	err = json.NewDecoder(req.Body).Decode(&activity)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse activity err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// the bot access token is sent to the service url when replying, so it must be signed in the token
+	if err = teamsTokenValidator.VerifyServiceURL(claims, activity.ServiceURL); err != nil {
+		slog.Error(fmt.Sprintf("verify service url err: %v", err))
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if activity.Type == teams.ActivityTypeMessage {
+		userMessage := teams.NewUserMessage(&activity, teamsClient)
+		// run the async process and notify user when finished
+		go chatbot.HandleUserInput(userMessage)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+// initTeams creates the shared client and validator so that the access token and signing keys are cached
+func initTeams() {
+	teamsOnce.Do(func() {
+		teamsClient = teams.NewClient(&teams.Config{
+			TokenURL:    conf.AppConfig.TeamsTokenURL,
+			AppId:       conf.AppConfig.TeamsAppId,
+			AppPassword: conf.AppConfig.TeamsAppPassword,
+		})
+		teamsTokenValidator = teams.NewTokenValidator(conf.AppConfig.TeamsJWKSURL, conf.AppConfig.TeamsAppId,
+			conf.AppConfig.GetTeamsServiceHosts())
+	})
+}
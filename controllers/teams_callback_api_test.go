@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signTeamsToken(t *testing.T, privateKey *rsa.PrivateKey, claims map[string]any) string {
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": "test-kid"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestReceiveTeamsActivity(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":"test-kid","n":%q,"e":%q}]}`,
+			base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()))
+	}))
+	defer jwksServer.Close()
+	conf.AppConfig = &conf.Config{
+		TeamsAppId:        "test-app-id",
+		TeamsAppPassword:  "test-app-password",
+		TeamsJWKSURL:      jwksServer.URL,
+		TeamsServiceHosts: "smba.trafficmanager.net",
+	}
+	createToken := func(serviceURL string) string {
+		claims := map[string]any{
+			"iss": "https://api.botframework.com",
+			"aud": "test-app-id",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if serviceURL != "" {
+			claims["serviceurl"] = serviceURL
+		}
+		return signTeamsToken(t, privateKey, claims)
+	}
+	serviceURL := "https://smba.trafficmanager.net/amer/"
+	// the conversationUpdate activity is not handled so that no reply is sent
+	activity := `{"type":"conversationUpdate","serviceUrl":"` + serviceURL + `"}`
+	testCases := []struct {
+		name       string
+		token      string
+		activity   string
+		wantStatus int
+	}{
+		{name: "valid activity", token: createToken(serviceURL), activity: activity, wantStatus: http.StatusOK},
+		{name: "serviceUrl mismatch", token: createToken(serviceURL), activity: strings.Replace(activity, serviceURL, "https://127.0.0.1/", 1), wantStatus: http.StatusUnauthorized},
+		{name: "no serviceurl claim", token: createToken(""), activity: activity, wantStatus: http.StatusUnauthorized},
+		{name: "not allowed service host", token: createToken("https://attacker.example.com/"), activity: strings.Replace(activity, serviceURL, "https://attacker.example.com/", 1), wantStatus: http.StatusUnauthorized},
+		{name: "no token", activity: activity, wantStatus: http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/chatbot/teams-messages", strings.NewReader(testCase.activity))
+			if testCase.token != "" {
+				req.Header.Set("Authorization", "Bearer "+testCase.token)
+			}
+			resp := httptest.NewRecorder()
+			ReceiveTeamsActivity(resp, req)
+			if resp.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+		})
+	}
+}
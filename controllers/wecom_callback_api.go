@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/wecom"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+/*
+ReceiveWecomRobotMessage 企业微信群机器人消息回调接口，参考文档：https://developer.work.weixin.qq.com/document/path/90930
+此接口会接收到两种类型的请求，一种是 GET 回调地址验证请求，另一种是 POST 机器人消息请求。
+两种请求的 msg_signature、timestamp、nonce 参数都通过 query string 传递，
+验证请求的 echostr 和消息请求 body 中的 Encrypt 都需要通过 aes 解密后使用。
+*/
+func ReceiveWecomRobotMessage(resp http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet {
+		handleWecomVerify(resp, req)
+	} else {
+		handleWecomMessage(resp, req)
+	}
+}
+
+// handleWecomVerify 处理回调地址验证请求，验证通过之后，返回解密后的 echostr。
+func handleWecomVerify(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	signature := query.Get("msg_signature")
+	timestamp := query.Get("timestamp")
+	nonce := query.Get("nonce")
+	echostr := query.Get("echostr")
+	token := conf.AppConfig.WecomRobotToken
+	if wecom.CalcWecomSignature(token, timestamp, nonce, echostr) != signature {
+		slog.Error("signature not match")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	plainEchostr, err := wecom.DecryptWecomMessage(echostr, conf.AppConfig.WecomRobotEncodingAESKey, "")
+	if err != nil {
+		slog.Error(fmt.Sprintf("decrypt echostr err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_, _ = resp.Write(plainEchostr)
+}
+
+// handleWecomMessage 处理机器人消息请求。
+func handleWecomMessage(resp http.ResponseWriter, req *http.Request) {
+	msgBodyBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("read body err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var encryptedBody wecom.EncryptedBody
+	err = xml.Unmarshal(msgBodyBytes, &encryptedBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse encrypted message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	query := req.URL.Query()
+	signature := query.Get("msg_signature")
+	localSignature := wecom.CalcWecomSignature(conf.AppConfig.WecomRobotToken, query.Get("timestamp"),
+		query.Get("nonce"), encryptedBody.Encrypt)
+	if localSignature != signature {
+		slog.Error("signature not match")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	srcMsgBytes, err := wecom.DecryptWecomMessage(encryptedBody.Encrypt, conf.AppConfig.WecomRobotEncodingAESKey, "")
+	if err != nil {
+		slog.Error(fmt.Sprintf("decrypt message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// parse src message
+	slog.Debug(fmt.Sprintf("src message: %s", string(srcMsgBytes)))
+	var callbackBody wecom.CallbackBody
+	err = xml.Unmarshal(srcMsgBytes, &callbackBody)
+	if err != nil {
+		slog.Error(fmt.Sprintf("parse src message err: %v", err))
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if callbackBody.MsgType == wecom.MessageTypeText {
+		userMessage := wecom.NewUserMessage(&callbackBody, wecom.NewClient(&wecom.Config{}))
+		// run the async process and notify user when finished
+		go chatbot.HandleUserInput(userMessage)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
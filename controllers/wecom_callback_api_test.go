@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/wecom"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	testWecomToken          = "QDG6eK"
+	testWecomEncodingAESKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+	// testWecomEchostr is the encrypted "hello wecom" with the receive id "wwcorp"
+	testWecomEchostr = "x6vyfrfXtFf8dewTSPkTUSnkJIDDS1q3a/k1LPELV/Rzfr5x3DfnTauhUOgN9Cw/PewVrCvKAbhMyRhdtDue2w=="
+)
+
+func newWecomRequest(method, signature, encrypt string) *http.Request {
+	query := url.Values{}
+	query.Set("timestamp", "1409659813")
+	query.Set("nonce", "1372623149")
+	if signature == "" {
+		signature = wecom.CalcWecomSignature(testWecomToken, "1409659813", "1372623149", encrypt)
+	}
+	query.Set("msg_signature", signature)
+	var body string
+	if method == http.MethodGet {
+		query.Set("echostr", encrypt)
+	} else {
+		body = "<xml><Encrypt><![CDATA[" + encrypt + "]]></Encrypt></xml>"
+	}
+	return httptest.NewRequest(method, "/api/chatbot/wecom-callback?"+query.Encode(), strings.NewReader(body))
+}
+
+func TestReceiveWecomRobotMessage(t *testing.T) {
+	conf.AppConfig = &conf.Config{
+		WecomRobotToken:          testWecomToken,
+		WecomRobotEncodingAESKey: testWecomEncodingAESKey,
+	}
+	testCases := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "url verification", req: newWecomRequest(http.MethodGet, "", testWecomEchostr),
+			wantStatus: http.StatusOK, wantBody: "hello wecom"},
+		{name: "url verification with bad signature", req: newWecomRequest(http.MethodGet, "bad-signature", testWecomEchostr),
+			wantStatus: http.StatusUnauthorized},
+		{name: "url verification with garbled echostr", req: newWecomRequest(http.MethodGet, "", "garbled"),
+			wantStatus: http.StatusBadRequest},
+		{name: "message with bad signature", req: newWecomRequest(http.MethodPost, "bad-signature", testWecomEchostr),
+			wantStatus: http.StatusUnauthorized},
+		// the decrypted "hello wecom" is not a valid xml message
+		{name: "message not xml", req: newWecomRequest(http.MethodPost, "", testWecomEchostr),
+			wantStatus: http.StatusBadRequest},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			ReceiveWecomRobotMessage(resp, testCase.req)
+			if resp.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, testCase.wantStatus)
+			}
+			if testCase.wantBody != "" && resp.Body.String() != testCase.wantBody {
+				t.Errorf("body = %q, want %q", resp.Body.String(), testCase.wantBody)
+			}
+		})
+	}
+}
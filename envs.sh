@@ -7,4 +7,85 @@ export INFOFLOW_ROBOT_TOKEN=xxx
 export INFOFLOW_ROBOT_ENCODING_AES_KEY=xxx
 export OPENAI_API_KEY=xxx
 export OPENAI_API_BASE=https://xxx
-export OPENAI_MODEL=gpt
\ No newline at end of file
+export OPENAI_MODEL=gpt
+# optional, enable the slack adapter
+# export SLACK_SIGNING_SECRET=xxx
+# export SLACK_BOT_TOKEN=xoxb-xxx
+# export SLACK_API_BASE=https://slack.com/api
+
+# optional, enable the feishu/lark adapter
+# export FEISHU_APP_ID=cli_xxx
+# export FEISHU_APP_SECRET=xxx
+# export FEISHU_VERIFICATION_TOKEN=xxx
+# export FEISHU_ENCRYPT_KEY=xxx
+# export FEISHU_API_BASE=https://open.feishu.cn
+
+# optional, enable the dingtalk robot adapter
+# export DINGTALK_ROBOT_APP_SECRET=xxx
+
+# optional, enable the wecom robot adapter
+# export WECOM_ROBOT_TOKEN=xxx
+# export WECOM_ROBOT_ENCODING_AES_KEY=xxx
+
+# optional, enable the microsoft teams adapter
+# export TEAMS_APP_ID=xxx
+# export TEAMS_APP_PASSWORD=xxx
+# export TEAMS_JWKS_URL=https://login.botframework.com/v1/.well-known/keys
+# export TEAMS_TOKEN_URL=https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token
+# export TEAMS_SERVICE_HOSTS=smba.trafficmanager.net,smba.infra.gcc.teams.microsoft.com,*.botframework.com
+
+# optional, enable the mattermost slash command adapter
+# export MATTERMOST_COMMAND_TOKEN=xxx
+
+# optional, enable the rocket.chat outgoing webhook adapter
+# export ROCKETCHAT_WEBHOOK_TOKEN=xxx
+# export ROCKETCHAT_INCOMING_WEBHOOK_URL=https://chat.example.com/hooks/xxx
+
+# optional, enable the query api with comma separated api keys
+# export COPILOT_API_KEYS=key1,key2
+
+# optional, the directory of the prompt templates, default to `prompts`
+# export COPILOT_PROMPTS_DIR=/app/prompts
+
+# optional, enable the embedding based retrieval for large grafana instances
+# export OPENAI_EMBEDDING_MODEL=text-embedding-3-small
+# export COPILOT_INDEX_PATH=/data/dashboard_index.json
+# export COPILOT_RETRIEVAL_TOP_K=50
+# export COPILOT_INDEX_REFRESH_INTERVAL=3600
+
+# optional, `lexical` matches the dashboards by keywords without any llm, the openai envs are not required then,
+# and the embedding based retrieval is disabled, the panels are matched within the dashboards found by keywords
+# export COPILOT_SEARCH_MODE=lexical
+# optional, the timeout in seconds of each llm call, the keyword matching is used if the llm fails or times out,
+# the messages without slash command share one timeout across the intent classification and the matching
+# export COPILOT_LLM_TIMEOUT=30
+
+# optional, the interval in seconds to refresh the dashboard catalog cache, 0 disables the cache
+# export COPILOT_CATALOG_REFRESH_INTERVAL=300
+
+# optional, multiple grafana instances, they replace GRAFANA_HOST, GRAFANA_TOKEN and GRAFANA_BASE_URL
+# export GRAFANA_INSTANCES=prod,staging
+# export GRAFANA_PROD_HOST=http://grafana-prod:3000
+# export GRAFANA_PROD_TOKEN=xxx
+# export GRAFANA_PROD_BASE_URL=https://grafana.example.com
+# export GRAFANA_PROD_ALIASES=生产,production
+# export GRAFANA_STAGING_HOST=http://grafana-staging:3000
+# export GRAFANA_STAGING_TOKEN=xxx
+# export GRAFANA_STAGING_ALIASES=测试,预发
+
+# optional, search the dashboards of multiple orgs, the org token is optional since the grafana token
+# is used with the X-Grafana-Org-Id header if it can access the org, use GRAFANA_<NAME>_ORGS and
+# GRAFANA_<NAME>_ORG_<ID>_TOKEN for the named instances
+# export GRAFANA_ORGS=1,2
+# export GRAFANA_ORG_2_TOKEN=xxx
+
+# optional, the size in pixels of the panel snapshots rendered by the /Snapshot command, which requires
+# the grafana image renderer plugin
+# export COPILOT_RENDER_WIDTH=1000
+# export COPILOT_RENDER_HEIGHT=500
+
+# optional, receive the grafana alert notifications by the webhook contact point at /api/v1/alerts/grafana-webhook,
+# set the token as the authorization credentials or the basic auth password of the contact point, the alerts
+# are posted with the matched dashboards to the infoflow groups
+# export GRAFANA_WEBHOOK_TOKEN=xxx
+# export INFOFLOW_ALERT_GROUP_IDS=123456
@@ -1,24 +1,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"github.com/jemygraw/grafana-copilot/cli"
 	"github.com/jemygraw/grafana-copilot/conf"
 	"github.com/jemygraw/grafana-copilot/controllers"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 )
 
-func initLogging(debug bool) {
+func initLogging(debug bool, output io.Writer) {
 	var logLevel slog.Level
 	if debug {
 		logLevel = slog.LevelDebug
 	} else {
 		logLevel = slog.LevelInfo
 	}
-	jsonHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	jsonHandler := slog.NewJSONHandler(output, &slog.HandlerOptions{
 		Level: logLevel,
 	})
 	logger := slog.New(jsonHandler)
@@ -26,6 +32,11 @@ func initLogging(debug bool) {
 }
 
 func main() {
+	// run the command line client, keep stdout clean for shell pipelines
+	if len(os.Args) > 1 && os.Args[1] == "ask" {
+		initLogging(false, os.Stderr)
+		os.Exit(cli.RunAsk(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	// parse flags
 	var listenHost string
 	var listenPort int
@@ -37,12 +48,48 @@ func main() {
 	// parse envs
 	conf.MustParseConfigFromEnvs()
 	// init logging
-	initLogging(debug)
+	initLogging(debug, os.Stdout)
+	// cache the dashboard catalog in background
+	if conf.AppConfig.CopilotCatalogRefreshInterval > 0 {
+		grafana.StartCatalogRefresher(context.Background(), time.Duration(conf.AppConfig.CopilotCatalogRefreshInterval)*time.Second)
+	}
+	// build the dashboard retrieval index in background
+	if retrieval.IsEnabled() {
+		retrieval.StartIndexer(context.Background())
+	}
 	// listen server
 	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 	http.HandleFunc("/api/chatbot/infoflow-robot-callback", controllers.ReceiveInfoflowRobotMessage)
+	if conf.AppConfig.SlackSigningSecret != "" {
+		http.HandleFunc("/api/chatbot/slack-callback", controllers.ReceiveSlackMessage)
+	}
+	if conf.AppConfig.FeishuAppId != "" {
+		http.HandleFunc("/api/chatbot/feishu-callback", controllers.ReceiveFeishuMessage)
+	}
+	if conf.AppConfig.DingtalkRobotAppSecret != "" {
+		http.HandleFunc("/api/chatbot/dingtalk-robot-callback", controllers.ReceiveDingtalkRobotMessage)
+	}
+	if conf.AppConfig.WecomRobotToken != "" {
+		http.HandleFunc("/api/chatbot/wecom-robot-callback", controllers.ReceiveWecomRobotMessage)
+	}
+	if conf.AppConfig.TeamsAppId != "" {
+		http.HandleFunc("/api/chatbot/teams-messages", controllers.ReceiveTeamsActivity)
+	}
+	if conf.AppConfig.MattermostCommandToken != "" {
+		http.HandleFunc("/api/chatbot/mattermost-command", controllers.ReceiveMattermostCommand)
+	}
+	if conf.AppConfig.RocketchatWebhookToken != "" {
+		http.HandleFunc("/api/chatbot/rocketchat-webhook", controllers.ReceiveRocketchatMessage)
+	}
+	if conf.AppConfig.GrafanaWebhookToken != "" {
+		http.HandleFunc("/api/v1/alerts/grafana-webhook", controllers.ReceiveGrafanaWebhook)
+	}
+	if len(conf.AppConfig.GetAPIKeys()) > 0 {
+		http.HandleFunc("/api/v1/query", controllers.QueryDashboards)
+		http.HandleFunc("/api/v1/admin/catalog", controllers.ManageCatalog)
+	}
 	slog.Info(fmt.Sprintf("Starting grafana copilot server on %s:%d ...", listenHost, listenPort))
 	err := http.ListenAndServe(fmt.Sprintf("%s:%d", listenHost, listenPort), nil)
 	if err != nil {
@@ -0,0 +1,55 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// MaxNotifiedAlerts limits the alerts enriched and posted in one notification
+	MaxNotifiedAlerts = 5
+	// maxAlertDashboards limits the dashboards suggested for each alert
+	maxAlertDashboards = 2
+)
+
+// EnrichWebhookAlerts fills the relevant dashboards of the firing alerts in the notification. The
+// dashboard or panel linked to the alert rule is used if any, otherwise the dashboards are matched by
+// the alert name and summary, and the alerts with the same name and summary share the matching result.
+func EnrichWebhookAlerts(ctx context.Context, payload *grafana.WebhookPayload) {
+	matchedDashboards := make(map[string][]grafana.Dashboard)
+	for index := range payload.Alerts {
+		if index == MaxNotifiedAlerts {
+			break
+		}
+		alert := &payload.Alerts[index]
+		if alert.Status != grafana.WebhookStatusFiring {
+			continue
+		}
+		if linkedURL := alert.PanelURL; linkedURL != "" || alert.DashboardURL != "" {
+			if linkedURL == "" {
+				linkedURL = alert.DashboardURL
+			}
+			alert.Dashboards = []grafana.Dashboard{{Title: "(告警规则关联)", URL: linkedURL}}
+			continue
+		}
+		query := strings.TrimSpace(fmt.Sprintf("%s %s", alert.GetName(), alert.GetSummary()))
+		if query == "" {
+			continue
+		}
+		dashboards, ok := matchedDashboards[query]
+		if !ok {
+			var err error
+			if dashboards, err = matchDashboards(ctx, query, false); err != nil {
+				slog.Error(fmt.Sprintf("match dashboards for alert %s err: %v", alert.GetName(), err))
+			}
+			if len(dashboards) > maxAlertDashboards {
+				dashboards = dashboards[:maxAlertDashboards]
+			}
+			matchedDashboards[query] = dashboards
+		}
+		alert.Dashboards = dashboards
+	}
+}
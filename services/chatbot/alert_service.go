@@ -0,0 +1,324 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AlertsCmd = "alerts"
+)
+
+const (
+	// maxReplyAlerts limits the alerts listed in the reply to fit the text length limit of the platforms
+	maxReplyAlerts = 5
+	// maxAlertLabelValues limits the values of each label in the prompt
+	maxAlertLabelValues = 20
+	// maxAlertTextLength truncates the labels and the summary of each alert in the reply
+	maxAlertTextLength = 80
+)
+
+// alertStateOrder sorts the alerts in the reply, the firing ones are listed first
+var alertStateOrder = map[string]int{
+	grafana.AlertStateFiring:   0,
+	grafana.AlertStatePending:  1,
+	grafana.AlertStateSilenced: 2,
+}
+
+// alertMatcherRegexp matches the label matchers in the user input, e.g. service=order-service or severity!=info
+var alertMatcherRegexp = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)(!=|=)(.+)$`)
+
+// AlertMatcher matches the alerts by the label value, the value is compared case-insensitively
+type AlertMatcher struct {
+	Name  string
+	Value string
+	// Equal is false for the != matchers
+	Equal bool
+}
+
+func (m *AlertMatcher) Matches(alert grafana.Alert) bool {
+	return strings.EqualFold(alert.Labels[m.Name], m.Value) == m.Equal
+}
+
+// AlertFilter is the alert filter extracted from the natural language by the llm
+type AlertFilter struct {
+	Labels map[string]string `json:"labels"`
+	States []string          `json:"states"`
+}
+
+type GrafanaAlertFilterContext struct {
+	AlertLabels string
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        AlertsCmd,
+		Aliases:     []string{"alert", "告警"},
+		Usage:       "/alerts [标签=值] [问题]，例如 /alerts service=order-service 或 /alerts order-service 有什么告警",
+		Description: "查询 Grafana 中正在触发、等待触发和已静默的告警",
+		Handler:     handleAlertsCommand,
+	})
+}
+
+// handleAlertsCommand lists the alerts matching the user input
+func handleAlertsCommand(ctx context.Context, userMessage *message.UserMessage) {
+	alerts, err := MatchAlerts(ctx, userMessage.Input)
+	if err != nil {
+		errMsg := fmt.Sprintf("Handle grafana alerts copilot err: %s", err.Error())
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	NotifyUserText(userMessage, CreateAlertsMessage(alerts))
+}
+
+// MatchAlerts lists the alerts of the grafana instances mentioned in the user input, and filters them
+// by the label matchers in the user input first, then by the labels and states extracted from the rest
+// of the user input by the llm. The keyword matching is used if the llm is disabled or unavailable.
+func MatchAlerts(ctx context.Context, userInput string) (matchedAlerts []grafana.Alert, err error) {
+	alerts, err := grafana.ListAllAlerts(grafana.MatchInstances(userInput))
+	if err != nil {
+		return
+	}
+	matchers, freeText := ParseAlertMatchers(userInput)
+	matchedAlerts = filterAlertsByMatchers(alerts, matchers)
+	if freeText != "" && len(matchedAlerts) > 0 {
+		if conf.AppConfig.IsLexicalMode() {
+			matchedAlerts = matchAlertsLexically(freeText, matchedAlerts)
+		} else {
+			var filter AlertFilter
+			filter, err = ExtractAlertFilter(ctx, freeText, matchedAlerts)
+			if err != nil {
+				slog.Error(fmt.Sprintf("%v, fallback to keyword matching", err))
+				matchedAlerts = matchAlertsLexically(freeText, matchedAlerts)
+				err = nil
+			} else {
+				matchedAlerts = filterAlerts(matchedAlerts, filter)
+			}
+		}
+	}
+	sortAlerts(matchedAlerts)
+	return
+}
+
+// ParseAlertMatchers splits the user input into the label matchers and the natural language text
+func ParseAlertMatchers(userInput string) (matchers []AlertMatcher, freeText string) {
+	words := make([]string, 0)
+	for _, field := range strings.Fields(userInput) {
+		items := alertMatcherRegexp.FindStringSubmatch(field)
+		if items == nil {
+			words = append(words, field)
+			continue
+		}
+		matchers = append(matchers, AlertMatcher{
+			Name:  items[1],
+			Value: strings.Trim(items[3], `"'`),
+			Equal: items[2] == "=",
+		})
+	}
+	freeText = strings.Join(words, " ")
+	return
+}
+
+// ExtractAlertFilter asks the llm to extract the labels and states of the alerts from the user input
+func ExtractAlertFilter(ctx context.Context, userInput string, alerts []grafana.Alert) (filter AlertFilter, err error) {
+	renderCtx := GrafanaAlertFilterContext{
+		AlertLabels: createAlertLabelsTable(alerts),
+	}
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_alert_filter_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm input:\n %s", systemMessage))
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		return
+	}
+	jsonOutput := ernie.GetResponseJsonContent(llmOutput)
+	if err = json.Unmarshal([]byte(jsonOutput), &filter); err != nil {
+		err = fmt.Errorf("parse alert filter err: %w", err)
+		return
+	}
+	return
+}
+
+// createAlertLabelsTable converts the label names and values of the alerts to markdown table
+func createAlertLabelsTable(alerts []grafana.Alert) string {
+	labelValues := make(map[string][]string)
+	for _, alert := range alerts {
+		for name, value := range alert.GetLabels() {
+			if !slices.Contains(labelValues[name], value) {
+				labelValues[name] = append(labelValues[name], value)
+			}
+		}
+	}
+	labelNames := make([]string, 0, len(labelValues))
+	for name := range labelValues {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|Name|Values|\n")
+	markdownBuf.WriteString("|---|---|\n")
+	for _, name := range labelNames {
+		values := labelValues[name]
+		sort.Strings(values)
+		if len(values) > maxAlertLabelValues {
+			values = values[:maxAlertLabelValues]
+		}
+		markdownBuf.WriteString(fmt.Sprintf("|%s|%s|\n", name, escapeMarkdownCell(strings.Join(values, ", "))))
+	}
+	return markdownBuf.String()
+}
+
+func filterAlerts(alerts []grafana.Alert, filter AlertFilter) []grafana.Alert {
+	matchers := make([]AlertMatcher, 0, len(filter.Labels))
+	for name, value := range filter.Labels {
+		matchers = append(matchers, AlertMatcher{Name: name, Value: value, Equal: true})
+	}
+	filteredAlerts := make([]grafana.Alert, 0, len(alerts))
+	for _, alert := range filterAlertsByMatchers(alerts, matchers) {
+		if len(filter.States) == 0 || slices.Contains(filter.States, alert.State) {
+			filteredAlerts = append(filteredAlerts, alert)
+		}
+	}
+	return filteredAlerts
+}
+
+func filterAlertsByMatchers(alerts []grafana.Alert, matchers []AlertMatcher) []grafana.Alert {
+	if len(matchers) == 0 {
+		return alerts
+	}
+	filteredAlerts := make([]grafana.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		matched := true
+		for _, matcher := range matchers {
+			if !matcher.Matches(alert) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filteredAlerts = append(filteredAlerts, alert)
+		}
+	}
+	return filteredAlerts
+}
+
+// matchAlertsLexically matches the alerts by the keywords in their labels and summaries
+func matchAlertsLexically(userInput string, alerts []grafana.Alert) (matchedAlerts []grafana.Alert) {
+	documents := make([]retrieval.LexicalDocument, 0, len(alerts))
+	for index, alert := range alerts {
+		labelValues := make([]string, 0, len(alert.Labels))
+		for _, value := range alert.GetLabels() {
+			labelValues = append(labelValues, value)
+		}
+		documents = append(documents, retrieval.LexicalDocument{
+			Id: strconv.Itoa(index),
+			Fields: []retrieval.LexicalField{
+				{Text: alert.GetName(), Weight: 3},
+				{Text: strings.Join(labelValues, " "), Weight: 2},
+				{Text: alert.GetSummary(), Weight: 1},
+			},
+		})
+	}
+	results := searchLexically(documents, userInput, len(documents))
+	matchedAlerts = make([]grafana.Alert, 0, len(results))
+	for _, result := range results {
+		index, _ := strconv.Atoi(result.Id)
+		matchedAlerts = append(matchedAlerts, alerts[index])
+	}
+	return
+}
+
+// sortAlerts lists the firing alerts first, and the alerts lasting longer first in the same state
+func sortAlerts(alerts []grafana.Alert) {
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alertStateOrder[alerts[i].State] != alertStateOrder[alerts[j].State] {
+			return alertStateOrder[alerts[i].State] < alertStateOrder[alerts[j].State]
+		}
+		return alerts[i].ActiveAt.Before(alerts[j].ActiveAt)
+	})
+}
+
+// CreateAlertsMessage lists the state, duration, labels, summary and rule link of the alerts
+func CreateAlertsMessage(alerts []grafana.Alert) string {
+	if len(alerts) == 0 {
+		return "没有找到匹配的告警"
+	}
+	stateCounts := make(map[string]int)
+	for _, alert := range alerts {
+		stateCounts[alert.State]++
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(fmt.Sprintf("共找到 %d 条告警（firing %d，pending %d，silenced %d）", len(alerts),
+		stateCounts[grafana.AlertStateFiring], stateCounts[grafana.AlertStatePending], stateCounts[grafana.AlertStateSilenced]))
+	if len(alerts) > maxReplyAlerts {
+		buf.WriteString(fmt.Sprintf("，仅展示前 %d 条", maxReplyAlerts))
+		alerts = alerts[:maxReplyAlerts]
+	}
+	buf.WriteString(":\n")
+	for index, alert := range alerts {
+		name := alert.GetName()
+		if alert.Source != "" {
+			name = fmt.Sprintf("[%s] %s", alert.Source, name)
+		}
+		buf.WriteString(fmt.Sprintf("\n%d. [%s] %s，持续 %s\n", index+1, alert.State, name,
+			formatAlertDuration(time.Since(alert.ActiveAt))))
+		if labels := formatAlertLabels(alert); labels != "" {
+			buf.WriteString(fmt.Sprintf("标签: %s\n", truncateText(labels, maxAlertTextLength)))
+		}
+		if summary := alert.GetSummary(); summary != "" {
+			buf.WriteString(fmt.Sprintf("摘要: %s\n", truncateText(summary, maxAlertTextLength)))
+		}
+		buf.WriteString(fmt.Sprintf("规则: %s\n", alert.RuleURL))
+	}
+	return buf.String()
+}
+
+// formatAlertLabels joins the labels except the alert name and folder which are shown elsewhere
+func formatAlertLabels(alert grafana.Alert) string {
+	labels := alert.GetLabels()
+	items := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if name == grafana.AlertNameLabel || name == "grafana_folder" {
+			continue
+		}
+		items = append(items, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
+
+// formatAlertDuration formats the duration in two units at most, e.g. 2d3h, 1h5m, 10m and <1m
+func formatAlertDuration(duration time.Duration) string {
+	if duration < time.Minute {
+		return "<1m"
+	}
+	days := int(duration / (24 * time.Hour))
+	hours := int(duration % (24 * time.Hour) / time.Hour)
+	minutes := int(duration % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,157 @@
+package chatbot
+
+import (
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAlertMatchers(t *testing.T) {
+	testCases := []struct {
+		name         string
+		userInput    string
+		wantMatchers []AlertMatcher
+		wantFreeText string
+	}{
+		{name: "equal", userInput: "service=order-service",
+			wantMatchers: []AlertMatcher{{Name: "service", Value: "order-service", Equal: true}}},
+		{name: "not equal", userInput: "severity!=info",
+			wantMatchers: []AlertMatcher{{Name: "severity", Value: "info", Equal: false}}},
+		{name: "quoted values", userInput: `service="order" env='prod'`,
+			wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}, {Name: "env", Value: "prod", Equal: true}}},
+		{name: "matchers and free text", userInput: "订单服务 service=order 有什么告警",
+			wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}}, wantFreeText: "订单服务 有什么告警"},
+		{name: "not a label name", userInput: "1a=b ==c",
+			wantFreeText: "1a=b ==c"},
+		{name: "empty", userInput: "  "},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			matchers, freeText := ParseAlertMatchers(testCase.userInput)
+			if !reflect.DeepEqual(matchers, testCase.wantMatchers) {
+				t.Errorf("matchers = %+v, want %+v", matchers, testCase.wantMatchers)
+			}
+			if freeText != testCase.wantFreeText {
+				t.Errorf("free text = %q, want %q", freeText, testCase.wantFreeText)
+			}
+		})
+	}
+}
+
+func newTestAlert(name, state string, labels map[string]string) grafana.Alert {
+	alertLabels := map[string]string{grafana.AlertNameLabel: name}
+	for labelName, value := range labels {
+		alertLabels[labelName] = value
+	}
+	return grafana.Alert{Labels: alertLabels, State: state}
+}
+
+func alertNames(alerts []grafana.Alert) []string {
+	names := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		names = append(names, alert.GetName())
+	}
+	return names
+}
+
+func TestFilterAlerts(t *testing.T) {
+	alerts := []grafana.Alert{
+		newTestAlert("HighLatency", grafana.AlertStateFiring, map[string]string{"service": "Order-Service", "env": "prod"}),
+		newTestAlert("SlowQuery", grafana.AlertStatePending, map[string]string{"service": "order-service", "env": "dev"}),
+		newTestAlert("DiskFull", grafana.AlertStateSilenced, map[string]string{"instance": "node-1"}),
+	}
+	testCases := []struct {
+		name     string
+		matchers []AlertMatcher
+		filter   AlertFilter
+		want     []string
+	}{
+		{name: "no filter", want: []string{"HighLatency", "SlowQuery", "DiskFull"}},
+		{name: "case insensitive matcher", matchers: []AlertMatcher{{Name: "service", Value: "ORDER-SERVICE", Equal: true}},
+			want: []string{"HighLatency", "SlowQuery"}},
+		{name: "not equal matcher", matchers: []AlertMatcher{{Name: "env", Value: "PROD", Equal: false}},
+			want: []string{"SlowQuery", "DiskFull"}},
+		{name: "all matchers", matchers: []AlertMatcher{{Name: "service", Value: "order-service", Equal: true},
+			{Name: "env", Value: "dev", Equal: true}}, want: []string{"SlowQuery"}},
+		{name: "llm labels", filter: AlertFilter{Labels: map[string]string{"instance": "Node-1"}}, want: []string{"DiskFull"}},
+		{name: "llm states", filter: AlertFilter{States: []string{grafana.AlertStateFiring, grafana.AlertStatePending}},
+			want: []string{"HighLatency", "SlowQuery"}},
+		{name: "no match", matchers: []AlertMatcher{{Name: "service", Value: "pay", Equal: true}}, want: []string{}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			filtered := filterAlerts(filterAlertsByMatchers(alerts, testCase.matchers), testCase.filter)
+			if names := alertNames(filtered); !reflect.DeepEqual(names, testCase.want) {
+				t.Errorf("alerts = %v, want %v", names, testCase.want)
+			}
+		})
+	}
+}
+
+func TestSortAlerts(t *testing.T) {
+	now := time.Now()
+	alerts := []grafana.Alert{
+		{Labels: map[string]string{grafana.AlertNameLabel: "silenced"}, State: grafana.AlertStateSilenced, ActiveAt: now.Add(-3 * time.Hour)},
+		{Labels: map[string]string{grafana.AlertNameLabel: "pending"}, State: grafana.AlertStatePending, ActiveAt: now.Add(-2 * time.Hour)},
+		{Labels: map[string]string{grafana.AlertNameLabel: "firing-new"}, State: grafana.AlertStateFiring, ActiveAt: now.Add(-time.Minute)},
+		{Labels: map[string]string{grafana.AlertNameLabel: "firing-old"}, State: grafana.AlertStateFiring, ActiveAt: now.Add(-time.Hour)},
+	}
+	sortAlerts(alerts)
+	want := []string{"firing-old", "firing-new", "pending", "silenced"}
+	if names := alertNames(alerts); !reflect.DeepEqual(names, want) {
+		t.Errorf("alerts = %v, want %v", names, want)
+	}
+}
+
+func TestCreateAlertsMessage(t *testing.T) {
+	if message := CreateAlertsMessage(nil); message != "没有找到匹配的告警" {
+		t.Errorf("message = %q, want the no alert message", message)
+	}
+	alerts := make([]grafana.Alert, 0, maxReplyAlerts+2)
+	for index := 0; index < maxReplyAlerts+2; index++ {
+		alert := newTestAlert("HighLatency", grafana.AlertStateFiring, map[string]string{
+			"service": "order", "grafana_folder": "prod", "__alert_rule_uid__": "rule-1"})
+		alert.Annotations = map[string]string{"summary": strings.Repeat("慢", maxAlertTextLength+10)}
+		alert.ActiveAt = time.Now().Add(-90 * time.Minute)
+		alert.RuleURL = "https://grafana.example.com/alerting/grafana/rule-1/view"
+		alert.Source = "prod"
+		alerts = append(alerts, alert)
+	}
+	alerts[maxReplyAlerts+1].State = grafana.AlertStateSilenced
+	message := CreateAlertsMessage(alerts)
+	for _, want := range []string{
+		"共找到 7 条告警（firing 6，pending 0，silenced 1），仅展示前 5 条",
+		"1. [firing] [prod] HighLatency，持续 1h30m",
+		"标签: service=order\n",
+		"规则: https://grafana.example.com/alerting/grafana/rule-1/view",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message %q does not contain %q", message, want)
+		}
+	}
+	if strings.Contains(message, "6. ") || strings.Contains(message, "grafana_folder") || strings.Contains(message, "__alert_rule_uid__") {
+		t.Errorf("message %q contains the hidden alerts or labels", message)
+	}
+	if strings.Contains(message, strings.Repeat("慢", maxAlertTextLength+1)) {
+		t.Errorf("summary is not truncated in %q", message)
+	}
+}
+
+func TestFormatAlertDuration(t *testing.T) {
+	testCases := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{duration: 30 * time.Second, want: "<1m"},
+		{duration: 10 * time.Minute, want: "10m"},
+		{duration: 65 * time.Minute, want: "1h5m"},
+		{duration: 51 * time.Hour, want: "2d3h"},
+	}
+	for _, testCase := range testCases {
+		if got := formatAlertDuration(testCase.duration); got != testCase.want {
+			t.Errorf("formatAlertDuration(%s) = %s, want %s", testCase.duration, got, testCase.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"strings"
+	"sync"
+)
+
+const (
+	HelpCmd = "help"
+)
+
+// CommandHandler handles the user message of the command and replies the result by itself, the llm calls
+// of the command are cancelled when the ctx is done
+type CommandHandler func(ctx context.Context, userMessage *message.UserMessage)
+
+// Command is a slash command which can be triggered by the user in any chat platform
+type Command struct {
+	// Name is the command name without the leading slash, e.g. Grafana
+	Name string
+	// Aliases are the alternative names of the command
+	Aliases []string
+	// Usage is the one line usage shown in the help message
+	Usage string
+	// Description describes what the command does
+	Description string
+	Handler     CommandHandler
+}
+
+var commandsLock sync.RWMutex
+
+// commands keeps the registration order which is used by the help message
+var commands []*Command
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        GrafanaCmd,
+		Aliases:     []string{"dashboard", "看板"},
+		Usage:       "/Grafana <问题>，例如 /Grafana kafka 消费延迟",
+		Description: "根据问题匹配最合适的 Grafana 看板",
+		Handler:     handleGrafanaCommand,
+	})
+	RegisterCommand(&Command{
+		Name:        HelpCmd,
+		Aliases:     []string{"h", "帮助"},
+		Usage:       "/help",
+		Description: "列出当前部署支持的所有命令",
+		Handler:     handleHelpCommand,
+	})
+}
+
+// RegisterCommand registers the command, the command with the same name is replaced. It panics if the name or
+// an alias collides with the name or an alias of another command, so that the conflicts are found at init
+// instead of being shadowed silently by the registration order. Command names and aliases are matched
+// case-insensitively.
+func RegisterCommand(command *Command) {
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+	replacedIndex := -1
+	for index, registered := range commands {
+		if strings.EqualFold(registered.Name, command.Name) {
+			replacedIndex = index
+			continue
+		}
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			if registered.matchName(name) {
+				panic(fmt.Sprintf("command /%s conflicts with the command /%s", name, registered.Name))
+			}
+		}
+	}
+	if replacedIndex >= 0 {
+		commands[replacedIndex] = command
+		return
+	}
+	commands = append(commands, command)
+}
+
+// FindCommand returns the command matching the name or alias, nil if not found
+func FindCommand(name string) *Command {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	for _, command := range commands {
+		if command.matchName(name) {
+			return command
+		}
+	}
+	return nil
+}
+
+// matchName checks whether the name is the name or an alias of the command
+func (c *Command) matchName(name string) bool {
+	if strings.EqualFold(c.Name, name) {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListCommands returns the registered commands in the registration order
+func ListCommands() []*Command {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	return append([]*Command(nil), commands...)
+}
+
+// CreateHelpMessage renders all the registered commands as the help message
+func CreateHelpMessage() string {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("当前支持的命令如下:\n")
+	for _, command := range ListCommands() {
+		buf.WriteString(fmt.Sprintf("\n/%s", command.Name))
+		if len(command.Aliases) > 0 {
+			buf.WriteString(fmt.Sprintf(" (别名: /%s)", strings.Join(command.Aliases, ", /")))
+		}
+		buf.WriteString(fmt.Sprintf("\n  %s", command.Description))
+		if command.Usage != "" {
+			buf.WriteString(fmt.Sprintf("\n  用法: %s", command.Usage))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+func handleHelpCommand(ctx context.Context, userMessage *message.UserMessage) {
+	NotifyUserText(userMessage, CreateHelpMessage())
+}
@@ -0,0 +1,82 @@
+package chatbot
+
+import (
+	"context"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"testing"
+)
+
+// withCommands runs the test with a copy of the registered commands and restores them afterwards
+func withCommands(t *testing.T) {
+	commandsLock.Lock()
+	saved := append([]*Command(nil), commands...)
+	commandsLock.Unlock()
+	t.Cleanup(func() {
+		commandsLock.Lock()
+		commands = saved
+		commandsLock.Unlock()
+	})
+}
+
+func TestFindCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		wantName string
+	}{
+		{name: "Grafana", wantName: GrafanaCmd},
+		{name: "grafana", wantName: GrafanaCmd},
+		{name: "看板", wantName: GrafanaCmd},
+		{name: "H", wantName: HelpCmd},
+		{name: "query", wantName: PromQLCmd},
+		{name: "render", wantName: SnapshotCmd},
+		{name: "logql", wantName: LogsCmd},
+		{name: "unknown"},
+	}
+	for _, testCase := range testCases {
+		command := FindCommand(testCase.name)
+		if testCase.wantName == "" {
+			if command != nil {
+				t.Errorf("FindCommand(%q) = /%s, want nil", testCase.name, command.Name)
+			}
+			continue
+		}
+		if command == nil || command.Name != testCase.wantName {
+			t.Errorf("FindCommand(%q) = %v, want /%s", testCase.name, command, testCase.wantName)
+		}
+	}
+}
+
+func TestRegisterCommandReplace(t *testing.T) {
+	withCommands(t)
+	count := len(ListCommands())
+	handler := func(ctx context.Context, userMessage *message.UserMessage) {}
+	RegisterCommand(&Command{Name: "HELP", Aliases: []string{"h", "帮助", "?"}, Description: "replaced", Handler: handler})
+	if len(ListCommands()) != count {
+		t.Errorf("the command with the same name is added instead of replaced")
+	}
+	if command := FindCommand("?"); command == nil || command.Description != "replaced" {
+		t.Errorf("FindCommand(\"?\") = %v, want the replaced command", command)
+	}
+}
+
+func TestRegisterCommandConflict(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command *Command
+	}{
+		{name: "alias collides with name", command: &Command{Name: "dashboards", Aliases: []string{"grafana"}}},
+		{name: "alias collides with alias", command: &Command{Name: "metrics", Aliases: []string{"Query"}}},
+		{name: "name collides with alias", command: &Command{Name: "render"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			withCommands(t)
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterCommand(/%s) did not panic", testCase.command.Name)
+				}
+			}()
+			RegisterCommand(testCase.command)
+		})
+	}
+}
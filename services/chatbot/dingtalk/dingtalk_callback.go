@@ -0,0 +1,86 @@
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxRequestAge is the timestamp window of the robot callback required by dingtalk
+const MaxRequestAge = time.Hour
+
+// SessionWebhookHosts are the hosts of the session webhooks issued by dingtalk, the replies are only
+// posted to these hosts because the sessionWebhook in the body is not covered by the signature
+var SessionWebhookHosts = []string{"oapi.dingtalk.com", "api.dingtalk.com"}
+
+var usedSignaturesLock sync.Mutex
+
+// usedSignatures keeps the signatures seen in the timestamp window to reject the replayed requests
+var usedSignatures = make(map[string]time.Time)
+
+// CalcDingtalkSignature 计算钉钉机器人回调请求的签名
+// sign = base64(hmac_sha256(appSecret, timestamp + "\n" + appSecret))
+func CalcDingtalkSignature(timestamp, appSecret string) string {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write([]byte(fmt.Sprintf("%s\n%s", timestamp, appSecret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyDingtalkSignature checks the timestamp (in milliseconds) window and the signature of the request
+func VerifyDingtalkSignature(timestamp, signature, appSecret string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	age := now.Sub(time.UnixMilli(ts))
+	if age > MaxRequestAge || age < -MaxRequestAge {
+		return fmt.Errorf("timestamp %s out of window", timestamp)
+	}
+	localSignature := CalcDingtalkSignature(timestamp, appSecret)
+	if !hmac.Equal([]byte(localSignature), []byte(signature)) {
+		return fmt.Errorf("signature not match")
+	}
+	// the signature only covers the timestamp, so each one is accepted only once
+	if !markSignatureUsed(signature, now) {
+		return fmt.Errorf("signature already used")
+	}
+	return nil
+}
+
+// markSignatureUsed records the signature and returns false if it was seen in the timestamp window
+func markSignatureUsed(signature string, now time.Time) bool {
+	usedSignaturesLock.Lock()
+	defer usedSignaturesLock.Unlock()
+	for usedSignature, expireAt := range usedSignatures {
+		if now.After(expireAt) {
+			delete(usedSignatures, usedSignature)
+		}
+	}
+	if _, ok := usedSignatures[signature]; ok {
+		return false
+	}
+	usedSignatures[signature] = now.Add(MaxRequestAge * 2)
+	return true
+}
+
+// VerifySessionWebhook checks that the session webhook is an unexpired https url issued by dingtalk,
+// the expired time is in milliseconds
+func VerifySessionWebhook(sessionWebhook string, expiredTime int64, now time.Time) error {
+	webhookURL, err := url.Parse(sessionWebhook)
+	if err != nil {
+		return fmt.Errorf("invalid session webhook, %s", err.Error())
+	}
+	if webhookURL.Scheme != "https" || webhookURL.User != nil || !slices.Contains(SessionWebhookHosts, webhookURL.Host) {
+		return fmt.Errorf("session webhook host %q not allowed", webhookURL.Host)
+	}
+	if !now.Before(time.UnixMilli(expiredTime)) {
+		return fmt.Errorf("session webhook expired at %d", expiredTime)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package dingtalk
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyDingtalkSignature(t *testing.T) {
+	appSecret := "test-app-secret"
+	now := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name      string
+		timestamp time.Time
+		secret    string
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", timestamp: now, secret: appSecret, now: now},
+		{name: "valid within window", timestamp: now.Add(-time.Minute), secret: appSecret, now: now.Add(MaxRequestAge - time.Minute)},
+		{name: "wrong secret", timestamp: now.Add(time.Second), secret: "other", now: now, wantErr: true},
+		{name: "out of window", timestamp: now.Add(-MaxRequestAge - time.Second), secret: appSecret, now: now, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			timestamp := strconv.FormatInt(testCase.timestamp.UnixMilli(), 10)
+			err := VerifyDingtalkSignature(timestamp, CalcDingtalkSignature(timestamp, testCase.secret), appSecret, testCase.now)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("VerifyDingtalkSignature() err = %v, wantErr %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyDingtalkSignatureReplay(t *testing.T) {
+	appSecret := "test-app-secret"
+	now := time.UnixMilli(1700000100000)
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	signature := CalcDingtalkSignature(timestamp, appSecret)
+	if err := VerifyDingtalkSignature(timestamp, signature, appSecret, now); err != nil {
+		t.Fatalf("first request err: %v", err)
+	}
+	if err := VerifyDingtalkSignature(timestamp, signature, appSecret, now.Add(time.Second)); err == nil {
+		t.Error("replayed request accepted")
+	}
+}
+
+func TestVerifySessionWebhook(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	expiredTime := now.Add(time.Hour).UnixMilli()
+	testCases := []struct {
+		name           string
+		sessionWebhook string
+		expiredTime    int64
+		wantErr        bool
+	}{
+		{name: "oapi host", sessionWebhook: "https://oapi.dingtalk.com/robot/sendBySession?session=xxx", expiredTime: expiredTime},
+		{name: "api host", sessionWebhook: "https://api.dingtalk.com/v1.0/robot/sendBySession?session=xxx", expiredTime: expiredTime},
+		{name: "http scheme", sessionWebhook: "http://oapi.dingtalk.com/robot/sendBySession", expiredTime: expiredTime, wantErr: true},
+		{name: "other host", sessionWebhook: "https://169.254.169.254/latest/meta-data", expiredTime: expiredTime, wantErr: true},
+		{name: "suffix host", sessionWebhook: "https://oapi.dingtalk.com.example.com/robot", expiredTime: expiredTime, wantErr: true},
+		{name: "host with port", sessionWebhook: "https://oapi.dingtalk.com:8443/robot", expiredTime: expiredTime, wantErr: true},
+		{name: "user info", sessionWebhook: "https://user@oapi.dingtalk.com/robot", expiredTime: expiredTime, wantErr: true},
+		{name: "empty", sessionWebhook: "", expiredTime: expiredTime, wantErr: true},
+		{name: "expired", sessionWebhook: "https://oapi.dingtalk.com/robot/sendBySession", expiredTime: now.UnixMilli(), wantErr: true},
+		{name: "no expired time", sessionWebhook: "https://oapi.dingtalk.com/robot/sendBySession", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := VerifySessionWebhook(testCase.sessionWebhook, testCase.expiredTime, now)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("VerifySessionWebhook() err = %v, wantErr %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
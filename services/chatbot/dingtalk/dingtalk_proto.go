@@ -0,0 +1,61 @@
+package dingtalk
+
+const (
+	MessageTypeText     = "text"
+	MessageTypeMarkdown = "markdown"
+)
+
+// CallbackBody is the message posted by the dingtalk robot when it is @ in the group.
+// See https://open.dingtalk.com/document/orgapp/receive-message
+type CallbackBody struct {
+	MsgId                     string      `json:"msgId"`
+	MsgType                   string      `json:"msgtype"`
+	Text                      TextContent `json:"text"`
+	ConversationId            string      `json:"conversationId"`
+	ConversationType          string      `json:"conversationType"`
+	ConversationTitle         string      `json:"conversationTitle"`
+	ChatbotUserId             string      `json:"chatbotUserId"`
+	SenderId                  string      `json:"senderId"`
+	SenderNick                string      `json:"senderNick"`
+	SenderStaffId             string      `json:"senderStaffId"`
+	SenderCorpId              string      `json:"senderCorpId"`
+	AtUsers                   []AtUser    `json:"atUsers"`
+	IsAdmin                   bool        `json:"isAdmin"`
+	SessionWebhook            string      `json:"sessionWebhook"`
+	SessionWebhookExpiredTime int64       `json:"sessionWebhookExpiredTime"`
+	CreateAt                  int64       `json:"createAt"`
+	RobotCode                 string      `json:"robotCode"`
+}
+
+type TextContent struct {
+	Content string `json:"content"`
+}
+
+type AtUser struct {
+	DingtalkId string `json:"dingtalkId"`
+	StaffId    string `json:"staffId"`
+}
+
+// Message is the message sent to the session webhook.
+// See https://open.dingtalk.com/document/orgapp/custom-bot-send-message-type
+type Message struct {
+	MsgType  string           `json:"msgtype"`
+	Text     *TextContent     `json:"text,omitempty"`
+	Markdown *MarkdownContent `json:"markdown,omitempty"`
+	At       *MessageAt       `json:"at,omitempty"`
+}
+
+type MarkdownContent struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type MessageAt struct {
+	AtUserIds []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll,omitempty"`
+}
+
+type ResponseBody struct {
+	ErrorCode    int    `json:"errcode"`
+	ErrorMessage string `json:"errmsg"`
+}
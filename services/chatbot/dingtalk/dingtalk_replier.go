@@ -0,0 +1,63 @@
+package dingtalk
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+)
+
+const PlatformName = "dingtalk"
+
+// Replier sends the copilot replies to the session webhook as markdown and @ the user who asks
+type Replier struct {
+	client         *Client
+	sessionWebhook string
+	staffId        string
+}
+
+func NewReplier(client *Client, sessionWebhook, staffId string) *Replier {
+	return &Replier{
+		client:         client,
+		sessionWebhook: sessionWebhook,
+		staffId:        staffId,
+	}
+}
+
+// NewUserMessage converts the dingtalk callback body to the platform neutral user message
+func NewUserMessage(callbackBody *CallbackBody, client *Client) *message.UserMessage {
+	command, input := message.ParseCommand(callbackBody.Text.Content)
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        command,
+		Input:          input,
+		FromUserId:     callbackBody.SenderStaffId,
+		ConversationId: callbackBody.ConversationId,
+		Replier:        NewReplier(client, callbackBody.SessionWebhook, callbackBody.SenderStaffId),
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.sendMarkdown("Grafana Copilot", text)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("为您找到如下看板:\n\n")
+	for _, dashboard := range dashboards {
+		buf.WriteString(fmt.Sprintf("- [%s](%s)\n", dashboard.Title, dashboard.URL))
+	}
+	return r.sendMarkdown("为您找到如下看板", buf.String())
+}
+
+// sendMarkdown sends the markdown message, dingtalk requires both the `@staffId` in text and the at field to @ the user
+func (r *Replier) sendMarkdown(title, text string) error {
+	return r.client.SendMessage(r.sessionWebhook, &Message{
+		MsgType: MessageTypeMarkdown,
+		Markdown: &MarkdownContent{
+			Title: title,
+			Text:  fmt.Sprintf("@%s %s", r.staffId, text),
+		},
+		At: &MessageAt{AtUserIds: []string{r.staffId}},
+	})
+}
@@ -0,0 +1,98 @@
+package feishu
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MaxRequestAge is the timestamp window to prevent replay attacks
+const MaxRequestAge = time.Minute * 5
+
+var mentionKeyPattern = regexp.MustCompile(`@_user_\d+`)
+
+// DecryptFeishuMessage decrypts the `encrypt` field of the callback body.
+// The key is the sha256 sum of the encrypt key, and the first block of the data is the iv of AES-256-CBC.
+// See https://open.feishu.cn/document/server-docs/event-subscription-guide/event-subscription-configure-/encrypt-key-encryption-configuration-case
+func DecryptFeishuMessage(encrypt string, encryptKey string) ([]byte, error) {
+	encryptedBytes, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64, %s", err.Error())
+	}
+	if len(encryptedBytes) < aes.BlockSize || len(encryptedBytes)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted data length %d", len(encryptedBytes))
+	}
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("create cipher error, %s", err.Error())
+	}
+	iv := encryptedBytes[:aes.BlockSize]
+	decryptedBytes := make([]byte, len(encryptedBytes)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decryptedBytes, encryptedBytes[aes.BlockSize:])
+	return pkcs7Unpadding(decryptedBytes)
+}
+
+func pkcs7Unpadding(src []byte) ([]byte, error) {
+	length := len(src)
+	if length == 0 {
+		return nil, fmt.Errorf("empty decrypted data")
+	}
+	padding := int(src[length-1])
+	if padding == 0 || padding > aes.BlockSize || padding > length {
+		return nil, fmt.Errorf("invalid padding %d", padding)
+	}
+	if !bytes.Equal(src[length-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("invalid padding %d", padding)
+	}
+	return src[:length-padding], nil
+}
+
+// CalcFeishuSignature calculates the X-Lark-Signature header of the event request when the encrypt key is set
+func CalcFeishuSignature(timestamp, nonce, encryptKey string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(timestamp + nonce + encryptKey))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyFeishuSignature checks both the X-Lark-Request-Timestamp window and the X-Lark-Signature header in constant time
+func VerifyFeishuSignature(timestamp, nonce, encryptKey string, body []byte, signature string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > MaxRequestAge || age < -MaxRequestAge {
+		return fmt.Errorf("timestamp %s out of window", timestamp)
+	}
+	localSignature := CalcFeishuSignature(timestamp, nonce, encryptKey, body)
+	if signature == "" || !hmac.Equal([]byte(localSignature), []byte(signature)) {
+		return fmt.Errorf("signature not match")
+	}
+	return nil
+}
+
+// GetUserInput returns the command and user input of the text message.
+// The mention placeholders like `@_user_1` are stripped, e.g. "@_user_1 /Grafana kafka lag"
+// returns "Grafana" and "kafka lag"
+func (m ReceiveMessage) GetUserInput() (command, input string) {
+	if m.MessageType != MessageTypeText {
+		return
+	}
+	var content TextContent
+	if err := json.Unmarshal([]byte(m.Content), &content); err != nil {
+		return
+	}
+	return message.ParseCommand(mentionKeyPattern.ReplaceAllString(content.Text, ""))
+}
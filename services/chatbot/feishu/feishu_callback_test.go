@@ -0,0 +1,113 @@
+package feishu
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// encryptFeishuMessage encrypts the message in the same way as the feishu open platform
+func encryptFeishuMessage(t *testing.T, plainText []byte, encryptKey string) string {
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(plainText)%aes.BlockSize
+	for i := 0; i < padding; i++ {
+		plainText = append(plainText, byte(padding))
+	}
+	iv := []byte("0123456789abcdef")
+	encryptedBytes := make([]byte, len(plainText))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encryptedBytes, plainText)
+	return base64.StdEncoding.EncodeToString(append(iv, encryptedBytes...))
+}
+
+func TestDecryptFeishuMessage(t *testing.T) {
+	encryptKey := "test key"
+	plainText := `{"challenge":"ajls384kdjx98XX","token":"xxxxxx","type":"url_verification"}`
+	testCases := []struct {
+		name       string
+		encrypt    string
+		encryptKey string
+		wantErr    bool
+	}{
+		{name: "valid", encrypt: encryptFeishuMessage(t, []byte(plainText), encryptKey), encryptKey: encryptKey},
+		{name: "wrong key", encrypt: encryptFeishuMessage(t, []byte(plainText), encryptKey), encryptKey: "other key", wantErr: true},
+		{name: "invalid base64", encrypt: "!!!", encryptKey: encryptKey, wantErr: true},
+		{name: "invalid length", encrypt: base64.StdEncoding.EncodeToString([]byte("short")), encryptKey: encryptKey, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decrypted, err := DecryptFeishuMessage(testCase.encrypt, testCase.encryptKey)
+			// the wrong key may produce a valid padding by chance, but never the plain text
+			if testCase.wantErr {
+				if err == nil && string(decrypted) == plainText {
+					t.Errorf("DecryptFeishuMessage() = %s, want err", decrypted)
+				}
+				return
+			}
+			if err != nil || string(decrypted) != plainText {
+				t.Errorf("DecryptFeishuMessage() = %s, %v, want %s", decrypted, err, plainText)
+			}
+		})
+	}
+}
+
+func TestVerifyFeishuSignature(t *testing.T) {
+	body := []byte(`{"encrypt":"abc"}`)
+	now := time.Unix(1600000000, 0)
+	signature := CalcFeishuSignature("1600000000", "nonce", "test key", body)
+	testCases := []struct {
+		name      string
+		timestamp string
+		nonce     string
+		body      []byte
+		signature string
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", timestamp: "1600000000", nonce: "nonce", body: body, signature: signature, now: now},
+		{name: "valid within window", timestamp: "1600000000", nonce: "nonce", body: body, signature: signature,
+			now: now.Add(MaxRequestAge - time.Second)},
+		{name: "empty signature", timestamp: "1600000000", nonce: "nonce", body: body, signature: "", now: now, wantErr: true},
+		{name: "wrong nonce", timestamp: "1600000000", nonce: "other", body: body, signature: signature, now: now, wantErr: true},
+		{name: "tampered body", timestamp: "1600000000", nonce: "nonce", body: []byte(`{"encrypt":"abd"}`), signature: signature,
+			now: now, wantErr: true},
+		{name: "replayed request", timestamp: "1600000000", nonce: "nonce", body: body, signature: signature,
+			now: now.Add(MaxRequestAge + time.Second), wantErr: true},
+		{name: "timestamp in the future", timestamp: "1600000000", nonce: "nonce", body: body, signature: signature,
+			now: now.Add(-MaxRequestAge - time.Second), wantErr: true},
+		{name: "invalid timestamp", timestamp: "", nonce: "nonce", body: body,
+			signature: CalcFeishuSignature("", "nonce", "test key", body), now: now, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := VerifyFeishuSignature(testCase.timestamp, testCase.nonce, "test key", testCase.body, testCase.signature, testCase.now)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("VerifyFeishuSignature() err = %v, want error %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetUserInput(t *testing.T) {
+	testCases := []struct {
+		message     ReceiveMessage
+		wantCommand string
+		wantInput   string
+	}{
+		{message: ReceiveMessage{MessageType: MessageTypeText, Content: `{"text":"@_user_1 /Grafana kafka lag"}`}, wantCommand: "Grafana", wantInput: "kafka lag"},
+		{message: ReceiveMessage{MessageType: MessageTypeText, Content: `{"text":"@_user_1 kafka lag"}`}, wantInput: "kafka lag"},
+		{message: ReceiveMessage{MessageType: "image", Content: `{"image_key":"img"}`}},
+	}
+	for _, testCase := range testCases {
+		command, input := testCase.message.GetUserInput()
+		if command != testCase.wantCommand || input != testCase.wantInput {
+			t.Errorf("GetUserInput(%s) = %q, %q, want %q, %q", testCase.message.Content, command, input, testCase.wantCommand, testCase.wantInput)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package feishu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = time.Second * 10 // 10 seconds
+	DefaultAPIBase = "https://open.feishu.cn"
+	// tokenRefreshAhead refreshes the tenant access token before it really expires
+	tokenRefreshAhead = time.Minute * 5
+)
+
+type Config struct {
+	Timeout   int    `json:"timeout"`
+	APIBase   string `json:"apiBase"`
+	AppId     string `json:"appId"`
+	AppSecret string `json:"appSecret"`
+}
+
+// Client is a feishu open api client to reply messages, the tenant access token is cached
+// and refreshed automatically, so the client should be shared.
+type Client struct {
+	httpClient *http.Client
+	APIBase    string
+	AppId      string
+	AppSecret  string
+
+	tokenLock     sync.Mutex
+	token         string
+	tokenExpireAt time.Time
+}
+
+func NewClient(cfg *Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return NewClientWithHttpClient(cfg, &http.Client{Timeout: timeout})
+}
+
+func NewClientWithHttpClient(cfg *Config, httpClient *http.Client) *Client {
+	apiBase := cfg.APIBase
+	if apiBase == "" {
+		apiBase = DefaultAPIBase
+	}
+	return &Client{
+		httpClient: httpClient,
+		APIBase:    strings.TrimSuffix(apiBase, "/"),
+		AppId:      cfg.AppId,
+		AppSecret:  cfg.AppSecret,
+	}
+}
+
+// ReplyMessage replies to the message.
+// See https://open.feishu.cn/document/server-docs/im-v1/message/reply
+func (c *Client) ReplyMessage(messageId string, msgType string, content any) (err error) {
+	contentBytes, mErr := json.Marshal(content)
+	if mErr != nil {
+		err = fmt.Errorf("marshal message content error, %s", mErr.Error())
+		return
+	}
+	token, err := c.getTenantAccessToken()
+	if err != nil {
+		return
+	}
+	reqURL := fmt.Sprintf("%s/open-apis/im/v1/messages/%s/reply", c.APIBase, messageId)
+	var respBody ReplyMessageResponse
+	err = c.callAPI(reqURL, token, &ReplyMessageRequest{MsgType: msgType, Content: string(contentBytes)}, &respBody)
+	if err != nil {
+		return
+	}
+	if respBody.Code != 0 {
+		err = fmt.Errorf("call api error, %d: %s", respBody.Code, respBody.Msg)
+		return
+	}
+	return
+}
+
+// getTenantAccessToken returns the cached token or fetches a new one.
+// See https://open.feishu.cn/document/server-docs/authentication-management/access-token/tenant_access_token_internal
+func (c *Client) getTenantAccessToken() (token string, err error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpireAt) {
+		token = c.token
+		return
+	}
+	reqURL := fmt.Sprintf("%s/open-apis/auth/v3/tenant_access_token/internal", c.APIBase)
+	var respBody TenantAccessTokenResponse
+	err = c.callAPI(reqURL, "", &TenantAccessTokenRequest{AppId: c.AppId, AppSecret: c.AppSecret}, &respBody)
+	if err != nil {
+		err = fmt.Errorf("get tenant access token error, %s", err.Error())
+		return
+	}
+	if respBody.Code != 0 {
+		err = fmt.Errorf("get tenant access token error, %d: %s", respBody.Code, respBody.Msg)
+		return
+	}
+	c.token = respBody.TenantAccessToken
+	c.tokenExpireAt = time.Now().Add(time.Duration(respBody.Expire)*time.Second - tokenRefreshAhead)
+	token = c.token
+	return
+}
+
+func (c *Client) callAPI(reqURL string, token string, reqBody any, respBody any) (err error) {
+	reqBodyBytes, mErr := json.Marshal(reqBody)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	req, newErr := http.NewRequest(http.MethodPost, reqURL, bytes.NewReader(reqBodyBytes))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// check status code
+	if resp.StatusCode != http.StatusOK {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	decoder := json.NewDecoder(resp.Body)
+	if decErr := decoder.Decode(respBody); decErr != nil {
+		err = fmt.Errorf("parse response error, %s", decErr.Error())
+		return
+	}
+	return
+}
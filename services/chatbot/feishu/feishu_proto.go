@@ -0,0 +1,150 @@
+package feishu
+
+const (
+	EventTypeURLVerification = "url_verification"
+	EventTypeMessageReceive  = "im.message.receive_v1"
+)
+
+const (
+	MessageTypeText        = "text"
+	MessageTypeInteractive = "interactive"
+)
+
+// EncryptedBody is the callback body when the encrypt key is set
+type EncryptedBody struct {
+	Encrypt string `json:"encrypt"`
+}
+
+// CallbackBody is the decrypted callback body, it is either an url_verification request
+// or an event request of schema 2.0.
+// See https://open.feishu.cn/document/server-docs/event-subscription-guide/event-subscription-configure-/request-url-configuration-case
+type CallbackBody struct {
+	// fields of url_verification request
+	Challenge string `json:"challenge"`
+	Token     string `json:"token"`
+	Type      string `json:"type"`
+	// fields of event request
+	Schema string         `json:"schema"`
+	Header CallbackHeader `json:"header"`
+	Event  MessageEvent   `json:"event"`
+}
+
+// GetToken returns the verification token of both the url_verification and the event request
+func (b *CallbackBody) GetToken() string {
+	if b.Header.Token != "" {
+		return b.Header.Token
+	}
+	return b.Token
+}
+
+type CallbackHeader struct {
+	EventId    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	CreateTime string `json:"create_time"`
+	Token      string `json:"token"`
+	AppId      string `json:"app_id"`
+	TenantKey  string `json:"tenant_key"`
+}
+
+// MessageEvent is the event of im.message.receive_v1
+// See https://open.feishu.cn/document/server-docs/im-v1/message/events/receive
+type MessageEvent struct {
+	Sender  MessageSender  `json:"sender"`
+	Message ReceiveMessage `json:"message"`
+}
+
+type MessageSender struct {
+	SenderId   UserId `json:"sender_id"`
+	SenderType string `json:"sender_type"`
+	TenantKey  string `json:"tenant_key"`
+}
+
+type UserId struct {
+	UnionId string `json:"union_id"`
+	UserId  string `json:"user_id"`
+	OpenId  string `json:"open_id"`
+}
+
+type ReceiveMessage struct {
+	MessageId   string    `json:"message_id"`
+	RootId      string    `json:"root_id"`
+	ParentId    string    `json:"parent_id"`
+	CreateTime  string    `json:"create_time"`
+	ChatId      string    `json:"chat_id"`
+	ChatType    string    `json:"chat_type"`
+	MessageType string    `json:"message_type"`
+	Content     string    `json:"content"`
+	Mentions    []Mention `json:"mentions"`
+}
+
+type Mention struct {
+	Key  string `json:"key"`
+	Id   UserId `json:"id"`
+	Name string `json:"name"`
+}
+
+// TextContent is the content of text message, which is json encoded into the message content
+type TextContent struct {
+	Text string `json:"text"`
+}
+
+type TenantAccessTokenRequest struct {
+	AppId     string `json:"app_id"`
+	AppSecret string `json:"app_secret"`
+}
+
+type TenantAccessTokenResponse struct {
+	Code              int    `json:"code"`
+	Msg               string `json:"msg"`
+	TenantAccessToken string `json:"tenant_access_token"`
+	// Expire is in seconds
+	Expire int `json:"expire"`
+}
+
+type ReplyMessageRequest struct {
+	MsgType string `json:"msg_type"`
+	// Content is the json encoded string of the message content
+	Content string `json:"content"`
+}
+
+type ReplyMessageResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// Card is a simplified interactive message card.
+// See https://open.feishu.cn/document/common-capabilities/message-card/message-cards-content/card-structure/card-content
+type Card struct {
+	Config   CardConfig    `json:"config"`
+	Header   CardHeader    `json:"header"`
+	Elements []CardElement `json:"elements"`
+}
+
+type CardConfig struct {
+	WideScreenMode bool `json:"wide_screen_mode"`
+}
+
+type CardHeader struct {
+	Template string   `json:"template,omitempty"`
+	Title    CardText `json:"title"`
+}
+
+type CardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+// CardElement is either a div element with text or an action element with buttons
+type CardElement struct {
+	Tag     string       `json:"tag"`
+	Text    *CardText    `json:"text,omitempty"`
+	Actions []CardButton `json:"actions,omitempty"`
+	Layout  string       `json:"layout,omitempty"`
+}
+
+type CardButton struct {
+	Tag  string   `json:"tag"`
+	Text CardText `json:"text"`
+	Type string   `json:"type"`
+	URL  string   `json:"url"`
+}
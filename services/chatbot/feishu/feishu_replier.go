@@ -0,0 +1,79 @@
+package feishu
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+)
+
+const PlatformName = "feishu"
+
+// Replier replies the copilot results to the feishu message and @ the user who asks
+type Replier struct {
+	client    *Client
+	messageId string
+	openId    string
+}
+
+func NewReplier(client *Client, messageId, openId string) *Replier {
+	return &Replier{
+		client:    client,
+		messageId: messageId,
+		openId:    openId,
+	}
+}
+
+// NewUserMessage converts the im.message.receive_v1 event to the platform neutral user message
+func NewUserMessage(event *MessageEvent, client *Client) *message.UserMessage {
+	command, input := event.Message.GetUserInput()
+	openId := event.Sender.SenderId.OpenId
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        command,
+		Input:          input,
+		FromUserId:     openId,
+		ConversationId: event.Message.ChatId,
+		Replier:        NewReplier(client, event.Message.MessageId, openId),
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.client.ReplyMessage(r.messageId, MessageTypeText, &TextContent{
+		Text: fmt.Sprintf("<at user_id=\"%s\"></at> %s", r.openId, text),
+	})
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	return r.client.ReplyMessage(r.messageId, MessageTypeInteractive, CreateDashboardCard(r.openId, dashboards))
+}
+
+// CreateDashboardCard renders the dashboards as an interactive card with one button per dashboard
+func CreateDashboardCard(openId string, dashboards []grafana.Dashboard) *Card {
+	buttons := make([]CardButton, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		buttons = append(buttons, CardButton{
+			Tag:  "button",
+			Text: CardText{Tag: "plain_text", Content: dashboard.Title},
+			Type: "default",
+			URL:  dashboard.URL,
+		})
+	}
+	return &Card{
+		Config: CardConfig{WideScreenMode: true},
+		Header: CardHeader{
+			Template: "blue",
+			Title:    CardText{Tag: "plain_text", Content: "Grafana Copilot"},
+		},
+		Elements: []CardElement{
+			{
+				Tag:  "div",
+				Text: &CardText{Tag: "lark_md", Content: fmt.Sprintf("<at id=%s></at> 为您找到如下看板:", openId)},
+			},
+			{
+				Tag:     "action",
+				Layout:  "flow",
+				Actions: buttons,
+			},
+		},
+	}
+}
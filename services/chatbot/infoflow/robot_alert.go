@@ -0,0 +1,107 @@
+package infoflow
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+)
+
+const (
+	// maxAlertTextLength truncates the title, the alert names, summaries, values and dashboard titles
+	maxAlertTextLength = 100
+	// maxAlertMessageLength keeps the text of the notification within the 2k text limit, see the error 40061
+	maxAlertMessageLength = 1900
+	// alertFooterReserve reserves the space for the footer of the alerts not shown
+	alertFooterReserve = 100
+	alertTimeLayout    = "2006-01-02 15:04"
+)
+
+// NewAlertNotificationMessage formats the grafana alert notification with the dashboards suggested by
+// the copilot, only the first maxAlerts alerts are listed. The alerts are dropped from the end to keep
+// the text within maxAlertMessageLength bytes.
+func NewAlertNotificationMessage(groupIds []int, payload *grafana.WebhookPayload, maxAlerts int) *Message {
+	title := payload.Title
+	if title == "" {
+		title = fmt.Sprintf("[%s:%d] Grafana 告警通知", strings.ToUpper(payload.Status), len(payload.Alerts))
+	}
+	body := []MessageBody{newAlertText(truncateAlertText(title))}
+	budget := maxAlertMessageLength - getTextLength(body) - alertFooterReserve
+	var shownAlerts int
+	for index, alert := range payload.Alerts {
+		if index == maxAlerts {
+			break
+		}
+		alertBody := createAlertBody(index, alert)
+		if budget -= getTextLength(alertBody); budget < 0 {
+			break
+		}
+		body = append(body, alertBody...)
+		shownAlerts++
+	}
+	if hiddenAlerts := len(payload.Alerts) - shownAlerts + payload.TruncatedAlerts; hiddenAlerts > 0 {
+		body = append(body, newAlertText(fmt.Sprintf("\n\n另有 %d 条告警未展示", hiddenAlerts)))
+	}
+	return &Message{
+		Header: MessageHeader{ToId: groupIds},
+		Body:   body,
+	}
+}
+
+// createAlertBody formats the alert with its summary, value and links
+func createAlertBody(index int, alert grafana.WebhookAlert) (body []MessageBody) {
+	addText := func(text string) {
+		body = append(body, newAlertText(text))
+	}
+	addLink := func(title, link string) {
+		addText(fmt.Sprintf("\n%s: ", title))
+		body = append(body, MessageBody{Type: MessageBodyTypeLink, Href: link})
+	}
+	name := truncateAlertText(alert.GetName())
+	if alert.Status == grafana.WebhookStatusResolved {
+		addText(fmt.Sprintf("\n\n%d. [已恢复] %s，恢复于 %s", index+1, name, alert.EndsAt.Local().Format(alertTimeLayout)))
+	} else {
+		addText(fmt.Sprintf("\n\n%d. [告警中] %s，开始于 %s", index+1, name, alert.StartsAt.Local().Format(alertTimeLayout)))
+	}
+	if summary := alert.GetSummary(); summary != "" {
+		addText(fmt.Sprintf("\n摘要: %s", truncateAlertText(summary)))
+	}
+	if alert.ValueString != "" {
+		addText(fmt.Sprintf("\n取值: %s", truncateAlertText(alert.ValueString)))
+	}
+	for _, dashboard := range alert.Dashboards {
+		dashboardTitle := dashboard.Title
+		if dashboard.Source != "" {
+			dashboardTitle = fmt.Sprintf("[%s] %s", dashboard.Source, dashboardTitle)
+		}
+		addLink(fmt.Sprintf("相关看板 %s", truncateAlertText(dashboardTitle)), dashboard.URL)
+	}
+	if alert.GeneratorURL != "" {
+		addLink("告警规则", alert.GeneratorURL)
+	}
+	if alert.SilenceURL != "" && alert.Status != grafana.WebhookStatusResolved {
+		addLink("静默告警", alert.SilenceURL)
+	}
+	return
+}
+
+func newAlertText(text string) MessageBody {
+	return MessageBody{Type: MessageBodyTypeText, Content: text}
+}
+
+// getTextLength returns the total length of the text bodies, the links are limited separately
+func getTextLength(body []MessageBody) (length int) {
+	for _, item := range body {
+		if item.Type == MessageBodyTypeText {
+			length += len(item.Content)
+		}
+	}
+	return
+}
+
+func truncateAlertText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxAlertTextLength {
+		return text
+	}
+	return string(runes[:maxAlertTextLength]) + "..."
+}
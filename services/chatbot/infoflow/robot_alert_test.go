@@ -0,0 +1,116 @@
+package infoflow
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAlert(name, summary string, dashboards int) grafana.WebhookAlert {
+	alert := grafana.WebhookAlert{
+		Status:       grafana.WebhookStatusFiring,
+		Labels:       map[string]string{grafana.AlertNameLabel: name},
+		Annotations:  map[string]string{"summary": summary},
+		StartsAt:     time.Now(),
+		GeneratorURL: "http://grafana.local/alerting/grafana/abc/view",
+		SilenceURL:   "http://grafana.local/alerting/silence/new",
+	}
+	for index := 0; index < dashboards; index++ {
+		alert.Dashboards = append(alert.Dashboards, grafana.Dashboard{
+			Title: fmt.Sprintf("%s dashboard %d", name, index),
+			URL:   fmt.Sprintf("http://grafana.local/d/%d", index),
+		})
+	}
+	return alert
+}
+
+func getMessageText(message *Message) string {
+	var buf strings.Builder
+	for _, body := range message.Body {
+		if body.Type == MessageBodyTypeText {
+			buf.WriteString(body.Content)
+		}
+	}
+	return buf.String()
+}
+
+func TestNewAlertNotificationMessage(t *testing.T) {
+	longText := strings.Repeat("告警", 200)
+	testCases := []struct {
+		name            string
+		payload         grafana.WebhookPayload
+		maxAlerts       int
+		wantShownAlerts int
+		wantHidden      int
+	}{
+		{
+			name:            "all alerts shown",
+			payload:         grafana.WebhookPayload{Status: "firing", Alerts: []grafana.WebhookAlert{newTestAlert("cpu", "high cpu", 2)}},
+			maxAlerts:       5,
+			wantShownAlerts: 1,
+		},
+		{
+			name: "max alerts",
+			payload: grafana.WebhookPayload{Status: "firing", TruncatedAlerts: 3, Alerts: []grafana.WebhookAlert{
+				newTestAlert("a", "", 0), newTestAlert("b", "", 0), newTestAlert("c", "", 0)}},
+			maxAlerts:       2,
+			wantShownAlerts: 2,
+			wantHidden:      4,
+		},
+		{
+			name: "long texts are truncated and the alerts are dropped to fit the limit",
+			payload: grafana.WebhookPayload{Title: longText, Status: "firing", Alerts: []grafana.WebhookAlert{
+				newTestAlert(longText, longText, 2), newTestAlert(longText, longText, 2), newTestAlert(longText, longText, 2),
+				newTestAlert(longText, longText, 2), newTestAlert(longText, longText, 2)}},
+			maxAlerts: 5,
+			// each alert takes more than 1k bytes after truncated
+			wantShownAlerts: 1,
+			wantHidden:      4,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			message := NewAlertNotificationMessage([]int{1}, &testCase.payload, testCase.maxAlerts)
+			text := getMessageText(message)
+			if len(text) > maxAlertMessageLength {
+				t.Errorf("text length = %d, exceeds %d", len(text), maxAlertMessageLength)
+			}
+			if shown := strings.Count(text, "[告警中]"); shown != testCase.wantShownAlerts {
+				t.Errorf("shown alerts = %d, want %d", shown, testCase.wantShownAlerts)
+			}
+			hiddenText := fmt.Sprintf("另有 %d 条告警未展示", testCase.wantHidden)
+			if (testCase.wantHidden > 0) != strings.Contains(text, hiddenText) || strings.Contains(text, "未展示") != (testCase.wantHidden > 0) {
+				t.Errorf("text %q, want hidden alerts %d", text, testCase.wantHidden)
+			}
+			for _, body := range message.Body {
+				if body.Type == MessageBodyTypeText && len([]rune(body.Content)) > maxAlertTextLength+50 {
+					t.Errorf("text body is not truncated: %q", body.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateAlertBody(t *testing.T) {
+	resolved := newTestAlert("disk", "disk full", 1)
+	resolved.Status = grafana.WebhookStatusResolved
+	resolved.EndsAt = time.Now()
+	resolved.Dashboards[0].Source = "staging"
+	body := createAlertBody(0, resolved)
+	var links []string
+	for _, item := range body {
+		if item.Type == MessageBodyTypeLink {
+			links = append(links, item.Href)
+		}
+	}
+	text := getMessageText(&Message{Body: body})
+	if !strings.Contains(text, "1. [已恢复] disk") || !strings.Contains(text, "相关看板 [staging] disk dashboard 0") {
+		t.Errorf("text = %q", text)
+	}
+	// the silence link is omitted for the resolved alerts
+	if len(links) != 2 || links[0] != resolved.Dashboards[0].URL || links[1] != resolved.GeneratorURL {
+		t.Errorf("links = %v, want the dashboard and the alert rule", links)
+	}
+}
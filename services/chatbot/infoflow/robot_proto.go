@@ -62,6 +62,9 @@ type MessageBody struct {
 
 const ErrNone = 0
 
+// MaxImageSize is the max size of the image message, see the error 40066
+const MaxImageSize = 1024 * 1024
+
 var ErrorMap = map[int]string{
 	-1:    "系统错误",
 	40000: "参数错误",
@@ -0,0 +1,83 @@
+package infoflow
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strconv"
+)
+
+const PlatformName = "infoflow"
+
+// Replier sends the copilot replies to the infoflow group and @ the user who asks
+type Replier struct {
+	client     *Client
+	groupId    int
+	fromUserId string
+}
+
+func NewReplier(client *Client, groupId int, fromUserId string) *Replier {
+	return &Replier{
+		client:     client,
+		groupId:    groupId,
+		fromUserId: fromUserId,
+	}
+}
+
+// NewUserMessage converts the infoflow callback body to the platform neutral user message
+func NewUserMessage(callbackBody *CallbackBody, client *Client) *message.UserMessage {
+	fromUserId := callbackBody.Message.Header.FromUserId
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        callbackBody.Message.GetUserCommand(),
+		Input:          callbackBody.Message.GetUserInput(),
+		FromUserId:     fromUserId,
+		ConversationId: strconv.Itoa(callbackBody.GroupId),
+		Replier:        NewReplier(client, callbackBody.GroupId, fromUserId),
+	}
+}
+
+func (r *Replier) ReplyText(text string) (err error) {
+	options := MessageOptions{AtUserIds: []string{r.fromUserId}}
+	_, err = r.client.SendTextMessage([]int{r.groupId}, text, &options)
+	return
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) (err error) {
+	body := make([]MessageBody, 0, 2)
+	body = append(body, MessageBody{
+		Type:    MessageBodyTypeText,
+		Content: "为您找到如下看板:",
+	})
+	// add the suggested kanban links
+	for _, dashboard := range dashboards {
+		body = append(body, MessageBody{
+			Type:    MessageBodyTypeText,
+			Content: fmt.Sprintf("\n%s: ", dashboard.Title),
+		})
+		body = append(body, MessageBody{
+			Type: MessageBodyTypeLink,
+			Href: dashboard.URL,
+		})
+	}
+	// check the options
+	options := MessageOptions{AtUserIds: []string{r.fromUserId}}
+	body = append(body, options.CreateAtBody())
+	msg := Message{
+		Header: MessageHeader{ToId: []int{r.groupId}},
+		Body:   body,
+	}
+	_, err = r.client.SendMessage(&msg)
+	return
+}
+
+// MaxImageSize returns the max size of the raw image, the limit is applied to the base64 encoded content
+// to be safe, which is 4/3 of the raw image size.
+func (r *Replier) MaxImageSize() int {
+	return MaxImageSize / 4 * 3
+}
+
+func (r *Replier) ReplyImage(image []byte) (err error) {
+	_, err = r.client.SendImageMessage([]int{r.groupId}, image)
+	return
+}
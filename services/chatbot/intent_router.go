@@ -0,0 +1,120 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"log/slog"
+	"time"
+)
+
+const (
+	IntentDashboardSearch = "dashboard_search"
+	IntentAlertQuery      = "alert_query"
+	IntentMetricQuery     = "metric_query"
+	IntentChitChat        = "chitchat"
+)
+
+// IntentConfidenceThreshold is the min confidence to dispatch the message, a clarifying question
+// is replied if the confidence is lower.
+const IntentConfidenceThreshold = 0.6
+
+// intentCommands maps the intents to the commands handling them
+var intentCommands = map[string]string{
+	IntentDashboardSearch: GrafanaCmd,
+	IntentAlertQuery:      AlertsCmd,
+	IntentMetricQuery:     PromQLCmd,
+}
+
+// Intent is the classification result of the user message returned by the llm
+type Intent struct {
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+	// Reply is the short answer of the chitchat
+	Reply string `json:"reply"`
+	// Question is the clarifying question when the confidence is low
+	Question string `json:"question"`
+}
+
+// handleNaturalLanguage classifies the message without slash command and dispatches it to the command of the intent.
+// The classification and the dispatched command share one llm timeout, so that a slow llm makes the user wait
+// at most one timeout before the keyword matching fallback.
+func handleNaturalLanguage(userMessage *message.UserMessage) {
+	if userMessage.Input == "" {
+		NotifyUserText(userMessage, CreateHelpMessage())
+		return
+	}
+	ctx := context.Background()
+	if conf.AppConfig.CopilotLLMTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conf.AppConfig.CopilotLLMTimeout)*time.Second)
+		defer cancel()
+	}
+	// search the dashboards directly if the llm is disabled or unavailable
+	if conf.AppConfig.IsLexicalMode() {
+		dispatchCommand(ctx, userMessage, GrafanaCmd)
+		return
+	}
+	intent, err := ClassifyIntent(ctx, userMessage.Input)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Classify intent err: %v, fallback to dashboard search", err))
+		dispatchCommand(ctx, userMessage, GrafanaCmd)
+		return
+	}
+	slog.Debug(fmt.Sprintf("classified intent: %s, confidence: %.2f", intent.Intent, intent.Confidence))
+	if intent.Confidence < IntentConfidenceThreshold {
+		question := intent.Question
+		if question == "" {
+			question = "没有理解您的问题，您是想查找看板、查询告警还是查询指标呢？"
+		}
+		NotifyUserText(userMessage, question)
+		return
+	}
+	if intent.Intent == IntentChitChat {
+		reply := intent.Reply
+		if reply == "" {
+			reply = CreateHelpMessage()
+		}
+		NotifyUserText(userMessage, reply)
+		return
+	}
+	commandName, ok := intentCommands[intent.Intent]
+	if !ok {
+		NotifyUserText(userMessage, CreateHelpMessage())
+		return
+	}
+	dispatchCommand(ctx, userMessage, commandName)
+}
+
+// dispatchCommand handles the message by the command as if it is sent with the slash command
+func dispatchCommand(ctx context.Context, userMessage *message.UserMessage, commandName string) {
+	command := FindCommand(commandName)
+	if command == nil {
+		NotifyUserText(userMessage, fmt.Sprintf("当前部署暂不支持该类问题，发送 /%s 查看所有可用的命令", HelpCmd))
+		return
+	}
+	userMessage.Command = command.Name
+	command.Handler(ctx, userMessage)
+}
+
+// ClassifyIntent asks the llm to classify the intent of the user input
+func ClassifyIntent(ctx context.Context, userInput string) (intent Intent, err error) {
+	systemMessage, err := RenderTemplate(GetPromptPath("intent_classify_prompt.md"), nil)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		return
+	}
+	jsonOutput := ernie.GetResponseJsonContent(llmOutput)
+	if err = json.Unmarshal([]byte(jsonOutput), &intent); err != nil {
+		err = fmt.Errorf("parse intent err: %w", err)
+		return
+	}
+	return
+}
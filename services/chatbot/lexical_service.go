@@ -0,0 +1,92 @@
+package chatbot
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"strings"
+)
+
+const (
+	// maxLexicalResults limits the dashboards or panels returned by the keyword matching
+	maxLexicalResults = 5
+	// minLexicalScoreRatio drops the results scored much lower than the best one
+	minLexicalScoreRatio = 0.5
+)
+
+// MatchDashboardsLexically matches the dashboards by the keywords in their titles, tags and folders
+// without any llm, which is used in the lexical search mode and when the llm is unavailable.
+func MatchDashboardsLexically(userInput string, dashboardMetas []grafana.Dashboard) (suggestedDashboards []grafana.Dashboard) {
+	dashboardMetaMap := make(map[string]grafana.Dashboard, len(dashboardMetas))
+	documents := make([]retrieval.LexicalDocument, 0, len(dashboardMetas))
+	for _, dashboardMeta := range dashboardMetas {
+		dashboardMetaMap[dashboardMeta.Key()] = dashboardMeta
+		documents = append(documents, retrieval.LexicalDocument{
+			Id: dashboardMeta.Key(),
+			Fields: []retrieval.LexicalField{
+				{Text: dashboardMeta.Title, Weight: 3},
+				{Text: strings.Join(dashboardMeta.Tags, " "), Weight: 2},
+				{Text: dashboardMeta.FolderTitle, Weight: 1},
+			},
+		})
+	}
+	results := searchLexically(documents, userInput, maxLexicalResults)
+	suggestedDashboards = make([]grafana.Dashboard, 0, len(results))
+	for _, result := range results {
+		dashboard := dashboardMetaMap[result.Id]
+		suggestedDashboards = append(suggestedDashboards, createDashboardSuggestion(dashboard, dashboard.Title,
+			createLexicalReason(result.MatchedTerms)))
+	}
+	return
+}
+
+// matchPanelsLexically matches the panels by the keywords in their titles, descriptions and queries
+func matchPanelsLexically(userInput string, candidates []panelCandidate) (suggestedPanels []grafana.Dashboard) {
+	candidateMap := make(map[string]panelCandidate, len(candidates))
+	documents := make([]retrieval.LexicalDocument, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidateMap[candidate.Key] = candidate
+		documents = append(documents, retrieval.LexicalDocument{
+			Id: candidate.Key,
+			Fields: []retrieval.LexicalField{
+				{Text: candidate.Panel.Title, Weight: 3},
+				{Text: candidate.DashboardDetail.Dashboard.Title, Weight: 1},
+				{Text: candidate.Panel.Description, Weight: 1},
+				{Text: strings.Join(candidate.Panel.GetQueries(), " "), Weight: 1},
+			},
+		})
+	}
+	results := searchLexically(documents, userInput, maxLexicalResults)
+	suggestedPanels = make([]grafana.Dashboard, 0, len(results))
+	for _, result := range results {
+		suggestedPanels = append(suggestedPanels, createPanelSuggestion(candidateMap[result.Id],
+			createLexicalReason(result.MatchedTerms)))
+	}
+	return
+}
+
+func searchLexically(documents []retrieval.LexicalDocument, userInput string, topK int) (results []retrieval.LexicalResult) {
+	results = retrieval.NewLexicalIndex(documents).Search(userInput, topK)
+	for index, result := range results {
+		if result.Score < results[0].Score*minLexicalScoreRatio {
+			results = results[:index]
+			break
+		}
+	}
+	return
+}
+
+// createLexicalReason lists the matched keywords, the single chinese characters are omitted
+// if any longer term is matched since they are mostly part of the longer ones.
+func createLexicalReason(matchedTerms []string) string {
+	keywords := make([]string, 0, len(matchedTerms))
+	for _, term := range matchedTerms {
+		if len([]rune(term)) > 1 {
+			keywords = append(keywords, term)
+		}
+	}
+	if len(keywords) == 0 {
+		keywords = matchedTerms
+	}
+	return fmt.Sprintf("关键词匹配：%s", strings.Join(keywords, ", "))
+}
@@ -0,0 +1,127 @@
+package chatbot
+
+import (
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"testing"
+)
+
+// testGrafanaHost is the host of the single grafana instance in the tests
+const testGrafanaHost = "http://grafana.local"
+
+// setTestConfig sets the app config of the tests, the grafana clients are created once with the first config
+func setTestConfig(t *testing.T, config conf.Config) {
+	config.GrafanaInstances = []conf.GrafanaInstance{{Host: testGrafanaHost}}
+	previous := conf.AppConfig
+	conf.AppConfig = &config
+	t.Cleanup(func() {
+		conf.AppConfig = previous
+	})
+}
+
+func TestMatchDashboardsLexically(t *testing.T) {
+	setTestConfig(t, conf.Config{})
+	dashboardMetas := []grafana.Dashboard{
+		{Uid: "kafka", Title: "Kafka Overview", URL: "/d/kafka/kafka-overview", FolderTitle: "Middleware"},
+		{Uid: "redis", Title: "Redis Overview", URL: "/d/redis/redis-overview", FolderTitle: "Middleware"},
+		{Uid: "order", Title: "订单服务", URL: "/d/order/order", Tags: []string{"checkout"}, FolderTitle: "Business"},
+	}
+	testCases := []struct {
+		name       string
+		userInput  string
+		wantUids   []string
+		wantReason string
+	}{
+		{name: "title", userInput: "kafka 的消费延迟", wantUids: []string{"kafka"}, wantReason: "关键词匹配：kafka"},
+		{name: "typo", userInput: "redsi", wantUids: []string{"redis"}, wantReason: "关键词匹配：redis"},
+		{name: "tags", userInput: "checkout", wantUids: []string{"order"}, wantReason: "关键词匹配：checkout"},
+		{name: "chinese", userInput: "订单", wantUids: []string{"order"}, wantReason: "关键词匹配：订单"},
+		{name: "no match", userInput: "mysql", wantUids: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			suggested := MatchDashboardsLexically(testCase.userInput, dashboardMetas)
+			if len(suggested) != len(testCase.wantUids) {
+				t.Fatalf("suggested = %v, want %v", suggested, testCase.wantUids)
+			}
+			for index, dashboard := range suggested {
+				if dashboard.Uid != testCase.wantUids[index] {
+					t.Errorf("suggested %d = %s, want %s", index, dashboard.Uid, testCase.wantUids[index])
+				}
+			}
+			if len(suggested) > 0 {
+				if suggested[0].Reason != testCase.wantReason {
+					t.Errorf("reason = %q, want %q", suggested[0].Reason, testCase.wantReason)
+				}
+				if suggested[0].URL != testGrafanaHost+dashboardMetas[indexOfDashboard(dashboardMetas, suggested[0].Uid)].URL {
+					t.Errorf("url = %s, want the full url", suggested[0].URL)
+				}
+			}
+		})
+	}
+}
+
+func indexOfDashboard(dashboards []grafana.Dashboard, uid string) int {
+	for index, dashboard := range dashboards {
+		if dashboard.Uid == uid {
+			return index
+		}
+	}
+	return -1
+}
+
+func TestMatchPanelsLexically(t *testing.T) {
+	setTestConfig(t, conf.Config{})
+	dashboardDetail := grafana.DashboardDetail{
+		Dashboard: grafana.DashboardModel{
+			Uid:   "checkout",
+			Title: "Checkout",
+			Panels: []grafana.Panel{
+				{Id: 1, Title: "Requests", Targets: []grafana.PanelTarget{{Expr: "sum(rate(http_requests_total[5m]))"}}},
+				{Id: 2, Title: "P99 Latency", Description: "the p99 latency of the checkout api"},
+				{Id: 3, Title: "CPU Usage"},
+			},
+		},
+		Meta: grafana.DashboardMeta{URL: "/d/checkout/checkout"},
+	}
+	candidates := createPanelCandidates([]grafana.DashboardDetail{dashboardDetail})
+	testCases := []struct {
+		name      string
+		userInput string
+		wantURLs  []string
+	}{
+		{name: "title", userInput: "cpu", wantURLs: []string{testGrafanaHost + "/d/checkout/checkout?viewPanel=3"}},
+		{name: "description", userInput: "p99 延迟", wantURLs: []string{testGrafanaHost + "/d/checkout/checkout?viewPanel=2"}},
+		{name: "query", userInput: "http_requests_total", wantURLs: []string{testGrafanaHost + "/d/checkout/checkout?viewPanel=1"}},
+		{name: "no match", userInput: "disk", wantURLs: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			suggested := matchPanelsLexically(testCase.userInput, candidates)
+			if len(suggested) != len(testCase.wantURLs) {
+				t.Fatalf("suggested = %v, want %v", suggested, testCase.wantURLs)
+			}
+			for index, panel := range suggested {
+				if panel.URL != testCase.wantURLs[index] {
+					t.Errorf("url %d = %s, want %s", index, panel.URL, testCase.wantURLs[index])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateLexicalReason(t *testing.T) {
+	testCases := []struct {
+		name         string
+		matchedTerms []string
+		want         string
+	}{
+		{name: "single chinese characters omitted", matchedTerms: []string{"订", "订单", "kafka"}, want: "关键词匹配：订单, kafka"},
+		{name: "only single characters", matchedTerms: []string{"订", "单"}, want: "关键词匹配：订, 单"},
+	}
+	for _, testCase := range testCases {
+		if got := createLexicalReason(testCase.matchedTerms); got != testCase.want {
+			t.Errorf("%s: createLexicalReason = %q, want %q", testCase.name, got, testCase.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxVariableOptions limits the options of each variable in the prompt
+const maxVariableOptions = 20
+
+// linkTimeHintRegexp matches the time range in the user input, e.g. 最近3小时, 昨天, last 2h or 2024-01-01 10:00
+var linkTimeHintRegexp = regexp.MustCompile(`(?i)\d+\s*(s|m|h|d|w|mins?|minutes?|hours?|days?|weeks?|months?)\b|` +
+	`\b(today|yesterday|last|past|ago|since)\b|\d{4}-\d{1,2}-\d{1,2}|\d{1,2}:\d{2}|\d{1,2}点|` +
+	`秒|分钟|小时|天|周|星期|月|年|最近|过去|凌晨|早上|上午|中午|下午|晚上`)
+
+// linkVariableHintRegexp matches the variable values given explicitly in the user input, e.g. env=prod
+var linkVariableHintRegexp = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*\s*=\s*\S`)
+
+// LinkParams are the time range and the variable values extracted from the user input
+type LinkParams struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Variables map[string]string `json:"variables"`
+}
+
+type GrafanaLinkParamsContext struct {
+	Now              string
+	GrafanaVariables string
+}
+
+// ExtractLinkParams asks the llm to extract the time range and the values of the variables
+// defined in the dashboards from the user input
+func ExtractLinkParams(ctx context.Context, userInput string, dashboardDetails []grafana.DashboardDetail) (params LinkParams, err error) {
+	// convert the variables to markdown table, the variables with the same name are merged
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|Name|Label|Type|Options|\n")
+	markdownBuf.WriteString("|---|---|---|---|\n")
+	seen := make(map[string]bool)
+	for _, dashboardDetail := range dashboardDetails {
+		for _, variable := range dashboardDetail.Dashboard.Templating.List {
+			if _, ok := dashboardDetail.Dashboard.GetVariable(variable.Name); !ok || seen[variable.Name] {
+				continue
+			}
+			optionValues := variable.GetOptionValues()
+			// the values of the variables without any known value can't be verified
+			if len(optionValues) == 0 && !variable.AcceptsAnyValue() {
+				continue
+			}
+			seen[variable.Name] = true
+			if len(optionValues) > maxVariableOptions {
+				optionValues = optionValues[:maxVariableOptions]
+			}
+			markdownBuf.WriteString(fmt.Sprintf("|%s|%s|%s|%s|\n", variable.Name, escapeMarkdownCell(variable.Label),
+				variable.Type, escapeMarkdownCell(strings.Join(optionValues, ", "))))
+		}
+	}
+	renderCtx := GrafanaLinkParamsContext{
+		Now:              time.Now().Format(time.RFC3339),
+		GrafanaVariables: markdownBuf.String(),
+	}
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_link_params_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		return
+	}
+	jsonOutput := ernie.GetResponseJsonContent(llmOutput)
+	if err = json.Unmarshal([]byte(jsonOutput), &params); err != nil {
+		err = fmt.Errorf("parse link params err: %w", err)
+		return
+	}
+	return
+}
+
+// CreateLinkParams validates the params against the dashboard and returns the from, to and var-<name>
+// params of the dashboard URL. The invalid time range and the unknown variables or values are dropped,
+// see TemplateVariable.MatchValue.
+func CreateLinkParams(params LinkParams, dashboard grafana.DashboardModel) url.Values {
+	urlParams := url.Values{}
+	if grafana.IsValidTime(params.From) {
+		urlParams.Set("from", params.From)
+		if grafana.IsValidTime(params.To) {
+			urlParams.Set("to", params.To)
+		} else {
+			urlParams.Set("to", "now")
+		}
+	}
+	for name, value := range params.Variables {
+		variable, ok := dashboard.GetVariable(name)
+		if !ok {
+			continue
+		}
+		if matched, ok := variable.MatchValue(strings.TrimSpace(value)); ok {
+			urlParams.Set(fmt.Sprintf("var-%s", variable.Name), matched)
+		}
+	}
+	return urlParams
+}
+
+// needsLinkParams checks whether the user input may contain any link param, that is a time range, an explicit
+// variable value, or any option of the variables of the dashboards, so that the llm is not called for nothing.
+func needsLinkParams(userInput string, dashboardDetails []grafana.DashboardDetail) bool {
+	if linkTimeHintRegexp.MatchString(userInput) || linkVariableHintRegexp.MatchString(userInput) {
+		return true
+	}
+	inputTerms := make(map[string]bool)
+	for _, term := range retrieval.Tokenize(userInput) {
+		inputTerms[term] = true
+	}
+	containsAllTerms := func(text string) bool {
+		terms := retrieval.Tokenize(text)
+		for _, term := range terms {
+			if !inputTerms[term] {
+				return false
+			}
+		}
+		return len(terms) > 0
+	}
+	for _, dashboardDetail := range dashboardDetails {
+		for _, variable := range dashboardDetail.Dashboard.Templating.List {
+			if _, ok := dashboardDetail.Dashboard.GetVariable(variable.Name); !ok {
+				continue
+			}
+			// the textbox variables accept any value, so the variable itself should be mentioned
+			if variable.AcceptsAnyValue() && (containsAllTerms(variable.Name) || containsAllTerms(variable.Label)) {
+				return true
+			}
+			for _, value := range variable.GetOptionValues() {
+				// skip the special values, e.g. $__all
+				if !strings.HasPrefix(value, "$") && containsAllTerms(value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// applyLinkParams adds the time range and the variable values in the user input to the URLs of the
+// suggested dashboards or panels. The URLs are kept unchanged if the extraction fails, or if the user
+// input does not mention any link param, see needsLinkParams.
+func applyLinkParams(ctx context.Context, userInput string, suggestedDashboards []grafana.Dashboard,
+	dashboardDetails []grafana.DashboardDetail) {
+	if len(suggestedDashboards) == 0 {
+		return
+	}
+	if !needsLinkParams(userInput, dashboardDetails) {
+		slog.Debug("no link params in the user input, skip the extraction")
+		return
+	}
+	params, err := ExtractLinkParams(ctx, userInput, dashboardDetails)
+	if err != nil {
+		slog.Error(fmt.Sprintf("extract link params err: %v", err))
+		return
+	}
+	slog.Debug(fmt.Sprintf("extracted link params: %+v", params))
+	dashboardMap := make(map[string]grafana.DashboardModel, len(dashboardDetails))
+	for _, dashboardDetail := range dashboardDetails {
+		dashboardMap[dashboardDetail.Key()] = dashboardDetail.Dashboard
+	}
+	for index, dashboard := range suggestedDashboards {
+		// only the time range is applied if the dashboard json model fails to be fetched
+		urlParams := CreateLinkParams(params, dashboardMap[dashboard.Key()])
+		if len(urlParams) > 0 {
+			suggestedDashboards[index].URL = grafana.AddURLParams(dashboard.URL, urlParams)
+		}
+	}
+}
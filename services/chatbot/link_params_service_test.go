@@ -0,0 +1,74 @@
+package chatbot
+
+import (
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"testing"
+)
+
+func TestCreateLinkParams(t *testing.T) {
+	dashboard := grafana.DashboardModel{
+		Templating: grafana.Templating{List: []grafana.TemplateVariable{
+			{Name: "env", Type: "custom", Options: []grafana.VariableOption{{Text: "Prod", Value: "Prod"}}},
+			{Name: "host", Type: "query", Current: grafana.VariableOption{Text: "web-1", Value: "web-1"}},
+			{Name: "service", Type: "query"},
+			{Name: "filter", Type: "textbox"},
+			{Name: "region", Type: "constant", Current: grafana.VariableOption{Text: "cn", Value: "cn"}},
+		}},
+	}
+	testCases := []struct {
+		name   string
+		params LinkParams
+		want   string
+	}{
+		{name: "time range", params: LinkParams{From: "now-3h", To: "now-1h"}, want: "from=now-3h&to=now-1h"},
+		{name: "invalid to", params: LinkParams{From: "now-3h", To: "yesterday"}, want: "from=now-3h&to=now"},
+		{name: "invalid from", params: LinkParams{From: "yesterday", To: "now"}, want: ""},
+		{name: "variables", params: LinkParams{Variables: map[string]string{"ENV": " prod ", "host": "web-1", "filter": "code=500"}},
+			want: "var-env=Prod&var-filter=code%3D500&var-host=web-1"},
+		{name: "unknown values are dropped", params: LinkParams{Variables: map[string]string{"env": "dev", "host": "web-2"}}, want: ""},
+		{name: "unverifiable values are dropped", params: LinkParams{Variables: map[string]string{"service": "checkout"}}, want: ""},
+		{name: "constant and unknown variables are dropped", params: LinkParams{Variables: map[string]string{"region": "cn", "zone": "a"}}, want: ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := CreateLinkParams(testCase.params, dashboard).Encode(); got != testCase.want {
+				t.Errorf("CreateLinkParams = %q, want %q", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestNeedsLinkParams(t *testing.T) {
+	dashboardDetails := []grafana.DashboardDetail{{Dashboard: grafana.DashboardModel{
+		Templating: grafana.Templating{List: []grafana.TemplateVariable{
+			{Name: "env", Type: "custom", Options: []grafana.VariableOption{{Text: "All", Value: "$__all"},
+				{Text: "生产", Value: "生产"}, {Text: "order-service", Value: "order-service"}}},
+			{Name: "filter", Label: "过滤条件", Type: "textbox"},
+			{Name: "region", Type: "constant", Current: grafana.VariableOption{Text: "kafka", Value: "kafka"}},
+		}},
+	}}}
+	testCases := []struct {
+		name      string
+		userInput string
+		want      bool
+	}{
+		{name: "no hint", userInput: "kafka 消费延迟", want: false},
+		{name: "chinese time range", userInput: "最近3小时的 kafka 消费延迟", want: true},
+		{name: "yesterday", userInput: "昨天的订单量", want: true},
+		{name: "english time range", userInput: "kafka lag in the last 2h", want: true},
+		{name: "absolute time", userInput: "kafka lag at 2024-01-01 10:00", want: true},
+		{name: "explicit variable", userInput: "kafka lag topic=orders", want: true},
+		{name: "option value", userInput: "生产环境的 kafka 消费延迟", want: true},
+		{name: "multi-word option value", userInput: "order service 的错误率", want: true},
+		{name: "special option value", userInput: "all kafka lag", want: false},
+		{name: "textbox variable label", userInput: "按过滤条件 code 500 查看错误", want: true},
+		{name: "constant variable", userInput: "kafka lag", want: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := needsLinkParams(testCase.userInput, dashboardDetails); got != testCase.want {
+				t.Errorf("needsLinkParams(%q) = %v, want %v", testCase.userInput, got, testCase.want)
+			}
+		})
+	}
+}
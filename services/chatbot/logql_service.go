@@ -0,0 +1,263 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	LogsCmd = "logs"
+)
+
+const (
+	// maxLogQLAttempts is the times to ask the llm, the query error is sent back to fix the query
+	maxLogQLAttempts = 2
+	// maxReplyLogLines limits the log lines queried and listed in the reply
+	maxReplyLogLines = 20
+	// maxLogLineLength truncates the long log lines in the reply
+	maxLogLineLength = 120
+	// maxQueryReplyLength keeps the LogQL and PromQL replies within the 2k text limit of infoflow, see the error 40061
+	maxQueryReplyLength = 1900
+	// maxReplyQueryLength and maxReplyExplanationLength truncate the query and the explanation written by the llm
+	maxReplyQueryLength       = 300
+	maxReplyExplanationLength = 150
+	// maxReplyURLLength drops the explore link which is too long, since a truncated link is broken
+	maxReplyURLLength = 800
+	defaultLogQLFrom  = "now-1h"
+	defaultLogQLTo    = "now"
+)
+
+type GrafanaLogQLContext struct {
+	Datasource    string
+	Now           string
+	Labels        string
+	PreviousQuery string
+	PreviousError string
+}
+
+// LogQLAnswer is the query and the time range written by the llm
+type LogQLAnswer struct {
+	Query       string `json:"query"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Explanation string `json:"explanation"`
+}
+
+// LogQLResult is the validated query and its log lines
+type LogQLResult struct {
+	Datasource  grafana.Datasource
+	Query       string
+	From        string
+	To          string
+	Explanation string
+	LogLines    []grafana.LogLine
+	ExploreURL  string
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        LogsCmd,
+		Aliases:     []string{"loki", "logql", "日志"},
+		Usage:       "/logs <问题>，例如 /logs gateway 最近15分钟的错误日志",
+		Description: "根据问题编写 LogQL 并在 Loki 数据源上执行，返回最新的日志和 Explore 链接",
+		Handler:     handleLogsCommand,
+	})
+}
+
+// handleLogsCommand generates and runs the logql of the user input
+func handleLogsCommand(ctx context.Context, userMessage *message.UserMessage) {
+	result, err := GenerateLogQL(ctx, userMessage.Input)
+	if err != nil {
+		errMsg := fmt.Sprintf("Handle grafana logs copilot err: %s", err.Error())
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	NotifyUserText(userMessage, CreateLogQLMessage(result))
+}
+
+// GenerateLogQL asks the llm to write the logql with the stream labels of the loki datasource, and validates
+// the query by running it. The query error is sent back to the llm to fix the query.
+func GenerateLogQL(ctx context.Context, userInput string) (result LogQLResult, err error) {
+	if conf.AppConfig.IsLexicalMode() {
+		err = fmt.Errorf("logql generation requires the llm, which is disabled in the %s search mode", conf.SearchModeLexical)
+		return
+	}
+	datasources, err := grafana.ListDatasourcesByType(grafana.MatchInstances(userInput), grafana.DatasourceTypeLoki)
+	if err != nil {
+		return
+	}
+	if len(datasources) == 0 {
+		err = fmt.Errorf("no loki datasource found")
+		return
+	}
+	datasource := selectDatasource(userInput, datasources)
+	client := grafana.GetClient(datasource.Source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", datasource.Source)
+		return
+	}
+	labelsTable, err := createLogQLLabelsTable(client, datasource, userInput)
+	if err != nil {
+		return
+	}
+	renderCtx := GrafanaLogQLContext{
+		Datasource: datasource.Name,
+		Now:        time.Now().Format(time.RFC3339),
+		Labels:     labelsTable,
+	}
+	for attempt := 1; attempt <= maxLogQLAttempts; attempt++ {
+		var answer LogQLAnswer
+		answer, err = askLogQL(ctx, renderCtx, userInput)
+		if err != nil {
+			return
+		}
+		result = LogQLResult{
+			Datasource:  datasource,
+			Query:       answer.Query,
+			From:        answer.From,
+			To:          answer.To,
+			Explanation: answer.Explanation,
+			ExploreURL:  client.CreateExploreURL(datasource, answer.Query, answer.From, answer.To),
+		}
+		result.LogLines, err = client.QueryLoki(datasource, answer.Query, answer.From, answer.To, maxReplyLogLines)
+		if err == nil {
+			return
+		}
+		slog.Error(fmt.Sprintf("run logql %s attempt %d err: %v", answer.Query, attempt, err))
+		renderCtx.PreviousQuery = answer.Query
+		renderCtx.PreviousError = err.Error()
+	}
+	err = fmt.Errorf("validate logql %s err: %w", result.Query, err)
+	return
+}
+
+// askLogQL asks the llm to write the logql, the invalid time range is replaced by the last hour
+func askLogQL(ctx context.Context, renderCtx GrafanaLogQLContext, userInput string) (answer LogQLAnswer, err error) {
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_logql_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm input:\n %s", systemMessage))
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		return
+	}
+	jsonOutput := ernie.GetResponseJsonContent(llmOutput)
+	if err = json.Unmarshal([]byte(jsonOutput), &answer); err != nil {
+		err = fmt.Errorf("parse logql err: %w", err)
+		return
+	}
+	if answer.Query = strings.TrimSpace(answer.Query); answer.Query == "" {
+		err = fmt.Errorf("no logql returned by the llm")
+		return
+	}
+	if !grafana.IsValidTime(answer.From) {
+		answer.From = defaultLogQLFrom
+	}
+	if !grafana.IsValidTime(answer.To) {
+		answer.To = defaultLogQLTo
+	}
+	return
+}
+
+// createLogQLLabelsTable lists the stream labels of the loki datasource, the values mentioned in the
+// user input are listed first
+func createLogQLLabelsTable(client *grafana.Client, datasource grafana.Datasource, userInput string) (labelsTable string, err error) {
+	allLabelNames, err := client.ListStreamLabelNames(datasource)
+	if err != nil {
+		return
+	}
+	labelNames := make([]string, 0, len(allLabelNames))
+	for _, labelName := range allLabelNames {
+		// skip the internal labels, e.g. __stream_shard__
+		if !strings.HasPrefix(labelName, "__") {
+			labelNames = append(labelNames, labelName)
+		}
+	}
+	if len(labelNames) > maxPromQLLabels {
+		labelNames = labelNames[:maxPromQLLabels]
+	}
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|Label|Values|\n")
+	markdownBuf.WriteString("|---|---|\n")
+	lowerInput := strings.ToLower(userInput)
+	for _, labelName := range labelNames {
+		labelValues, valuesErr := client.ListStreamLabelValues(datasource, labelName)
+		if valuesErr != nil {
+			slog.Error(fmt.Sprintf("%v, skip the label %s", valuesErr, labelName))
+			continue
+		}
+		sort.SliceStable(labelValues, func(i, j int) bool {
+			return strings.Contains(lowerInput, strings.ToLower(labelValues[i])) &&
+				!strings.Contains(lowerInput, strings.ToLower(labelValues[j]))
+		})
+		if len(labelValues) > maxPromQLLabelValues {
+			labelValues = labelValues[:maxPromQLLabelValues]
+		}
+		markdownBuf.WriteString(fmt.Sprintf("|%s|%s|\n", labelName, escapeMarkdownCell(strings.Join(labelValues, ", "))))
+	}
+	labelsTable = markdownBuf.String()
+	return
+}
+
+// CreateLogQLMessage formats the query, the newest log lines and the explore link. The query and the explanation
+// are truncated, and the log lines are dropped from the end to keep the reply within maxQueryReplyLength bytes.
+func CreateLogQLMessage(result LogQLResult) string {
+	headerBuf := bytes.NewBuffer(nil)
+	headerBuf.WriteString(fmt.Sprintf("LogQL: %s\n", truncateText(result.Query, maxReplyQueryLength)))
+	if result.Explanation != "" {
+		headerBuf.WriteString(fmt.Sprintf("说明: %s\n", truncateText(result.Explanation, maxReplyExplanationLength)))
+	}
+	datasourceName := result.Datasource.Name
+	if result.Datasource.Source != "" {
+		datasourceName = fmt.Sprintf("[%s] %s", result.Datasource.Source, datasourceName)
+	}
+	headerBuf.WriteString(fmt.Sprintf("数据源: %s，时间范围: %s ~ %s\n", datasourceName, result.From, result.To))
+	footer := formatExploreLine(result.ExploreURL)
+	if len(result.LogLines) == 0 {
+		return fmt.Sprintf("%s查询结果: 无日志\n%s", headerBuf.String(), footer)
+	}
+	// reserve the space for the summary line
+	budget := max(maxQueryReplyLength-headerBuf.Len()-len(footer)-100, 0)
+	linesBuf := bytes.NewBuffer(nil)
+	var shownLines int
+	for _, logLine := range result.LogLines {
+		line := fmt.Sprintf("%s %s\n", logLine.Time.Local().Format("01-02 15:04:05"),
+			truncateText(strings.TrimSpace(logLine.Line), maxLogLineLength))
+		if linesBuf.Len()+len(line) > budget {
+			break
+		}
+		linesBuf.WriteString(line)
+		shownLines++
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(headerBuf.String())
+	buf.WriteString(fmt.Sprintf("查询结果: 最新 %d 条日志", len(result.LogLines)))
+	if shownLines < len(result.LogLines) {
+		buf.WriteString(fmt.Sprintf("，受消息长度限制仅展示前 %d 条", shownLines))
+	}
+	buf.WriteString("\n")
+	buf.WriteString(linesBuf.String())
+	buf.WriteString(footer)
+	return buf.String()
+}
+
+// formatExploreLine formats the explore link of the query, the link is dropped if it is too long
+func formatExploreLine(exploreURL string) string {
+	if len(exploreURL) > maxReplyURLLength {
+		return "Explore: 链接过长，未展示"
+	}
+	return fmt.Sprintf("Explore: %s", exploreURL)
+}
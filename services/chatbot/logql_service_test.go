@@ -0,0 +1,101 @@
+package chatbot
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTestLogLines(count, length int) (logLines []grafana.LogLine) {
+	for index := 0; index < count; index++ {
+		logLines = append(logLines, grafana.LogLine{
+			Time: time.Now().Add(-time.Duration(index) * time.Second),
+			Line: fmt.Sprintf("%03d %s", index, strings.Repeat("x", length)),
+		})
+	}
+	return
+}
+
+func TestCreateLogQLMessage(t *testing.T) {
+	result := LogQLResult{
+		Datasource:  grafana.Datasource{Name: "Loki", Source: "staging"},
+		Query:       `{app="gateway"} |= "error"`,
+		From:        "now-15m",
+		To:          "now",
+		Explanation: "gateway 的错误日志",
+		ExploreURL:  "http://grafana.local/explore?panes=abc",
+	}
+	testCases := []struct {
+		name     string
+		logLines []grafana.LogLine
+		// wantShown is the number of the lines shown, -1 means fewer than all the lines
+		wantShown int
+	}{
+		{name: "no logs", logLines: nil, wantShown: 0},
+		{name: "all lines shown", logLines: createTestLogLines(3, 10), wantShown: 3},
+		{name: "long lines are truncated", logLines: createTestLogLines(2, 1000), wantShown: 2},
+		{name: "lines dropped to fit the limit", logLines: createTestLogLines(maxReplyLogLines, maxLogLineLength), wantShown: -1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result.LogLines = testCase.logLines
+			text := CreateLogQLMessage(result)
+			if len(text) > maxQueryReplyLength {
+				t.Errorf("length = %d, exceeds %d", len(text), maxQueryReplyLength)
+			}
+			if !strings.Contains(text, "数据源: [staging] Loki") || !strings.HasSuffix(text, "Explore: "+result.ExploreURL) {
+				t.Errorf("text = %q, want the datasource and the explore link", text)
+			}
+			if len(testCase.logLines) == 0 {
+				if !strings.Contains(text, "查询结果: 无日志") {
+					t.Errorf("text = %q, want no logs", text)
+				}
+				return
+			}
+			var shown int
+			for _, line := range strings.Split(text, "\n") {
+				// the log lines start with the time, e.g. 01-02 15:04:05
+				if len(line) > 15 && line[2] == '-' && line[8] == ':' {
+					shown++
+					if len([]rune(line)) > len("01-02 15:04:05 ")+maxLogLineLength+len("...") {
+						t.Errorf("line is not truncated: %q", line)
+					}
+				}
+			}
+			wantTruncated := testCase.wantShown < 0
+			if wantTruncated && (shown == 0 || shown >= len(testCase.logLines)) {
+				t.Errorf("shown lines = %d, want fewer than %d", shown, len(testCase.logLines))
+			} else if !wantTruncated && shown != testCase.wantShown {
+				t.Errorf("shown lines = %d, want %d", shown, testCase.wantShown)
+			}
+			truncatedText := fmt.Sprintf("受消息长度限制仅展示前 %d 条", shown)
+			if strings.Contains(text, truncatedText) != wantTruncated {
+				t.Errorf("text = %q, want truncated %v", text, wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCreateLogQLMessageLongHeader(t *testing.T) {
+	result := LogQLResult{
+		Datasource:  grafana.Datasource{Name: "Loki"},
+		Query:       `{app="gateway"} |~ "` + strings.Repeat("error|", 500) + `"`,
+		From:        "now-1h",
+		To:          "now",
+		Explanation: strings.Repeat("网关错误日志", 200),
+		ExploreURL:  "http://grafana.local/explore?panes=" + strings.Repeat("a", 3000),
+		LogLines:    createTestLogLines(maxReplyLogLines, maxLogLineLength),
+	}
+	text := CreateLogQLMessage(result)
+	if len(text) > maxQueryReplyLength {
+		t.Errorf("length = %d, exceeds %d", len(text), maxQueryReplyLength)
+	}
+	if strings.Contains(text, result.ExploreURL) || !strings.HasSuffix(text, "Explore: 链接过长，未展示") {
+		t.Errorf("text = %q, want the long explore link dropped", text)
+	}
+	if !strings.Contains(text, "受消息长度限制仅展示前") {
+		t.Errorf("text = %q, want the log lines dropped", text)
+	}
+}
@@ -0,0 +1,72 @@
+package mattermost
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTimeout = time.Second * 10 // 10 seconds
+)
+
+type Config struct {
+	Timeout int `json:"timeout"`
+}
+
+// Client posts the deferred replies to the response_url of the slash command
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return NewClientWithHttpClient(cfg, &http.Client{Timeout: timeout})
+}
+
+func NewClientWithHttpClient(cfg *Config, httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+	}
+}
+
+// VerifyCommandToken checks the token of the slash command in constant time
+func VerifyCommandToken(token, commandToken string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(commandToken)) == 1
+}
+
+func (c *Client) PostResponse(responseURL string, response *CommandResponse) (err error) {
+	reqBody, mErr := json.Marshal(response)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	req, newErr := http.NewRequest(http.MethodPost, responseURL, bytes.NewReader(reqBody))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// discard response body to reuse underline tcp connections
+	_, _ = io.Copy(io.Discard, resp.Body)
+	// check status code
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	return
+}
@@ -0,0 +1,24 @@
+package mattermost
+
+import "testing"
+
+func TestVerifyCommandToken(t *testing.T) {
+	testCases := []struct {
+		name         string
+		token        string
+		commandToken string
+		want         bool
+	}{
+		{name: "match", token: "secret", commandToken: "secret", want: true},
+		{name: "mismatch", token: "secreT", commandToken: "secret", want: false},
+		{name: "prefix", token: "sec", commandToken: "secret", want: false},
+		{name: "empty token", token: "", commandToken: "", want: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := VerifyCommandToken(testCase.token, testCase.commandToken); got != testCase.want {
+				t.Errorf("VerifyCommandToken() = %v, want %v", got, testCase.want)
+			}
+		})
+	}
+}
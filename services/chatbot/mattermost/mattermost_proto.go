@@ -0,0 +1,29 @@
+package mattermost
+
+const (
+	ResponseTypeInChannel = "in_channel"
+	ResponseTypeEphemeral = "ephemeral"
+)
+
+// SlashCommand is the form posted by mattermost when user invokes the slash command.
+// See https://developers.mattermost.com/integrate/slash-commands/custom/
+type SlashCommand struct {
+	Token       string
+	TeamId      string
+	TeamDomain  string
+	ChannelId   string
+	ChannelName string
+	UserId      string
+	UserName    string
+	Command     string
+	Text        string
+	ResponseURL string
+	TriggerId   string
+}
+
+// CommandResponse is used both as the immediate response and the deferred post to the response_url
+type CommandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+	Username     string `json:"username,omitempty"`
+}
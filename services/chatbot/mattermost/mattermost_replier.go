@@ -0,0 +1,58 @@
+package mattermost
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+)
+
+const PlatformName = "mattermost"
+
+// Replier posts the deferred copilot replies to the response_url and @ the user who asks
+type Replier struct {
+	client      *Client
+	responseURL string
+	userName    string
+}
+
+func NewReplier(client *Client, responseURL, userName string) *Replier {
+	return &Replier{
+		client:      client,
+		responseURL: responseURL,
+		userName:    userName,
+	}
+}
+
+// NewUserMessage converts the slash command to the platform neutral user message
+func NewUserMessage(cmd *SlashCommand, client *Client) *message.UserMessage {
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        strings.TrimPrefix(cmd.Command, "/"),
+		Input:          strings.TrimSpace(cmd.Text),
+		FromUserId:     cmd.UserId,
+		ConversationId: cmd.ChannelId,
+		Replier:        NewReplier(client, cmd.ResponseURL, cmd.UserName),
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.post(text)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("为您找到如下看板:\n")
+	for _, dashboard := range dashboards {
+		buf.WriteString(fmt.Sprintf("- [%s](%s)\n", dashboard.Title, dashboard.URL))
+	}
+	return r.post(buf.String())
+}
+
+func (r *Replier) post(text string) error {
+	return r.client.PostResponse(r.responseURL, &CommandResponse{
+		ResponseType: ResponseTypeInChannel,
+		Text:         fmt.Sprintf("@%s %s", r.userName, text),
+	})
+}
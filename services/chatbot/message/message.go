@@ -0,0 +1,56 @@
+package message
+
+import (
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+)
+
+// UserMessage is a platform neutral message received from a chat robot.
+// Every chat adapter converts its own callback body into this struct before
+// handing it over to the copilot.
+type UserMessage struct {
+	// Platform is the name of the chat platform, e.g. infoflow
+	Platform string
+	// Command is the slash command triggered by the user, empty if not triggered by command
+	Command string
+	// Input is the text content typed by the user
+	Input string
+	// FromUserId is the user id of the message sender on the chat platform
+	FromUserId string
+	// ConversationId is the group or channel id where the message is sent
+	ConversationId string
+	// Replier is used to send the reply back to the conversation
+	Replier Replier
+}
+
+// Replier sends the copilot results back to the conversation which the user message comes from.
+type Replier interface {
+	// ReplyText sends a plain text message to the user
+	ReplyText(text string) error
+	// ReplyDashboards sends the suggested dashboards to the user
+	ReplyDashboards(dashboards []grafana.Dashboard) error
+}
+
+// ImageReplier is implemented by the repliers of the platforms which support sending images
+type ImageReplier interface {
+	// MaxImageSize returns the max size in bytes of the image accepted by the platform
+	MaxImageSize() int
+	// ReplyImage sends the png image to the user
+	ReplyImage(image []byte) error
+}
+
+// ParseCommand splits the text which starts with a slash command into the command name and the
+// remaining user input, e.g. "/Grafana kafka lag" returns "Grafana" and "kafka lag".
+// The command is empty if the text does not start with a slash.
+func ParseCommand(text string) (command, input string) {
+	input = strings.TrimSpace(text)
+	if strings.HasPrefix(input, "/") {
+		items := strings.SplitN(input[1:], " ", 2)
+		command = items[0]
+		input = ""
+		if len(items) == 2 {
+			input = strings.TrimSpace(items[1])
+		}
+	}
+	return
+}
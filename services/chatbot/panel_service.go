@@ -0,0 +1,303 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	PanelCmd = "Panel"
+)
+
+const (
+	// maxRetrievedPanels limits the panels ranked by the panel index for the llm to pick
+	maxRetrievedPanels = 20
+	// maxRetrievedPanelDashboards limits the dashboards of the ranked panels whose json models are fetched
+	maxRetrievedPanelDashboards = 10
+	// maxPanelCandidateDashboards limits the dashboards matched by title whose json models are fetched
+	// when the panel index is not ready
+	maxPanelCandidateDashboards = 5
+	// maxPanelQueryLength truncates the long queries in the prompt
+	maxPanelQueryLength = 200
+)
+
+type GrafanaPanelContext struct {
+	GrafanaPanels string
+	UserInput     string
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        PanelCmd,
+		Aliases:     []string{"面板"},
+		Usage:       "/Panel <问题>，例如 /Panel checkout 的 p99 延迟",
+		Description: "根据问题匹配最合适的 Grafana 面板，并返回直接打开面板的链接",
+		Handler:     handlePanelCommand,
+	})
+}
+
+// handlePanelCommand handles grafana panel matching
+func handlePanelCommand(ctx context.Context, userMessage *message.UserMessage) {
+	suggestedPanels, err := MatchPanels(ctx, userMessage.Input)
+	if err != nil || len(suggestedPanels) == 0 {
+		var errMsg string
+		if err != nil {
+			errMsg = fmt.Sprintf("Handle grafana panel copilot err: %s", err.Error())
+		} else {
+			errMsg = "没有找到匹配的面板，请尝试其他问题"
+		}
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+	} else {
+		NotifyUserResult(userMessage, suggestedPanels)
+	}
+}
+
+// MatchPanels ranks the panels of all the dashboards by the panel index first, then fetches the json
+// models of the top panels and asks the llm to pick the panels by their titles, descriptions and queries.
+// If the index is not ready, the panels are picked from the candidate dashboards matched by title.
+// The returned URLs open the panels in view mode.
+func MatchPanels(ctx context.Context, userInput string) (suggestedPanels []grafana.Dashboard, err error) {
+	panelCandidates, dashboardDetails := retrievePanelCandidates(ctx, userInput)
+	if len(panelCandidates) == 0 {
+		// the link params are extracted with the panels
+		var candidates []grafana.Dashboard
+		candidates, err = matchDashboards(ctx, userInput, false)
+		if err != nil || len(candidates) == 0 {
+			return
+		}
+		if len(candidates) > maxPanelCandidateDashboards {
+			candidates = candidates[:maxPanelCandidateDashboards]
+		}
+		dashboardDetails = fetchDashboardDetails(candidates)
+		if len(dashboardDetails) == 0 {
+			err = fmt.Errorf("get grafana dashboard details failed")
+			return
+		}
+		panelCandidates = createPanelCandidates(dashboardDetails)
+	}
+	if conf.AppConfig.IsLexicalMode() {
+		suggestedPanels = matchPanelsLexically(userInput, panelCandidates)
+		return
+	}
+	candidateMap := make(map[string]panelCandidate, len(panelCandidates))
+	// convert panels to markdown table
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|DashboardUid|PanelId|Title|Description|Queries|\n")
+	markdownBuf.WriteString("|---|---|---|---|---|\n")
+	for _, candidate := range panelCandidates {
+		queries := candidate.Panel.GetQueries()
+		for index, query := range queries {
+			queries[index] = truncateText(query, maxPanelQueryLength)
+		}
+		markdownBuf.WriteString(fmt.Sprintf("|%s|%d|%s|%s|%s|\n", candidate.DashboardDetail.Key(), candidate.Panel.Id,
+			escapeMarkdownCell(candidate.Panel.Title), escapeMarkdownCell(candidate.Panel.Description),
+			escapeMarkdownCell(strings.Join(queries, "; "))))
+		candidateMap[candidate.Key] = candidate
+	}
+	renderCtx := GrafanaPanelContext{
+		GrafanaPanels: markdownBuf.String(),
+		UserInput:     userInput,
+	}
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_panel_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm input:\n %s", systemMessage))
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		slog.Error(fmt.Sprintf("%v, fallback to keyword matching", err))
+		suggestedPanels = matchPanelsLexically(userInput, panelCandidates)
+		for index := range suggestedPanels {
+			suggestedPanels[index].Reason = fmt.Sprintf("大模型暂不可用，%s", suggestedPanels[index].Reason)
+		}
+		err = nil
+		return
+	}
+	textOutput := ernie.GetResponseTextContent(llmOutput)
+	suggestedPanels = make([]grafana.Dashboard, 0, 2)
+	for _, line := range strings.Split(textOutput, "\n") {
+		slog.Debug(fmt.Sprintf("get llm text line, %s", line))
+		// the reason is optional
+		items := strings.SplitN(line, "=", 4)
+		if len(items) < 3 {
+			continue
+		}
+		dashboardKey := strings.TrimSpace(items[0])
+		panelId, convErr := strconv.Atoi(strings.TrimSpace(items[1]))
+		if convErr != nil {
+			continue
+		}
+		candidate, ok := candidateMap[createPanelKey(dashboardKey, panelId)]
+		if !ok {
+			continue
+		}
+		var reason string
+		if len(items) == 4 {
+			reason = strings.TrimSpace(items[3])
+		}
+		suggestedPanels = append(suggestedPanels, createPanelSuggestion(candidate, reason))
+	}
+	applyLinkParams(ctx, userInput, suggestedPanels, dashboardDetails)
+	return
+}
+
+// panelCandidate is a panel of the candidate dashboards to be matched
+type panelCandidate struct {
+	Key             string
+	DashboardDetail grafana.DashboardDetail
+	Panel           grafana.Panel
+}
+
+// retrievePanelCandidates ranks the indexed panels of all the dashboards, and fetches the json models
+// of the dashboards which the top panels belong to. Nothing is returned if the index is not ready, e.g. in the
+// lexical mode where the embedding model is not configured.
+func retrievePanelCandidates(ctx context.Context, userInput string) (candidates []panelCandidate,
+	dashboardDetails []grafana.DashboardDetail) {
+	if !retrieval.IsEnabled() || retrieval.GetIndex().Size() == 0 {
+		return
+	}
+	dashboardMetas, err := grafana.ListCatalogDashboards()
+	if err != nil {
+		slog.Error(fmt.Sprintf("list grafana dashboard metas err: %v", err))
+		return
+	}
+	// only search the instances mentioned in the user input, e.g. "in staging"
+	dashboardMetaMap := make(map[string]grafana.Dashboard)
+	for _, dashboardMeta := range filterDashboardsBySource(userInput, dashboardMetas) {
+		dashboardMetaMap[dashboardMeta.Key()] = dashboardMeta
+	}
+	// retrieve more panels since the ones of the other instances are dropped
+	results, err := retrieval.RetrievePanels(ctx, userInput, maxRetrievedPanels*2)
+	if err != nil {
+		slog.Error(fmt.Sprintf("retrieve panels err: %v, fallback to the dashboard matching", err))
+		return
+	}
+	var rankedDocuments []*retrieval.Document
+	var dashboards []grafana.Dashboard
+	seen := make(map[string]bool)
+	for _, result := range results {
+		document := result.Document
+		dashboardMeta, ok := dashboardMetaMap[document.DashboardUid]
+		if !ok {
+			continue
+		}
+		if !seen[document.DashboardUid] {
+			if len(dashboards) >= maxRetrievedPanelDashboards {
+				continue
+			}
+			seen[document.DashboardUid] = true
+			dashboards = append(dashboards, dashboardMeta)
+		}
+		rankedDocuments = append(rankedDocuments, document)
+		if len(rankedDocuments) >= maxRetrievedPanels {
+			break
+		}
+	}
+	if len(rankedDocuments) == 0 {
+		return
+	}
+	dashboardDetails = fetchDashboardDetails(dashboards)
+	dashboardDetailMap := make(map[string]grafana.DashboardDetail, len(dashboardDetails))
+	for _, dashboardDetail := range dashboardDetails {
+		dashboardDetailMap[dashboardDetail.Key()] = dashboardDetail
+	}
+	for _, document := range rankedDocuments {
+		dashboardDetail, ok := dashboardDetailMap[document.DashboardUid]
+		if !ok {
+			continue
+		}
+		// the panel may be removed after the index is built
+		for _, panel := range dashboardDetail.Dashboard.GetPanels() {
+			if panel.Id == document.PanelId {
+				candidates = append(candidates, panelCandidate{
+					Key:             createPanelKey(dashboardDetail.Key(), panel.Id),
+					DashboardDetail: dashboardDetail,
+					Panel:           panel,
+				})
+				break
+			}
+		}
+	}
+	return
+}
+
+func createPanelCandidates(dashboardDetails []grafana.DashboardDetail) (candidates []panelCandidate) {
+	for _, dashboardDetail := range dashboardDetails {
+		for _, panel := range dashboardDetail.Dashboard.GetPanels() {
+			candidates = append(candidates, panelCandidate{
+				Key:             createPanelKey(dashboardDetail.Key(), panel.Id),
+				DashboardDetail: dashboardDetail,
+				Panel:           panel,
+			})
+		}
+	}
+	return
+}
+
+func createPanelKey(dashboardKey string, panelId int) string {
+	return fmt.Sprintf("%s#%d", dashboardKey, panelId)
+}
+
+// createPanelSuggestion creates the suggested panel with the URL to open the panel in view mode
+func createPanelSuggestion(candidate panelCandidate, reason string) grafana.Dashboard {
+	dashboardDetail := candidate.DashboardDetail
+	dashboardURL := grafana.CreateDashboardURL(dashboardDetail.Source, dashboardDetail.OrgId, dashboardDetail.Meta.URL)
+	return grafana.Dashboard{
+		Uid:    dashboardDetail.Dashboard.Uid,
+		Title:  fmt.Sprintf("%s / %s", dashboardDetail.Dashboard.Title, candidate.Panel.Title),
+		URL:    grafana.CreatePanelURL(dashboardURL, candidate.Panel.Id),
+		Source: dashboardDetail.Source,
+		OrgId:  dashboardDetail.OrgId,
+		Reason: reason,
+	}
+}
+
+// fetchDashboardDetails fetches the dashboard json models concurrently, the failed ones are skipped
+func fetchDashboardDetails(dashboards []grafana.Dashboard) (dashboardDetails []grafana.DashboardDetail) {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	for _, dashboard := range dashboards {
+		wg.Add(1)
+		go func(dashboard grafana.Dashboard) {
+			defer wg.Done()
+			dashboardDetail, err := grafana.GetDashboard(dashboard.Source, dashboard.OrgId, dashboard.Uid)
+			if err != nil {
+				slog.Error(fmt.Sprintf("get grafana dashboard %s err: %v", dashboard.Key(), err))
+				return
+			}
+			lock.Lock()
+			dashboardDetails = append(dashboardDetails, dashboardDetail)
+			lock.Unlock()
+		}(dashboard)
+	}
+	wg.Wait()
+	return
+}
+
+// escapeMarkdownCell makes the text safe to be put in a markdown table cell
+func escapeMarkdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\r", " ")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// truncateText truncates the text to the max runes
+func truncateText(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes]) + "..."
+}
@@ -0,0 +1,334 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	PromQLCmd = "promql"
+)
+
+const (
+	// maxPromQLMetrics limits the metrics in the prompt, the metrics are selected by keywords if there are more
+	maxPromQLMetrics = 200
+	// maxPromQLMetricNames limits the metric names listed from the datasource to select the metrics from
+	maxPromQLMetricNames = 50000
+	// maxPromQLLabelMetrics limits the metrics whose labels are listed in the prompt
+	maxPromQLLabelMetrics = 10
+	// maxPromQLLabels and maxPromQLLabelValues limit the labels and the values of each label in the prompt
+	maxPromQLLabels      = 15
+	maxPromQLLabelValues = 20
+	// maxPromQLHelpLength truncates the help text of the metrics in the prompt
+	maxPromQLHelpLength = 80
+	// maxPromQLAttempts is the times to ask the llm, the query error is sent back to fix the query
+	maxPromQLAttempts = 2
+	// maxReplySeries limits the series listed in the reply
+	maxReplySeries = 5
+)
+
+type GrafanaPromQLContext struct {
+	Datasource    string
+	Metrics       string
+	Labels        string
+	PreviousQuery string
+	PreviousError string
+}
+
+// PromQLAnswer is the query written by the llm
+type PromQLAnswer struct {
+	Query       string `json:"query"`
+	Explanation string `json:"explanation"`
+}
+
+// PromQLResult is the validated query and its result
+type PromQLResult struct {
+	Datasource  grafana.Datasource
+	Query       string
+	Explanation string
+	Results     []grafana.QueryResult
+	ExploreURL  string
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        PromQLCmd,
+		Aliases:     []string{"query", "指标"},
+		Usage:       "/promql <问题>，例如 /promql payments 服务过去5分钟的 QPS",
+		Description: "根据问题编写 PromQL 并在 Prometheus 数据源上执行，返回查询结果和 Explore 链接",
+		Handler:     handlePromQLCommand,
+	})
+}
+
+// handlePromQLCommand generates and runs the promql of the user input
+func handlePromQLCommand(ctx context.Context, userMessage *message.UserMessage) {
+	result, err := GeneratePromQL(ctx, userMessage.Input)
+	if err != nil {
+		errMsg := fmt.Sprintf("Handle grafana promql copilot err: %s", err.Error())
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	NotifyUserText(userMessage, CreatePromQLMessage(result))
+}
+
+// GeneratePromQL asks the llm to write the promql with the metrics and labels of the prometheus datasource,
+// and validates the query by running it. The query error is sent back to the llm to fix the query.
+func GeneratePromQL(ctx context.Context, userInput string) (result PromQLResult, err error) {
+	if conf.AppConfig.IsLexicalMode() {
+		err = fmt.Errorf("promql generation requires the llm, which is disabled in the %s search mode", conf.SearchModeLexical)
+		return
+	}
+	datasources, err := grafana.ListDatasourcesByType(grafana.MatchInstances(userInput), grafana.DatasourceTypePrometheus)
+	if err != nil {
+		return
+	}
+	if len(datasources) == 0 {
+		err = fmt.Errorf("no prometheus datasource found")
+		return
+	}
+	datasource := selectDatasource(userInput, datasources)
+	client := grafana.GetClient(datasource.Source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", datasource.Source)
+		return
+	}
+	metricNames, err := client.ListMetricNames(datasource, maxPromQLMetricNames)
+	if err != nil {
+		return
+	}
+	metadata, metadataErr := client.GetMetricMetadata(datasource)
+	if metadataErr != nil {
+		slog.Error(fmt.Sprintf("%v, continue without the metric metadata", metadataErr))
+	}
+	candidates, matchedMetrics := selectMetricCandidates(userInput, metricNames, metadata)
+	if len(matchedMetrics) > maxPromQLLabelMetrics {
+		matchedMetrics = matchedMetrics[:maxPromQLLabelMetrics]
+	}
+	renderCtx := GrafanaPromQLContext{
+		Datasource: datasource.Name,
+		Metrics:    createMetricsTable(candidates, metadata),
+		Labels:     createPromQLLabelsTable(client, datasource, userInput, matchedMetrics),
+	}
+	for attempt := 1; attempt <= maxPromQLAttempts; attempt++ {
+		var answer PromQLAnswer
+		answer, err = askPromQL(ctx, renderCtx, userInput)
+		if err != nil {
+			return
+		}
+		result = PromQLResult{
+			Datasource:  datasource,
+			Query:       answer.Query,
+			Explanation: answer.Explanation,
+			ExploreURL:  client.CreateExploreURL(datasource, answer.Query, "now-1h", "now"),
+		}
+		result.Results, err = client.QueryPrometheus(datasource, answer.Query)
+		if err == nil {
+			return
+		}
+		slog.Error(fmt.Sprintf("run promql %s attempt %d err: %v", answer.Query, attempt, err))
+		renderCtx.PreviousQuery = answer.Query
+		renderCtx.PreviousError = err.Error()
+	}
+	err = fmt.Errorf("validate promql %s err: %w", result.Query, err)
+	return
+}
+
+func askPromQL(ctx context.Context, renderCtx GrafanaPromQLContext, userInput string) (answer PromQLAnswer, err error) {
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_promql_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm input:\n %s", systemMessage))
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		return
+	}
+	jsonOutput := ernie.GetResponseJsonContent(llmOutput)
+	if err = json.Unmarshal([]byte(jsonOutput), &answer); err != nil {
+		err = fmt.Errorf("parse promql err: %w", err)
+		return
+	}
+	if answer.Query = strings.TrimSpace(answer.Query); answer.Query == "" {
+		err = fmt.Errorf("no promql returned by the llm")
+		return
+	}
+	return
+}
+
+// selectDatasource selects the datasource mentioned by name in the user input, or the default one
+func selectDatasource(userInput string, datasources []grafana.Datasource) grafana.Datasource {
+	lowerInput := strings.ToLower(userInput)
+	for _, datasource := range datasources {
+		if strings.Contains(lowerInput, strings.ToLower(datasource.Name)) {
+			return datasource
+		}
+	}
+	for _, datasource := range datasources {
+		if datasource.IsDefault {
+			return datasource
+		}
+	}
+	return datasources[0]
+}
+
+// selectMetricCandidates ranks the metrics by the keywords in their names and help texts. The matched
+// metrics are listed first, and the other metrics fill the rest of the prompt.
+func selectMetricCandidates(userInput string, metricNames []string, metadata map[string][]grafana.MetricMetadata) (candidates, matchedMetrics []string) {
+	documents := make([]retrieval.LexicalDocument, 0, len(metricNames))
+	for _, metricName := range metricNames {
+		documents = append(documents, retrieval.LexicalDocument{
+			Id: metricName,
+			Fields: []retrieval.LexicalField{
+				{Text: metricName, Weight: 2},
+				{Text: getMetricHelp(metadata, metricName), Weight: 1},
+			},
+		})
+	}
+	for _, result := range retrieval.NewLexicalIndex(documents).Search(userInput, maxPromQLMetrics) {
+		matchedMetrics = append(matchedMetrics, result.Id)
+	}
+	candidates = append(candidates, matchedMetrics...)
+	for _, metricName := range metricNames {
+		if len(candidates) >= maxPromQLMetrics {
+			break
+		}
+		if !slices.Contains(matchedMetrics, metricName) {
+			candidates = append(candidates, metricName)
+		}
+	}
+	return
+}
+
+func getMetricHelp(metadata map[string][]grafana.MetricMetadata, metricName string) string {
+	if items := metadata[metricName]; len(items) > 0 {
+		return items[0].Help
+	}
+	return ""
+}
+
+func getMetricType(metadata map[string][]grafana.MetricMetadata, metricName string) string {
+	if items := metadata[metricName]; len(items) > 0 {
+		return items[0].Type
+	}
+	return ""
+}
+
+// createMetricsTable converts the metrics with their types and help texts to markdown table
+func createMetricsTable(metricNames []string, metadata map[string][]grafana.MetricMetadata) string {
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|Metric|Type|Help|\n")
+	markdownBuf.WriteString("|---|---|---|\n")
+	for _, metricName := range metricNames {
+		markdownBuf.WriteString(fmt.Sprintf("|%s|%s|%s|\n", metricName, getMetricType(metadata, metricName),
+			escapeMarkdownCell(truncateText(getMetricHelp(metadata, metricName), maxPromQLHelpLength))))
+	}
+	return markdownBuf.String()
+}
+
+// createPromQLLabelsTable lists the labels of the matched metrics, the values mentioned in the user input
+// are listed first. The labels are optional in the prompt, so the errors are only logged.
+func createPromQLLabelsTable(client *grafana.Client, datasource grafana.Datasource, userInput string, metricNames []string) string {
+	markdownBuf := bytes.NewBuffer(nil)
+	markdownBuf.WriteString("|Label|Values|\n")
+	markdownBuf.WriteString("|---|---|\n")
+	if len(metricNames) == 0 {
+		return markdownBuf.String()
+	}
+	labelNames, err := client.ListLabelNames(datasource, metricNames)
+	if err != nil {
+		slog.Error(fmt.Sprintf("%v, continue without the labels", err))
+		return markdownBuf.String()
+	}
+	labelNames = slices.DeleteFunc(labelNames, func(labelName string) bool {
+		return labelName == "__name__"
+	})
+	if len(labelNames) > maxPromQLLabels {
+		labelNames = labelNames[:maxPromQLLabels]
+	}
+	lowerInput := strings.ToLower(userInput)
+	for _, labelName := range labelNames {
+		labelValues, err := client.ListLabelValues(datasource, labelName, metricNames)
+		if err != nil {
+			slog.Error(fmt.Sprintf("%v, skip the label %s", err, labelName))
+			continue
+		}
+		sort.SliceStable(labelValues, func(i, j int) bool {
+			return strings.Contains(lowerInput, strings.ToLower(labelValues[i])) &&
+				!strings.Contains(lowerInput, strings.ToLower(labelValues[j]))
+		})
+		if len(labelValues) > maxPromQLLabelValues {
+			labelValues = labelValues[:maxPromQLLabelValues]
+		}
+		markdownBuf.WriteString(fmt.Sprintf("|%s|%s|\n", labelName, escapeMarkdownCell(strings.Join(labelValues, ", "))))
+	}
+	return markdownBuf.String()
+}
+
+// CreatePromQLMessage formats the query, the explanation, the result summary and the explore link. The query and
+// the explanation are truncated, and the series are dropped from the end to keep the reply within maxQueryReplyLength bytes.
+func CreatePromQLMessage(result PromQLResult) string {
+	headerBuf := bytes.NewBuffer(nil)
+	headerBuf.WriteString(fmt.Sprintf("PromQL: %s\n", truncateText(result.Query, maxReplyQueryLength)))
+	if result.Explanation != "" {
+		headerBuf.WriteString(fmt.Sprintf("说明: %s\n", truncateText(result.Explanation, maxReplyExplanationLength)))
+	}
+	datasourceName := result.Datasource.Name
+	if result.Datasource.Source != "" {
+		datasourceName = fmt.Sprintf("[%s] %s", result.Datasource.Source, datasourceName)
+	}
+	headerBuf.WriteString(fmt.Sprintf("数据源: %s\n", datasourceName))
+	footer := formatExploreLine(result.ExploreURL)
+	if len(result.Results) == 0 {
+		return fmt.Sprintf("%s查询结果: 无数据\n%s", headerBuf.String(), footer)
+	}
+	// reserve the space for the summary line
+	budget := max(maxQueryReplyLength-headerBuf.Len()-len(footer)-100, 0)
+	seriesBuf := bytes.NewBuffer(nil)
+	var shownSeries int
+	for _, item := range result.Results {
+		if shownSeries >= maxReplySeries {
+			break
+		}
+		line := fmt.Sprintf("%s: %s\n", truncateText(formatSeriesLabels(item.Labels), maxAlertTextLength), item.FormatValue())
+		if seriesBuf.Len()+len(line) > budget {
+			break
+		}
+		seriesBuf.WriteString(line)
+		shownSeries++
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(headerBuf.String())
+	buf.WriteString(fmt.Sprintf("查询结果: 共 %d 条序列", len(result.Results)))
+	if shownSeries < len(result.Results) {
+		buf.WriteString(fmt.Sprintf("，仅展示前 %d 条", shownSeries))
+	}
+	buf.WriteString("\n")
+	buf.WriteString(seriesBuf.String())
+	buf.WriteString(footer)
+	return buf.String()
+}
+
+// formatSeriesLabels formats the labels in the prometheus style, e.g. http_requests_total{code="200"}
+func formatSeriesLabels(labels map[string]string) string {
+	items := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if name != "__name__" {
+			items = append(items, fmt.Sprintf("%s=%q", name, value))
+		}
+	}
+	sort.Strings(items)
+	return fmt.Sprintf("%s{%s}", labels["__name__"], strings.Join(items, ", "))
+}
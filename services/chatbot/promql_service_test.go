@@ -0,0 +1,114 @@
+package chatbot
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectMetricCandidates(t *testing.T) {
+	metricNames := []string{"go_goroutines", "http_requests_total", "kafka_consumergroup_lag", "node_cpu_seconds_total"}
+	metadata := map[string][]grafana.MetricMetadata{
+		"kafka_consumergroup_lag": {{Type: "gauge", Help: "Current approximate lag of a consumer group"}},
+		"node_cpu_seconds_total":  {{Type: "counter", Help: "Seconds the CPUs spent in each mode"}},
+	}
+	testCases := []struct {
+		name           string
+		userInput      string
+		wantMatched    []string
+		wantCandidates []string
+	}{
+		{name: "metric name", userInput: "http requests 的 qps", wantMatched: []string{"http_requests_total"},
+			wantCandidates: []string{"http_requests_total", "go_goroutines", "kafka_consumergroup_lag", "node_cpu_seconds_total"}},
+		{name: "help text", userInput: "consumer group lag", wantMatched: []string{"kafka_consumergroup_lag"},
+			wantCandidates: []string{"kafka_consumergroup_lag", "go_goroutines", "http_requests_total", "node_cpu_seconds_total"}},
+		{name: "no match keeps the order", userInput: "磁盘使用率",
+			wantCandidates: metricNames},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			candidates, matchedMetrics := selectMetricCandidates(testCase.userInput, metricNames, metadata)
+			if !reflect.DeepEqual(matchedMetrics, testCase.wantMatched) {
+				t.Errorf("matched = %v, want %v", matchedMetrics, testCase.wantMatched)
+			}
+			if !reflect.DeepEqual(candidates, testCase.wantCandidates) {
+				t.Errorf("candidates = %v, want %v", candidates, testCase.wantCandidates)
+			}
+		})
+	}
+}
+
+func TestSelectMetricCandidatesLimit(t *testing.T) {
+	metricNames := make([]string, 0, maxPromQLMetrics*2)
+	for index := 0; index < maxPromQLMetrics*2; index++ {
+		metricNames = append(metricNames, fmt.Sprintf("metric_%04d", index))
+	}
+	metricNames = append(metricNames, "kafka_consumergroup_lag")
+	candidates, matchedMetrics := selectMetricCandidates("kafka lag", metricNames, nil)
+	if len(candidates) != maxPromQLMetrics || candidates[0] != "kafka_consumergroup_lag" {
+		t.Errorf("candidates = %d, first = %s, want %d with the matched metric first", len(candidates), candidates[0], maxPromQLMetrics)
+	}
+	if !reflect.DeepEqual(matchedMetrics, []string{"kafka_consumergroup_lag"}) {
+		t.Errorf("matched = %v, want the kafka metric", matchedMetrics)
+	}
+}
+
+func createTestSeries(count int, metricName, filler string, labelLength int) (results []grafana.QueryResult) {
+	for index := 0; index < count; index++ {
+		results = append(results, grafana.QueryResult{
+			Labels: map[string]string{"__name__": metricName, "instance": fmt.Sprintf("%03d%s", index, strings.Repeat(filler, labelLength))},
+			Value:  float64(index) + 0.5,
+			Valid:  true,
+		})
+	}
+	return
+}
+
+func TestCreatePromQLMessage(t *testing.T) {
+	longResult := createTestSeries(1, "http_requests_total", "x", 10)
+	longResult[0].Value = math.NaN()
+	testCases := []struct {
+		name        string
+		result      PromQLResult
+		wantShown   int
+		wantContain []string
+	}{
+		{name: "no data", result: PromQLResult{Query: "up", ExploreURL: "http://grafana.local/explore"},
+			wantContain: []string{"PromQL: up\n", "查询结果: 无数据\n", "Explore: http://grafana.local/explore"}},
+		{name: "all series shown", result: PromQLResult{Query: "sum(rate(http_requests_total[5m])) by (instance)",
+			Explanation: "每个实例的 QPS", Results: createTestSeries(3, "http_requests_total", "x", 10), ExploreURL: "http://grafana.local/explore"},
+			wantShown: 3, wantContain: []string{"说明: 每个实例的 QPS\n", "查询结果: 共 3 条序列\n", `http_requests_total{instance="000xxxxxxxxxx"}: 0.5`}},
+		{name: "series limited", result: PromQLResult{Query: "up", Results: createTestSeries(maxReplySeries+2, "http_requests_total", "x", 10)},
+			wantShown: maxReplySeries, wantContain: []string{fmt.Sprintf("查询结果: 共 %d 条序列，仅展示前 %d 条", maxReplySeries+2, maxReplySeries)}},
+		{name: "NaN value", result: PromQLResult{Query: "up", Results: longResult}, wantShown: 1, wantContain: []string{": NaN\n"}},
+		{name: "long query, explanation and url", result: PromQLResult{
+			Query:       "sum(rate(http_requests_total{" + strings.Repeat(`code="500",`, 300) + "}[5m]))",
+			Explanation: strings.Repeat("服务错误率", 300),
+			Results:     createTestSeries(maxReplySeries, "接口请求数", "实例", 100),
+			ExploreURL:  "http://grafana.local/explore?panes=" + strings.Repeat("a", 3000),
+		}, wantShown: -1, wantContain: []string{"Explore: 链接过长，未展示"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			text := CreatePromQLMessage(testCase.result)
+			if len(text) > maxQueryReplyLength {
+				t.Errorf("length = %d, exceeds %d", len(text), maxQueryReplyLength)
+			}
+			for _, want := range testCase.wantContain {
+				if !strings.Contains(text, want) {
+					t.Errorf("text = %q, want %q", text, want)
+				}
+			}
+			shown := strings.Count(text, `{instance="`)
+			if testCase.wantShown >= 0 && shown != testCase.wantShown {
+				t.Errorf("shown series = %d, want %d", shown, testCase.wantShown)
+			}
+			if testCase.wantShown < 0 && shown >= len(testCase.result.Results) {
+				t.Errorf("shown series = %d, want fewer than %d", shown, len(testCase.result.Results))
+			}
+		})
+	}
+}
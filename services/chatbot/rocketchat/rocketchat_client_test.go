@@ -0,0 +1,52 @@
+package rocketchat
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCallbackBodyIsBot(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{name: "absent", body: `{"text":"hi"}`, want: false},
+		{name: "null", body: `{"bot":null}`, want: false},
+		{name: "false", body: `{"bot":false}`, want: false},
+		{name: "integration object", body: `{"bot":{"i":"JcJb5oFnBW7HKxXvP"}}`, want: true},
+		{name: "true", body: `{"bot":true}`, want: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var callbackBody CallbackBody
+			if err := json.Unmarshal([]byte(testCase.body), &callbackBody); err != nil {
+				t.Fatalf("parse callback body err: %v", err)
+			}
+			if got := callbackBody.IsBot(); got != testCase.want {
+				t.Errorf("IsBot() = %v, want %v", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookToken(t *testing.T) {
+	testCases := []struct {
+		name         string
+		token        string
+		webhookToken string
+		want         bool
+	}{
+		{name: "match", token: "secret", webhookToken: "secret", want: true},
+		{name: "mismatch", token: "secreT", webhookToken: "secret", want: false},
+		{name: "prefix", token: "sec", webhookToken: "secret", want: false},
+		{name: "empty token", token: "", webhookToken: "", want: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := VerifyWebhookToken(testCase.token, testCase.webhookToken); got != testCase.want {
+				t.Errorf("VerifyWebhookToken() = %v, want %v", got, testCase.want)
+			}
+		})
+	}
+}
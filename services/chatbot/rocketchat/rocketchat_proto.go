@@ -0,0 +1,40 @@
+package rocketchat
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CallbackBody is the json posted by the rocket.chat outgoing webhook integration.
+// See https://docs.rocket.chat/use-rocket.chat/workspace-administration/integrations#outgoing-webhook
+type CallbackBody struct {
+	Token string `json:"token"`
+	// Bot is false for the user messages and an object for the bot messages, e.g. {"i": "<integrationId>"}
+	Bot         json.RawMessage `json:"bot"`
+	ChannelId   string          `json:"channel_id"`
+	ChannelName string          `json:"channel_name"`
+	MessageId   string          `json:"message_id"`
+	Timestamp   string          `json:"timestamp"`
+	UserId      string          `json:"user_id"`
+	UserName    string          `json:"user_name"`
+	Text        string          `json:"text"`
+	TriggerWord string          `json:"trigger_word"`
+}
+
+// IsBot checks whether the message is sent by a bot, any bot value other than absent, null or false means a bot
+func (b *CallbackBody) IsBot() bool {
+	bot := bytes.TrimSpace(b.Bot)
+	return len(bot) > 0 && !bytes.Equal(bot, []byte("null")) && !bytes.Equal(bot, []byte("false"))
+}
+
+// Message is the json posted to the incoming webhook integration
+type Message struct {
+	// Channel overrides the default channel of the incoming webhook, e.g. #general or @user
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+type ResponseBody struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
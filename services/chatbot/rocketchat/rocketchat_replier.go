@@ -0,0 +1,66 @@
+package rocketchat
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+)
+
+const PlatformName = "rocketchat"
+
+// Replier posts the copilot replies to the channel through the incoming webhook and @ the user who asks
+type Replier struct {
+	client   *Client
+	channel  string
+	userName string
+}
+
+func NewReplier(client *Client, channel, userName string) *Replier {
+	return &Replier{
+		client:   client,
+		channel:  channel,
+		userName: userName,
+	}
+}
+
+// NewUserMessage converts the outgoing webhook body to the platform neutral user message,
+// the trigger word (e.g. `@copilot` or `grafana`) is stripped from the text.
+func NewUserMessage(callbackBody *CallbackBody, client *Client) *message.UserMessage {
+	text := strings.TrimPrefix(strings.TrimSpace(callbackBody.Text), callbackBody.TriggerWord)
+	command, input := message.ParseCommand(text)
+	// reply to the direct message if the channel name is unknown
+	channel := fmt.Sprintf("@%s", callbackBody.UserName)
+	if callbackBody.ChannelName != "" {
+		channel = fmt.Sprintf("#%s", callbackBody.ChannelName)
+	}
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        command,
+		Input:          input,
+		FromUserId:     callbackBody.UserId,
+		ConversationId: callbackBody.ChannelId,
+		Replier:        NewReplier(client, channel, callbackBody.UserName),
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.send(text)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("为您找到如下看板:\n")
+	for _, dashboard := range dashboards {
+		buf.WriteString(fmt.Sprintf("- [%s](%s)\n", dashboard.Title, dashboard.URL))
+	}
+	return r.send(buf.String())
+}
+
+func (r *Replier) send(text string) error {
+	return r.client.SendMessage(&Message{
+		Channel: r.channel,
+		Text:    fmt.Sprintf("@%s %s", r.userName, text),
+	})
+}
@@ -0,0 +1,481 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SilenceCmd = "silence"
+)
+
+const (
+	// defaultSilenceDuration is used if no duration is given in the user input
+	defaultSilenceDuration = time.Hour
+	maxSilenceDuration     = 7 * 24 * time.Hour
+	// silenceConfirmTimeout is the time to wait for the confirmation of the silence actions
+	silenceConfirmTimeout = 5 * time.Minute
+	// maxReplySilences limits the silences listed in the reply to fit the text length limit of the platforms
+	maxReplySilences = 10
+	// silenceIdDisplayLength is the length of the silence id prefix shown in the reply, which is
+	// enough to expire the silence
+	silenceIdDisplayLength = 8
+	silenceTimeLayout      = "2006-01-02 15:04"
+)
+
+// silenceDurationRegexp matches the duration in the user input, e.g. 30m, 2h, 1d12h or 2小时
+var silenceDurationRegexp = regexp.MustCompile(`^((\d+)(分钟|小时|天|周|[mhdw]))+$`)
+var silenceDurationPartRegexp = regexp.MustCompile(`(\d+)(分钟|小时|天|周|[mhdw])`)
+
+var silenceDurationUnits = map[string]time.Duration{
+	"m":  time.Minute,
+	"分钟": time.Minute,
+	"h":  time.Hour,
+	"小时": time.Hour,
+	"d":  24 * time.Hour,
+	"天":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"周":  7 * 24 * time.Hour,
+}
+
+// SilenceRequest is the silence to create parsed from the user input
+type SilenceRequest struct {
+	Matchers []AlertMatcher
+	Duration time.Duration
+	Comment  string
+}
+
+// silenceTarget is the grafana instance and org to create the silence
+type silenceTarget struct {
+	Source string
+	OrgId  int
+}
+
+// pendingSilenceAction is the silence action waiting for the confirmation of the user
+type pendingSilenceAction struct {
+	Execute   func() (result string, err error)
+	ExpiresAt time.Time
+}
+
+var pendingSilenceActionsLock sync.Mutex
+
+// pendingSilenceActions keeps the last action of each user in each conversation
+var pendingSilenceActions = make(map[string]pendingSilenceAction)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:    SilenceCmd,
+		Aliases: []string{"静默"},
+		Usage: "/silence <标签=值> [时长] [备注]，例如 /silence service=order-service 2h 发布中；" +
+			"/silence list 查看静默；/silence expire <静默Id> 解除静默；/silence confirm 确认操作；/silence cancel 取消操作",
+		Description: "创建、查看和解除 Grafana 告警静默，创建和解除静默前需要确认",
+		Handler:     handleSilenceCommand,
+	})
+}
+
+// handleSilenceCommand dispatches the silence subcommands, the create and expire subcommands are
+// executed only after the confirmation of the same user in the same conversation. Only the silences
+// can be listed if the platform does not tell the user, since the confirmation can't be bound to the user.
+func handleSilenceCommand(ctx context.Context, userMessage *message.UserMessage) {
+	subcommand, input, _ := strings.Cut(strings.TrimSpace(userMessage.Input), " ")
+	input = strings.TrimSpace(input)
+	var reply string
+	var err error
+	subcommand = strings.ToLower(subcommand)
+	switch {
+	case slices.Contains([]string{"list", "ls", "列表"}, subcommand):
+		reply, err = listSilences(input)
+	case userMessage.FromUserId == "":
+		reply = "无法识别发送人，不能创建或解除静默，仅支持 /silence list 查看静默"
+	default:
+		reply, err = handleSilenceWriteCommand(ctx, userMessage, subcommand, input)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Handle grafana silence copilot err: %s", err.Error())
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	NotifyUserText(userMessage, reply)
+}
+
+// handleSilenceWriteCommand handles the subcommands which create or expire the silences
+func handleSilenceWriteCommand(ctx context.Context, userMessage *message.UserMessage, subcommand, input string) (reply string, err error) {
+	switch subcommand {
+	case "expire", "delete", "解除":
+		reply, err = prepareExpireSilence(userMessage, input)
+	case "confirm", "yes", "确认":
+		reply, err = confirmSilenceAction(userMessage)
+	case "cancel", "no", "取消":
+		reply = cancelSilenceAction(userMessage)
+	case "create", "add", "创建":
+		reply, err = prepareCreateSilence(ctx, userMessage, input)
+	default:
+		reply, err = prepareCreateSilence(ctx, userMessage, userMessage.Input)
+	}
+	return
+}
+
+// ParseSilenceRequest splits the user input into the label matchers, the duration and the comment
+func ParseSilenceRequest(userInput string) (request SilenceRequest, err error) {
+	matchers, freeText := ParseAlertMatchers(userInput)
+	request.Matchers = matchers
+	words := make([]string, 0)
+	for _, word := range strings.Fields(freeText) {
+		if request.Duration == 0 && silenceDurationRegexp.MatchString(word) {
+			request.Duration = parseSilenceDuration(word)
+			continue
+		}
+		words = append(words, word)
+	}
+	request.Comment = strings.Join(words, " ")
+	if request.Duration == 0 {
+		request.Duration = defaultSilenceDuration
+	}
+	if request.Duration > maxSilenceDuration {
+		err = fmt.Errorf("silence duration %s exceeds the max duration %s", request.Duration, maxSilenceDuration)
+		return
+	}
+	return
+}
+
+func parseSilenceDuration(word string) (duration time.Duration) {
+	for _, items := range silenceDurationPartRegexp.FindAllStringSubmatch(word, -1) {
+		value, _ := strconv.Atoi(items[1])
+		duration += time.Duration(value) * silenceDurationUnits[items[2]]
+	}
+	return
+}
+
+// formatSilenceDuration formats the duration in days, hours and minutes, the zero units are omitted
+// so that the duration is shown as given by the user, e.g. 2h, 1d12h and 1h30m
+func formatSilenceDuration(duration time.Duration) string {
+	days := int(duration / (24 * time.Hour))
+	hours := int(duration % (24 * time.Hour) / time.Hour)
+	minutes := int(duration % time.Hour / time.Minute)
+	var buf strings.Builder
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{days, "d"}, {hours, "h"}, {minutes, "m"}} {
+		if part.value > 0 {
+			buf.WriteString(fmt.Sprintf("%d%s", part.value, part.unit))
+		}
+	}
+	if buf.Len() == 0 {
+		return "0m"
+	}
+	return buf.String()
+}
+
+func prepareCreateSilence(ctx context.Context, userMessage *message.UserMessage, userInput string) (reply string, err error) {
+	request, err := ParseSilenceRequest(userInput)
+	if err != nil {
+		return
+	}
+	alerts, err := grafana.ListAllAlerts(grafana.MatchInstances(userInput))
+	if err != nil {
+		slog.Error(fmt.Sprintf("list alerts for silence err: %v", err))
+		err = nil
+	}
+	// extract the labels from the natural language if no label matcher is given
+	if len(request.Matchers) == 0 && request.Comment != "" && len(alerts) > 0 && !conf.AppConfig.IsLexicalMode() {
+		filter, filterErr := ExtractAlertFilter(ctx, request.Comment, alerts)
+		if filterErr != nil {
+			slog.Error(fmt.Sprintf("extract silence matchers err: %v", filterErr))
+		}
+		for name, value := range filter.Labels {
+			request.Matchers = append(request.Matchers, AlertMatcher{Name: name, Value: value, Equal: true})
+		}
+	}
+	if !hasPositiveMatcher(request.Matchers) {
+		reply = "请至少指定一个标签，例如 /silence service=order-service 2h 发布中"
+		return
+	}
+	matchedAlerts := filterAlertsByMatchers(alerts, request.Matchers)
+	silenceMatchers := createSilenceMatchers(request.Matchers, matchedAlerts)
+	comment := request.Comment
+	if comment == "" {
+		comment = fmt.Sprintf("created by grafana copilot from %s", userMessage.Platform)
+	}
+	createdBy := userMessage.FromUserId
+	silences := make([]grafana.Silence, 0)
+	for _, target := range createSilenceTargets(userInput, matchedAlerts) {
+		silences = append(silences, grafana.Silence{
+			Matchers:  silenceMatchers,
+			CreatedBy: createdBy,
+			Comment:   comment,
+			Source:    target.Source,
+			OrgId:     target.OrgId,
+		})
+	}
+	if len(silences) == 0 {
+		reply = "没有可以创建静默的 Grafana 实例"
+		return
+	}
+	duration := request.Duration
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("即将创建如下静默:\n")
+	buf.WriteString(fmt.Sprintf("匹配条件: %s\n", silences[0].FormatMatchers()))
+	buf.WriteString(fmt.Sprintf("时长: %s，到期时间: %s\n", formatSilenceDuration(duration),
+		time.Now().Add(duration).Format(silenceTimeLayout)))
+	buf.WriteString(fmt.Sprintf("备注: %s\n", comment))
+	buf.WriteString(fmt.Sprintf("当前匹配告警: %d 条\n", len(matchedAlerts)))
+	buf.WriteString(fmt.Sprintf("创建位置: %s\n", formatSilenceTargets(silences)))
+	buf.WriteString(createConfirmHint())
+	setPendingSilenceAction(userMessage, func() (result string, err error) {
+		resultBuf := bytes.NewBuffer(nil)
+		resultBuf.WriteString("已创建静默:")
+		for _, silence := range silences {
+			silence.StartsAt = time.Now()
+			silence.EndsAt = silence.StartsAt.Add(duration)
+			if silence.Id, err = grafana.CreateSilence(silence); err != nil {
+				// report the silences created before the failure
+				err = fmt.Errorf("%w, %s", err, resultBuf.String())
+				return
+			}
+			slog.Info(fmt.Sprintf("silence %s created by %s: %s", silence.Key(), createdBy, silence.FormatMatchers()))
+			resultBuf.WriteString(fmt.Sprintf("\n%s，到期时间: %s", formatSilenceId(silence), silence.EndsAt.Format(silenceTimeLayout)))
+		}
+		result = resultBuf.String()
+		return
+	})
+	reply = buf.String()
+	return
+}
+
+func hasPositiveMatcher(matchers []AlertMatcher) bool {
+	for _, matcher := range matchers {
+		if matcher.Equal && matcher.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// createSilenceMatchers converts the matchers to the silence matchers, since the alertmanager matches
+// the label values case-sensitively, the values are replaced by the ones of the matched alerts
+func createSilenceMatchers(matchers []AlertMatcher, matchedAlerts []grafana.Alert) (silenceMatchers []grafana.SilenceMatcher) {
+	for _, matcher := range matchers {
+		value := matcher.Value
+		for _, alert := range matchedAlerts {
+			if labelValue, ok := alert.Labels[matcher.Name]; ok && strings.EqualFold(labelValue, value) {
+				value = labelValue
+				break
+			}
+		}
+		silenceMatchers = append(silenceMatchers, grafana.SilenceMatcher{
+			Name:    matcher.Name,
+			Value:   value,
+			IsEqual: matcher.Equal,
+		})
+	}
+	return
+}
+
+// createSilenceTargets returns the instances and orgs of the matched alerts, or the default orgs of the
+// instances mentioned in the user input if no alert matches, so that the silences can be created in
+// advance, e.g. before the deploy.
+func createSilenceTargets(userInput string, matchedAlerts []grafana.Alert) (targets []silenceTarget) {
+	for _, alert := range matchedAlerts {
+		target := silenceTarget{Source: alert.Source, OrgId: alert.OrgId}
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) > 0 {
+		return
+	}
+	sources := grafana.MatchInstances(userInput)
+	for _, client := range grafana.GetClients() {
+		if len(sources) == 0 || slices.Contains(sources, client.Name) {
+			targets = append(targets, silenceTarget{Source: client.Name, OrgId: client.GetOrgIds()[0]})
+		}
+	}
+	return
+}
+
+func formatSilenceTargets(silences []grafana.Silence) string {
+	items := make([]string, 0, len(silences))
+	for _, silence := range silences {
+		var item string
+		if silence.Source != "" {
+			item = silence.Source
+		} else {
+			item = "grafana"
+		}
+		if silence.OrgId > 0 {
+			item = fmt.Sprintf("%s org %d", item, silence.OrgId)
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ", ")
+}
+
+func formatSilenceId(silence grafana.Silence) string {
+	silenceId := silence.Id
+	if len(silenceId) > silenceIdDisplayLength {
+		silenceId = silenceId[:silenceIdDisplayLength]
+	}
+	if silence.Source != "" {
+		silenceId = fmt.Sprintf("[%s] %s", silence.Source, silenceId)
+	}
+	return silenceId
+}
+
+// listSilences lists the active and pending silences of the grafana instances mentioned in the user input
+func listSilences(userInput string) (reply string, err error) {
+	silences, err := listUnexpiredSilences(userInput)
+	if err != nil {
+		return
+	}
+	if len(silences) == 0 {
+		reply = "当前没有生效中的静默"
+		return
+	}
+	sort.SliceStable(silences, func(i, j int) bool {
+		return silences[i].EndsAt.Before(silences[j].EndsAt)
+	})
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(fmt.Sprintf("共有 %d 条生效中的静默", len(silences)))
+	if len(silences) > maxReplySilences {
+		buf.WriteString(fmt.Sprintf("，仅展示最先到期的 %d 条", maxReplySilences))
+		silences = silences[:maxReplySilences]
+	}
+	buf.WriteString(":\n")
+	for index, silence := range silences {
+		buf.WriteString(fmt.Sprintf("\n%d. %s [%s] %s\n", index+1, formatSilenceId(silence), silence.GetState(),
+			truncateText(silence.FormatMatchers(), maxAlertTextLength)))
+		buf.WriteString(fmt.Sprintf("到期时间: %s，创建人: %s\n", silence.EndsAt.Format(silenceTimeLayout), silence.CreatedBy))
+		if silence.Comment != "" {
+			buf.WriteString(fmt.Sprintf("备注: %s\n", truncateText(silence.Comment, maxAlertTextLength)))
+		}
+	}
+	reply = buf.String()
+	return
+}
+
+func listUnexpiredSilences(userInput string) (unexpiredSilences []grafana.Silence, err error) {
+	silences, err := grafana.ListAllSilences(grafana.MatchInstances(userInput))
+	if err != nil {
+		return
+	}
+	for _, silence := range silences {
+		if silence.GetState() != grafana.SilenceStateExpired {
+			unexpiredSilences = append(unexpiredSilences, silence)
+		}
+	}
+	return
+}
+
+// prepareExpireSilence finds the silence by the id or the id prefix shown in the silence list
+func prepareExpireSilence(userMessage *message.UserMessage, userInput string) (reply string, err error) {
+	silenceId, _, _ := strings.Cut(userInput, " ")
+	if silenceId == "" {
+		reply = "请指定要解除的静默Id，发送 /silence list 查看生效中的静默"
+		return
+	}
+	silences, err := listUnexpiredSilences(userInput)
+	if err != nil {
+		return
+	}
+	var matchedSilences []grafana.Silence
+	for _, silence := range silences {
+		if strings.HasPrefix(silence.Id, silenceId) {
+			matchedSilences = append(matchedSilences, silence)
+		}
+	}
+	switch len(matchedSilences) {
+	case 0:
+		reply = fmt.Sprintf("没有找到生效中的静默 %s，发送 /silence list 查看生效中的静默", silenceId)
+		return
+	case 1:
+	default:
+		reply = fmt.Sprintf("静默Id %s 匹配到 %d 条静默，请输入更长的静默Id", silenceId, len(matchedSilences))
+		return
+	}
+	silence := matchedSilences[0]
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("即将解除如下静默:\n")
+	buf.WriteString(fmt.Sprintf("%s %s\n", formatSilenceId(silence), silence.FormatMatchers()))
+	buf.WriteString(fmt.Sprintf("到期时间: %s，创建人: %s\n", silence.EndsAt.Format(silenceTimeLayout), silence.CreatedBy))
+	buf.WriteString(createConfirmHint())
+	setPendingSilenceAction(userMessage, func() (result string, err error) {
+		if err = grafana.ExpireSilence(silence); err != nil {
+			return
+		}
+		slog.Info(fmt.Sprintf("silence %s expired by %s", silence.Key(), userMessage.FromUserId))
+		result = fmt.Sprintf("已解除静默 %s", formatSilenceId(silence))
+		return
+	})
+	reply = buf.String()
+	return
+}
+
+func createConfirmHint() string {
+	return fmt.Sprintf("请在 %d 分钟内回复 /%s confirm 确认，或回复 /%s cancel 取消",
+		int(silenceConfirmTimeout/time.Minute), SilenceCmd, SilenceCmd)
+}
+
+// getPendingSilenceActionKey identifies the user in the conversation, so that the action can be
+// confirmed only by the user who requests it
+func getPendingSilenceActionKey(userMessage *message.UserMessage) string {
+	return fmt.Sprintf("%s/%s/%s", userMessage.Platform, userMessage.ConversationId, userMessage.FromUserId)
+}
+
+// setPendingSilenceAction replaces the previous pending action of the user
+func setPendingSilenceAction(userMessage *message.UserMessage, execute func() (string, error)) {
+	pendingSilenceActionsLock.Lock()
+	defer pendingSilenceActionsLock.Unlock()
+	now := time.Now()
+	for key, action := range pendingSilenceActions {
+		if now.After(action.ExpiresAt) {
+			delete(pendingSilenceActions, key)
+		}
+	}
+	pendingSilenceActions[getPendingSilenceActionKey(userMessage)] = pendingSilenceAction{
+		Execute:   execute,
+		ExpiresAt: now.Add(silenceConfirmTimeout),
+	}
+}
+
+// popPendingSilenceAction removes and returns the pending action of the user if not expired
+func popPendingSilenceAction(userMessage *message.UserMessage) (action pendingSilenceAction, ok bool) {
+	pendingSilenceActionsLock.Lock()
+	defer pendingSilenceActionsLock.Unlock()
+	key := getPendingSilenceActionKey(userMessage)
+	action, ok = pendingSilenceActions[key]
+	delete(pendingSilenceActions, key)
+	if ok && time.Now().After(action.ExpiresAt) {
+		ok = false
+	}
+	return
+}
+
+func confirmSilenceAction(userMessage *message.UserMessage) (reply string, err error) {
+	action, ok := popPendingSilenceAction(userMessage)
+	if !ok {
+		reply = "没有待确认的静默操作，或者操作已超时"
+		return
+	}
+	return action.Execute()
+}
+
+func cancelSilenceAction(userMessage *message.UserMessage) string {
+	if _, ok := popPendingSilenceAction(userMessage); !ok {
+		return "没有待确认的静默操作，或者操作已超时"
+	}
+	return "已取消静默操作"
+}
@@ -0,0 +1,154 @@
+package chatbot
+
+import (
+	"context"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeReplier records the replied texts
+type fakeReplier struct {
+	texts []string
+}
+
+func (r *fakeReplier) ReplyText(text string) error {
+	r.texts = append(r.texts, text)
+	return nil
+}
+
+func (r *fakeReplier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	return nil
+}
+
+func TestParseSilenceRequest(t *testing.T) {
+	testCases := []struct {
+		name         string
+		userInput    string
+		wantMatchers []AlertMatcher
+		wantDuration time.Duration
+		wantComment  string
+		wantErr      bool
+	}{
+		{name: "matchers, duration and comment", userInput: "service=order-service 2h 发布中",
+			wantMatchers: []AlertMatcher{{Name: "service", Value: "order-service", Equal: true}},
+			wantDuration: 2 * time.Hour, wantComment: "发布中"},
+		{name: "default duration", userInput: "service=order env!=dev",
+			wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}, {Name: "env", Value: "dev", Equal: false}},
+			wantDuration: defaultSilenceDuration},
+		{name: "chinese units", userInput: "service=order 1天12小时", wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}},
+			wantDuration: 36 * time.Hour},
+		{name: "only the first duration", userInput: "service=order 30m 2h", wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}},
+			wantDuration: 30 * time.Minute, wantComment: "2h"},
+		{name: "no matchers", userInput: "订单服务发布 1h", wantDuration: time.Hour, wantComment: "订单服务发布"},
+		{name: "max duration", userInput: "service=order 1w", wantMatchers: []AlertMatcher{{Name: "service", Value: "order", Equal: true}},
+			wantDuration: maxSilenceDuration},
+		{name: "exceeds max duration", userInput: "service=order 2w", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request, err := ParseSilenceRequest(testCase.userInput)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, testCase.wantErr)
+			}
+			if testCase.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(request.Matchers, testCase.wantMatchers) {
+				t.Errorf("matchers = %+v, want %+v", request.Matchers, testCase.wantMatchers)
+			}
+			if request.Duration != testCase.wantDuration {
+				t.Errorf("duration = %s, want %s", request.Duration, testCase.wantDuration)
+			}
+			if request.Comment != testCase.wantComment {
+				t.Errorf("comment = %q, want %q", request.Comment, testCase.wantComment)
+			}
+		})
+	}
+}
+
+func TestParseSilenceDuration(t *testing.T) {
+	testCases := []struct {
+		word string
+		want time.Duration
+	}{
+		{word: "30m", want: 30 * time.Minute},
+		{word: "2h", want: 2 * time.Hour},
+		{word: "1d12h", want: 36 * time.Hour},
+		{word: "1w", want: 7 * 24 * time.Hour},
+		{word: "90分钟", want: 90 * time.Minute},
+		{word: "2小时30分钟", want: 150 * time.Minute},
+		{word: "3天", want: 72 * time.Hour},
+		{word: "1周", want: 7 * 24 * time.Hour},
+	}
+	for _, testCase := range testCases {
+		if !silenceDurationRegexp.MatchString(testCase.word) {
+			t.Errorf("%q is not matched as a duration", testCase.word)
+		}
+		if got := parseSilenceDuration(testCase.word); got != testCase.want {
+			t.Errorf("parseSilenceDuration(%q) = %s, want %s", testCase.word, got, testCase.want)
+		}
+	}
+	for _, word := range []string{"2", "h", "2hours", "1.5h", "发布"} {
+		if silenceDurationRegexp.MatchString(word) {
+			t.Errorf("%q is matched as a duration", word)
+		}
+	}
+}
+
+func TestFormatSilenceDuration(t *testing.T) {
+	testCases := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{duration: 2 * time.Hour, want: "2h"},
+		{duration: 36 * time.Hour, want: "1d12h"},
+		{duration: 90 * time.Minute, want: "1h30m"},
+		{duration: 24*time.Hour + 5*time.Minute, want: "1d5m"},
+		{duration: 0, want: "0m"},
+	}
+	for _, testCase := range testCases {
+		if got := formatSilenceDuration(testCase.duration); got != testCase.want {
+			t.Errorf("formatSilenceDuration(%s) = %q, want %q", testCase.duration, got, testCase.want)
+		}
+	}
+}
+
+func TestHandleSilenceCommandWithoutUser(t *testing.T) {
+	for _, input := range []string{"service=order 2h", "create service=order", "expire abc", "confirm", "cancel"} {
+		t.Run(input, func(t *testing.T) {
+			replier := &fakeReplier{}
+			handleSilenceCommand(context.Background(), &message.UserMessage{
+				Platform:       "test",
+				Input:          input,
+				ConversationId: "group",
+				Replier:        replier,
+			})
+			if len(replier.texts) != 1 || !strings.Contains(replier.texts[0], "无法识别发送人") {
+				t.Errorf("replies = %v, want the refusal", replier.texts)
+			}
+		})
+	}
+}
+
+func TestConfirmSilenceActionByOtherUser(t *testing.T) {
+	requester := &message.UserMessage{Platform: "test", ConversationId: "group", FromUserId: "alice"}
+	other := &message.UserMessage{Platform: "test", ConversationId: "group", FromUserId: "bob"}
+	var executed bool
+	setPendingSilenceAction(requester, func() (string, error) {
+		executed = true
+		return "done", nil
+	})
+	if reply, _ := confirmSilenceAction(other); executed || !strings.Contains(reply, "没有待确认") {
+		t.Errorf("action confirmed by the other user, reply %q", reply)
+	}
+	if reply, err := confirmSilenceAction(requester); err != nil || !executed || reply != "done" {
+		t.Errorf("reply = %q, err = %v, executed = %v, want the action executed", reply, err, executed)
+	}
+	if reply, _ := confirmSilenceAction(requester); !strings.Contains(reply, "没有待确认") {
+		t.Errorf("action confirmed twice, reply %q", reply)
+	}
+}
@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureVersion = "v0"
+	// MaxRequestAge is the timestamp window to prevent replay attacks
+	MaxRequestAge = time.Minute * 5
+)
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+
+// CalcSlackSignature calculates the request signature with the app signing secret.
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func CalcSlackSignature(timestamp string, body []byte, signingSecret string) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("%s:%s:", SignatureVersion, timestamp)))
+	mac.Write(body)
+	return fmt.Sprintf("%s=%s", SignatureVersion, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// VerifySlackSignature checks both the timestamp window and the signature of the request
+func VerifySlackSignature(timestamp, signature string, body []byte, signingSecret string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > MaxRequestAge || age < -MaxRequestAge {
+		return fmt.Errorf("timestamp %s out of window", timestamp)
+	}
+	localSignature := CalcSlackSignature(timestamp, body, signingSecret)
+	if !hmac.Equal([]byte(localSignature), []byte(signature)) {
+		return fmt.Errorf("signature not match")
+	}
+	return nil
+}
+
+// ParseMentionText strips the bot mentions from the app_mention text and returns
+// the slash command (if the text starts with one) and the remaining user input.
+// e.g. "<@U0LAN0Z89> /Grafana kafka lag" returns "Grafana" and "kafka lag"
+func ParseMentionText(text string) (command, input string) {
+	return message.ParseCommand(mentionPattern.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,56 @@
+package slack
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySlackSignature(t *testing.T) {
+	signingSecret := "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&command=%2Fgrafana&text=kafka")
+	now := time.Unix(1531420618, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := CalcSlackSignature(timestamp, body, signingSecret)
+	testCases := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", timestamp: timestamp, signature: signature, body: body, now: now},
+		{name: "valid within window", timestamp: timestamp, signature: signature, body: body, now: now.Add(MaxRequestAge - time.Second)},
+		{name: "wrong signature", timestamp: timestamp, signature: "v0=0123", body: body, now: now, wantErr: true},
+		{name: "tampered body", timestamp: timestamp, signature: signature, body: append(body, '1'), now: now, wantErr: true},
+		{name: "replayed request", timestamp: timestamp, signature: signature, body: body, now: now.Add(MaxRequestAge + time.Second), wantErr: true},
+		{name: "timestamp in the future", timestamp: timestamp, signature: signature, body: body, now: now.Add(-MaxRequestAge - time.Second), wantErr: true},
+		{name: "invalid timestamp", timestamp: "abc", signature: signature, body: body, now: now, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := VerifySlackSignature(testCase.timestamp, testCase.signature, testCase.body, signingSecret, testCase.now)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("VerifySlackSignature() err = %v, wantErr %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMentionText(t *testing.T) {
+	testCases := []struct {
+		text        string
+		wantCommand string
+		wantInput   string
+	}{
+		{text: "<@U0LAN0Z89> /Grafana kafka lag", wantCommand: "Grafana", wantInput: "kafka lag"},
+		{text: "<@U0LAN0Z89|copilot> kafka lag", wantCommand: "", wantInput: "kafka lag"},
+	}
+	for _, testCase := range testCases {
+		command, input := ParseMentionText(testCase.text)
+		if command != testCase.wantCommand || input != testCase.wantInput {
+			t.Errorf("ParseMentionText(%q) = %q, %q, want %q, %q", testCase.text, command, input, testCase.wantCommand, testCase.wantInput)
+		}
+	}
+}
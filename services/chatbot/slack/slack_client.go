@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout = time.Second * 10 // 10 seconds
+	DefaultAPIBase = "https://slack.com/api"
+)
+
+type Config struct {
+	Timeout int `json:"timeout"`
+	// APIBase is the slack web api base, it can be pointed to a local fake slack web api in tests
+	APIBase  string `json:"apiBase"`
+	BotToken string `json:"botToken"`
+}
+
+// Client is a slack web api client to send messages
+type Client struct {
+	httpClient *http.Client
+	APIBase    string
+	BotToken   string
+}
+
+func NewClient(cfg *Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return NewClientWithHttpClient(cfg, &http.Client{Timeout: timeout})
+}
+
+func NewClientWithHttpClient(cfg *Config, httpClient *http.Client) *Client {
+	apiBase := cfg.APIBase
+	if apiBase == "" {
+		apiBase = DefaultAPIBase
+	}
+	return &Client{
+		httpClient: httpClient,
+		APIBase:    strings.TrimSuffix(apiBase, "/"),
+		BotToken:   cfg.BotToken,
+	}
+}
+
+// PostMessage sends a message to the channel.
+// See https://api.slack.com/methods/chat.postMessage
+func (c *Client) PostMessage(message *PostMessageRequest) (data PostMessageResponse, err error) {
+	reqBody, mErr := json.Marshal(message)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	req, newErr := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/chat.postMessage", c.APIBase), bytes.NewReader(reqBody))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.BotToken))
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// check status code
+	if resp.StatusCode != http.StatusOK {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	decoder := json.NewDecoder(resp.Body)
+	if decErr := decoder.Decode(&data); decErr != nil {
+		err = fmt.Errorf("parse response error, %s", decErr.Error())
+		return
+	}
+	// check logic result
+	if !data.Ok {
+		err = fmt.Errorf("call api error, %s", data.Error)
+		return
+	}
+	return
+}
+
+// PostResponse posts the deferred reply of the slash command to its response_url, which works in the
+// channels the bot is not a member of and in the direct messages.
+// See https://api.slack.com/interactivity/handling#message_responses
+func (c *Client) PostResponse(responseURL string, message *ResponseMessage) (err error) {
+	reqBody, mErr := json.Marshal(message)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	req, newErr := http.NewRequest(http.MethodPost, responseURL, bytes.NewReader(reqBody))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// discard response body to reuse underline tcp connections
+	_, _ = io.Copy(io.Discard, resp.Body)
+	// check status code
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package slack
+
+const (
+	EventTypeURLVerification = "url_verification"
+	EventTypeEventCallback   = "event_callback"
+	EventTypeAppMention      = "app_mention"
+)
+
+const (
+	ResponseTypeInChannel = "in_channel"
+	ResponseTypeEphemeral = "ephemeral"
+)
+
+const (
+	BlockTypeSection = "section"
+	BlockTypeDivider = "divider"
+	TextTypeMrkdwn   = "mrkdwn"
+	TextTypePlain    = "plain_text"
+	ElementButton    = "button"
+)
+
+// EventBody is the outer body of the Events API request.
+// See https://api.slack.com/apis/connections/events-api#receiving-events
+type EventBody struct {
+	Token     string        `json:"token"`
+	Type      string        `json:"type"`
+	Challenge string        `json:"challenge"`
+	TeamId    string        `json:"team_id"`
+	ApiAppId  string        `json:"api_app_id"`
+	EventId   string        `json:"event_id"`
+	EventTime int64         `json:"event_time"`
+	Event     CallbackEvent `json:"event"`
+}
+
+// CallbackEvent is the inner event of the event_callback request, only app_mention is handled now.
+type CallbackEvent struct {
+	Type     string `json:"type"`
+	User     string `json:"user"`
+	Text     string `json:"text"`
+	Ts       string `json:"ts"`
+	ThreadTs string `json:"thread_ts"`
+	Channel  string `json:"channel"`
+	BotId    string `json:"bot_id"`
+}
+
+// SlashCommand is the form posted by slack when user invokes the slash command.
+// See https://api.slack.com/interactivity/slash-commands#app_command_handling
+type SlashCommand struct {
+	Command     string
+	Text        string
+	UserId      string
+	ChannelId   string
+	TeamId      string
+	ResponseURL string
+	TriggerId   string
+}
+
+type PostMessageRequest struct {
+	Channel  string  `json:"channel"`
+	Text     string  `json:"text"`
+	ThreadTs string  `json:"thread_ts,omitempty"`
+	Blocks   []Block `json:"blocks,omitempty"`
+}
+
+// ResponseMessage is the deferred reply posted to the response_url of the slash command
+type ResponseMessage struct {
+	ResponseType string  `json:"response_type"`
+	Text         string  `json:"text"`
+	Blocks       []Block `json:"blocks,omitempty"`
+}
+
+type PostMessageResponse struct {
+	Ok      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+}
+
+// Block is a simplified block kit layout block, only section and divider are used now.
+// See https://api.slack.com/reference/block-kit/blocks
+type Block struct {
+	Type      string   `json:"type"`
+	Text      *Text    `json:"text,omitempty"`
+	Accessory *Element `json:"accessory,omitempty"`
+}
+
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type Element struct {
+	Type     string `json:"type"`
+	Text     *Text  `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"`
+	ActionId string `json:"action_id,omitempty"`
+}
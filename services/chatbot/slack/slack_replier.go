@@ -0,0 +1,116 @@
+package slack
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"strings"
+)
+
+const PlatformName = "slack"
+
+// Replier posts the copilot replies to the slack channel (and thread) and mentions the user who asks.
+// The replies of the slash commands are posted to the response_url instead.
+type Replier struct {
+	client      *Client
+	channel     string
+	threadTs    string
+	userId      string
+	responseURL string
+}
+
+func NewReplier(client *Client, channel, threadTs, userId string) *Replier {
+	return &Replier{
+		client:   client,
+		channel:  channel,
+		threadTs: threadTs,
+		userId:   userId,
+	}
+}
+
+// NewMentionUserMessage converts the app_mention event to the platform neutral user message,
+// the reply is posted in the thread of the mention message.
+func NewMentionUserMessage(event *CallbackEvent, client *Client) *message.UserMessage {
+	command, input := ParseMentionText(event.Text)
+	threadTs := event.ThreadTs
+	if threadTs == "" {
+		threadTs = event.Ts
+	}
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        command,
+		Input:          input,
+		FromUserId:     event.User,
+		ConversationId: event.Channel,
+		Replier:        NewReplier(client, event.Channel, threadTs, event.User),
+	}
+}
+
+// NewSlashCommandUserMessage converts the slash command to the platform neutral user message
+func NewSlashCommandUserMessage(cmd *SlashCommand, client *Client) *message.UserMessage {
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        strings.TrimPrefix(cmd.Command, "/"),
+		Input:          cmd.Text,
+		FromUserId:     cmd.UserId,
+		ConversationId: cmd.ChannelId,
+		Replier:        NewResponseReplier(client, cmd.ResponseURL, cmd.UserId),
+	}
+}
+
+// NewResponseReplier creates the replier which posts to the response_url of the slash command
+func NewResponseReplier(client *Client, responseURL, userId string) *Replier {
+	return &Replier{
+		client:      client,
+		userId:      userId,
+		responseURL: responseURL,
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.post(fmt.Sprintf("<@%s> %s", r.userId, text), nil)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	// text is used as the fallback of notifications
+	return r.post(fmt.Sprintf("Found %d dashboards for you", len(dashboards)), CreateDashboardBlocks(r.userId, dashboards))
+}
+
+func (r *Replier) post(text string, blocks []Block) (err error) {
+	if r.responseURL != "" {
+		return r.client.PostResponse(r.responseURL, &ResponseMessage{
+			ResponseType: ResponseTypeInChannel,
+			Text:         text,
+			Blocks:       blocks,
+		})
+	}
+	_, err = r.client.PostMessage(&PostMessageRequest{
+		Channel:  r.channel,
+		ThreadTs: r.threadTs,
+		Text:     text,
+		Blocks:   blocks,
+	})
+	return
+}
+
+// CreateDashboardBlocks renders the dashboards as block kit sections with a link button each
+func CreateDashboardBlocks(userId string, dashboards []grafana.Dashboard) []Block {
+	blocks := make([]Block, 0, len(dashboards)+2)
+	blocks = append(blocks, Block{
+		Type: BlockTypeSection,
+		Text: &Text{Type: TextTypeMrkdwn, Text: fmt.Sprintf("<@%s> Found the following dashboards:", userId)},
+	}, Block{Type: BlockTypeDivider})
+	for index, dashboard := range dashboards {
+		blocks = append(blocks, Block{
+			Type: BlockTypeSection,
+			Text: &Text{Type: TextTypeMrkdwn, Text: fmt.Sprintf("*<%s|%s>*", dashboard.URL, dashboard.Title)},
+			Accessory: &Element{
+				Type:     ElementButton,
+				Text:     &Text{Type: TextTypePlain, Text: "Open"},
+				URL:      dashboard.URL,
+				ActionId: fmt.Sprintf("open_dashboard_%d", index),
+			},
+		})
+	}
+	return blocks
+}
@@ -0,0 +1,145 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+)
+
+const (
+	SnapshotCmd = "Snapshot"
+)
+
+const (
+	// maxImageDownscales limits the times to downscale the image to fit the platform limit
+	maxImageDownscales = 5
+	// imageDownscaleRatio is the ratio of the width and height for each downscale
+	imageDownscaleRatio = 0.75
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:        SnapshotCmd,
+		Aliases:     []string{"render", "截图"},
+		Usage:       "/Snapshot <问题>，例如 /Snapshot 最近3小时 checkout 的 p99 延迟",
+		Description: "匹配最合适的 Grafana 面板，并将面板截图发送到群里，需要 Grafana 安装图片渲染插件",
+		Handler:     handleSnapshotCommand,
+	})
+}
+
+// handleSnapshotCommand renders the best matched panel and sends the image with the panel link
+func handleSnapshotCommand(ctx context.Context, userMessage *message.UserMessage) {
+	suggestedPanels, err := MatchPanels(ctx, userMessage.Input)
+	if err != nil || len(suggestedPanels) == 0 {
+		var errMsg string
+		if err != nil {
+			errMsg = fmt.Sprintf("Handle grafana snapshot copilot err: %s", err.Error())
+		} else {
+			errMsg = "没有找到匹配的面板，请尝试其他问题"
+		}
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	panel := suggestedPanels[0]
+	NotifyUserResult(userMessage, []grafana.Dashboard{panel})
+	imageReplier, ok := userMessage.Replier.(message.ImageReplier)
+	if !ok {
+		NotifyUserText(userMessage, "当前平台暂不支持发送图片，请点击链接查看面板")
+		return
+	}
+	snapshot, err := RenderPanelSnapshot(panel, imageReplier.MaxImageSize())
+	if err != nil {
+		errMsg := fmt.Sprintf("Render grafana panel err: %s", err.Error())
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, fmt.Sprintf("面板截图失败，请点击链接查看面板，%s", errMsg))
+		return
+	}
+	if err = imageReplier.ReplyImage(snapshot); err != nil {
+		slog.Error(fmt.Sprintf("send image to %s error: %v", userMessage.Platform, err))
+	}
+}
+
+// RenderPanelSnapshot renders the panel by the grafana image renderer and shrinks the png image to
+// the max size accepted by the chat platform
+func RenderPanelSnapshot(panel grafana.Dashboard, maxSize int) (snapshot []byte, err error) {
+	snapshot, err = grafana.RenderPanel(panel.Source, panel.OrgId, panel.URL,
+		conf.AppConfig.CopilotRenderWidth, conf.AppConfig.CopilotRenderHeight)
+	if err != nil {
+		return
+	}
+	return fitImageSize(snapshot, maxSize)
+}
+
+// fitImageSize re-encodes the png image with the best compression first, then downscales it
+// step by step until the size is within the limit
+func fitImageSize(pngData []byte, maxSize int) (fitData []byte, err error) {
+	if len(pngData) <= maxSize {
+		return pngData, nil
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		err = fmt.Errorf("decode png image err, %s", err.Error())
+		return
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	for index := 0; ; index++ {
+		buf := bytes.NewBuffer(nil)
+		if err = encoder.Encode(buf, img); err != nil {
+			err = fmt.Errorf("encode png image err, %s", err.Error())
+			return
+		}
+		slog.Debug(fmt.Sprintf("encode png image of %dx%d, size %d", img.Bounds().Dx(), img.Bounds().Dy(), buf.Len()))
+		if buf.Len() <= maxSize {
+			fitData = buf.Bytes()
+			return
+		}
+		if index == maxImageDownscales {
+			err = fmt.Errorf("png image size %d exceeds the limit %d", buf.Len(), maxSize)
+			return
+		}
+		img = downscaleImage(img, imageDownscaleRatio)
+	}
+}
+
+// downscaleImage resizes the image by the ratio, each target pixel is the average of the source
+// pixels it covers, which keeps the lines of the graphs readable
+func downscaleImage(img image.Image, ratio float64) image.Image {
+	bounds := img.Bounds()
+	width := max(int(float64(bounds.Dx())*ratio), 1)
+	height := max(int(float64(bounds.Dy())*ratio), 1)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		srcMinY := bounds.Min.Y + y*bounds.Dy()/height
+		srcMaxY := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, srcMinY+1)
+		for x := 0; x < width; x++ {
+			srcMinX := bounds.Min.X + x*bounds.Dx()/width
+			srcMaxX := max(bounds.Min.X+(x+1)*bounds.Dx()/width, srcMinX+1)
+			var r, g, b, a, count uint32
+			for srcY := srcMinY; srcY < srcMaxY; srcY++ {
+				for srcX := srcMinX; srcX < srcMaxX; srcX++ {
+					pixel := color.NRGBAModel.Convert(img.At(srcX, srcY)).(color.NRGBA)
+					r += uint32(pixel.R)
+					g += uint32(pixel.G)
+					b += uint32(pixel.B)
+					a += uint32(pixel.A)
+					count++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / count),
+				G: uint8(g / count),
+				B: uint8(b / count),
+				A: uint8(a / count),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package chatbot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+// createTestPNG creates the png image without compression, the noisy image can't be compressed
+func createTestPNG(t *testing.T, width, height int, noisy bool) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	random := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := color.NRGBA{R: 30, G: 120, B: 200, A: 255}
+			if noisy {
+				pixel = color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 255}
+			}
+			img.SetNRGBA(x, y, pixel)
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	encoder := png.Encoder{CompressionLevel: png.NoCompression}
+	if err := encoder.Encode(buf, img); err != nil {
+		t.Fatalf("encode png err: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFitImageSize(t *testing.T) {
+	solid := createTestPNG(t, 200, 100, false)
+	noisy := createTestPNG(t, 200, 100, true)
+	testCases := []struct {
+		name    string
+		data    []byte
+		maxSize int
+		// wantWidth is the width of the fit image, 0 means the data is returned unchanged
+		wantWidth int
+		wantErr   bool
+	}{
+		{name: "within the limit", data: solid, maxSize: len(solid), wantWidth: 0},
+		{name: "recompressed", data: solid, maxSize: len(solid) / 10, wantWidth: 200},
+		{name: "downscaled", data: noisy, maxSize: len(noisy) / 3, wantWidth: 112},
+		{name: "too large after downscales", data: noisy, maxSize: 100, wantErr: true},
+		{name: "invalid png", data: []byte("not a png image"), maxSize: 1, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fitData, err := fitImageSize(testCase.data, testCase.maxSize)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, testCase.wantErr)
+			}
+			if testCase.wantErr {
+				return
+			}
+			if len(fitData) > testCase.maxSize {
+				t.Errorf("size = %d, exceeds the limit %d", len(fitData), testCase.maxSize)
+			}
+			if testCase.wantWidth == 0 {
+				if !bytes.Equal(fitData, testCase.data) {
+					t.Errorf("data is changed")
+				}
+				return
+			}
+			img, err := png.Decode(bytes.NewReader(fitData))
+			if err != nil {
+				t.Fatalf("decode fit image err: %v", err)
+			}
+			if img.Bounds().Dx() != testCase.wantWidth {
+				t.Errorf("width = %d, want %d", img.Bounds().Dx(), testCase.wantWidth)
+			}
+		})
+	}
+}
+
+func TestDownscaleImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	// the left half is black and the right half is white
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			value := uint8(0)
+			if x >= 2 {
+				value = 255
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: value, G: value, B: value, A: 255})
+		}
+	}
+	scaled := downscaleImage(img, 0.5)
+	if scaled.Bounds().Dx() != 2 || scaled.Bounds().Dy() != 1 {
+		t.Fatalf("size = %v, want 2x1", scaled.Bounds())
+	}
+	left := color.NRGBAModel.Convert(scaled.At(0, 0)).(color.NRGBA)
+	right := color.NRGBAModel.Convert(scaled.At(1, 0)).(color.NRGBA)
+	if left.R != 0 || right.R != 255 || left.A != 255 {
+		t.Errorf("pixels = %v, %v, want black and white", left, right)
+	}
+	if tiny := downscaleImage(image.NewNRGBA(image.Rect(0, 0, 1, 1)), 0.5); tiny.Bounds().Dx() != 1 {
+		t.Errorf("width = %d, want at least 1", tiny.Bounds().Dx())
+	}
+}
@@ -0,0 +1,284 @@
+package teams
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BotFrameworkIssuer is the issuer of the tokens sent by the bot connector service
+	BotFrameworkIssuer = "https://api.botframework.com"
+	// jwksCacheTTL is the cache time of the signing keys, keys are refetched earlier if the kid is unknown
+	jwksCacheTTL = time.Hour * 24
+	// jwksRefetchInterval limits the refetches triggered by the unknown kids
+	jwksRefetchInterval = time.Minute
+	// maxUnknownKids limits the negative cache of the unknown kids
+	maxUnknownKids = 1000
+	// clockSkew is the allowed clock skew when checking exp and nbf
+	clockSkew = time.Minute * 5
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Claims is the claims of the bot connector token used by the copilot
+type Claims struct {
+	Issuer     string `json:"iss"`
+	Audience   string `json:"aud"`
+	ExpiresAt  int64  `json:"exp"`
+	NotBefore  int64  `json:"nbf"`
+	ServiceURL string `json:"serviceurl"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// TokenValidator validates the JWT bearer token sent by the bot connector service against the JWKS.
+// See https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-authentication#connector-to-bot
+type TokenValidator struct {
+	httpClient *http.Client
+	JWKSURL    string
+	Issuer     string
+	AppId      string
+	// ServiceHosts are the allowed hosts of the service urls, the leading `*.` matches any subdomain
+	ServiceHosts []string
+
+	// fetchLock serializes the jwks fetches, the cached keys are still readable while fetching
+	fetchLock     sync.Mutex
+	keysLock      sync.RWMutex
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+	// lastFetchAt is the time of the last fetch attempt, including the failed ones
+	lastFetchAt time.Time
+	// unknownKids caches the kids not found in the keys for jwksRefetchInterval
+	unknownKids map[string]time.Time
+}
+
+func NewTokenValidator(jwksURL, appId string, serviceHosts []string) *TokenValidator {
+	return &TokenValidator{
+		httpClient:   &http.Client{Timeout: DefaultTimeout},
+		JWKSURL:      jwksURL,
+		Issuer:       BotFrameworkIssuer,
+		AppId:        appId,
+		ServiceHosts: serviceHosts,
+	}
+}
+
+// ValidateAuthorization validates the `Authorization: Bearer <jwt>` header and returns the claims
+func (v *TokenValidator) ValidateAuthorization(authorization string, now time.Time) (claims Claims, err error) {
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found || token == "" {
+		err = fmt.Errorf("no bearer token")
+		return
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		err = fmt.Errorf("malformed token")
+		return
+	}
+	var header jwtHeader
+	if err = decodeSegment(parts[0], &header); err != nil {
+		err = fmt.Errorf("invalid token header, %s", err.Error())
+		return
+	}
+	if header.Alg != "RS256" {
+		err = fmt.Errorf("unsupported alg %s", header.Alg)
+		return
+	}
+	publicKey, err := v.getPublicKey(header.Kid)
+	if err != nil {
+		return
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		err = fmt.Errorf("invalid token signature, %s", err.Error())
+		return
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		err = fmt.Errorf("verify token signature err, %s", err.Error())
+		return
+	}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		err = fmt.Errorf("invalid token claims, %s", err.Error())
+		return
+	}
+	if claims.Issuer != v.Issuer {
+		err = fmt.Errorf("invalid issuer %s", claims.Issuer)
+		return
+	}
+	if claims.Audience != v.AppId {
+		err = fmt.Errorf("invalid audience %s", claims.Audience)
+		return
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		err = fmt.Errorf("token expired")
+		return
+	}
+	if claims.NotBefore > 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-clockSkew)) {
+		err = fmt.Errorf("token not valid yet")
+		return
+	}
+	return
+}
+
+// VerifyServiceURL checks that the service url of the activity is the same as the serviceurl claim of the
+// token and is an https url of the allowed hosts, the bot access token is sent to this url when replying
+func (v *TokenValidator) VerifyServiceURL(claims Claims, serviceURL string) error {
+	if claims.ServiceURL == "" {
+		return fmt.Errorf("no serviceurl claim")
+	}
+	if claims.ServiceURL != serviceURL {
+		return fmt.Errorf("service url not match, %s != %s", claims.ServiceURL, serviceURL)
+	}
+	parsedURL, err := url.Parse(serviceURL)
+	if err != nil {
+		return fmt.Errorf("invalid service url, %s", err.Error())
+	}
+	if parsedURL.Scheme != "https" || parsedURL.User != nil || !matchServiceHost(v.ServiceHosts, parsedURL.Host) {
+		return fmt.Errorf("service url host %q not allowed", parsedURL.Host)
+	}
+	return nil
+}
+
+func matchServiceHost(serviceHosts []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, serviceHost := range serviceHosts {
+		serviceHost = strings.ToLower(serviceHost)
+		if suffix, found := strings.CutPrefix(serviceHost, "*"); found {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == serviceHost {
+			return true
+		}
+	}
+	return false
+}
+
+// getPublicKey returns the cached public key by kid. The keys are refetched when expired or the kid is unknown,
+// but at most once per jwksRefetchInterval, and the unknown kids are negatively cached for the same interval,
+// so that the tokens with random kids can not flood the jwks endpoint or block the other validations.
+func (v *TokenValidator) getPublicKey(kid string) (publicKey *rsa.PublicKey, err error) {
+	publicKey, fresh, known := v.lookupKey(kid)
+	if fresh || !known {
+		return v.checkKey(kid, publicKey, known)
+	}
+	v.fetchLock.Lock()
+	defer v.fetchLock.Unlock()
+	// the keys may be refetched by another request while waiting for the lock
+	publicKey, fresh, known = v.lookupKey(kid)
+	if fresh || !known || time.Since(v.lastFetchAt) < jwksRefetchInterval {
+		return v.checkKey(kid, publicKey, known)
+	}
+	v.lastFetchAt = time.Now()
+	keys, fetchErr := v.fetchKeys()
+	if fetchErr != nil {
+		// keep using the stale key if the jwks endpoint is unavailable
+		if publicKey != nil {
+			return
+		}
+		err = fetchErr
+		return
+	}
+	v.keysLock.Lock()
+	v.keys = keys
+	v.keysFetchedAt = v.lastFetchAt
+	v.unknownKids = nil
+	v.keysLock.Unlock()
+	publicKey = keys[kid]
+	return v.checkKey(kid, publicKey, true)
+}
+
+// lookupKey returns the cached key and whether it is within the cache ttl. The known is false if the kid
+// is negatively cached in the last jwksRefetchInterval.
+func (v *TokenValidator) lookupKey(kid string) (publicKey *rsa.PublicKey, fresh, known bool) {
+	v.keysLock.RLock()
+	defer v.keysLock.RUnlock()
+	publicKey = v.keys[kid]
+	fresh = publicKey != nil && time.Since(v.keysFetchedAt) < jwksCacheTTL
+	unknownAt, unknown := v.unknownKids[kid]
+	known = !unknown || time.Since(unknownAt) >= jwksRefetchInterval
+	return
+}
+
+// checkKey returns the error of the unknown kid and adds it to the negative cache
+func (v *TokenValidator) checkKey(kid string, publicKey *rsa.PublicKey, known bool) (*rsa.PublicKey, error) {
+	if publicKey != nil {
+		return publicKey, nil
+	}
+	if known {
+		v.keysLock.Lock()
+		if v.unknownKids == nil || len(v.unknownKids) >= maxUnknownKids {
+			v.unknownKids = make(map[string]time.Time)
+		}
+		v.unknownKids[kid] = time.Now()
+		v.keysLock.Unlock()
+	}
+	return nil, fmt.Errorf("unknown kid %s", kid)
+}
+
+func (v *TokenValidator) fetchKeys() (keys map[string]*rsa.PublicKey, err error) {
+	resp, err := v.httpClient.Get(v.JWKSURL)
+	if err != nil {
+		err = fmt.Errorf("get jwks error, %s", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("get jwks error, %s", resp.Status)
+		return
+	}
+	var keySet jsonWebKeySet
+	if decErr := json.NewDecoder(resp.Body).Decode(&keySet); decErr != nil {
+		err = fmt.Errorf("parse jwks error, %s", decErr.Error())
+		return
+	}
+	keys = make(map[string]*rsa.PublicKey)
+	for _, key := range keySet.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		nBytes, nErr := base64.RawURLEncoding.DecodeString(key.N)
+		eBytes, eErr := base64.RawURLEncoding.DecodeString(key.E)
+		if nErr != nil || eErr != nil {
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}
+	}
+	return
+}
+
+func decodeSegment(segment string, v any) error {
+	segmentBytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(segmentBytes, v)
+}
@@ -0,0 +1,181 @@
+package teams
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testAppId      = "test-app-id"
+	testKid        = "test-kid"
+	testServiceURL = "https://smba.trafficmanager.net/amer/"
+)
+
+var testServiceHosts = []string{"smba.trafficmanager.net", "*.botframework.com"}
+
+// jwksStandIn serves the public key of the test signing key as the bot framework jwks endpoint
+type jwksStandIn struct {
+	server     *httptest.Server
+	privateKey *rsa.PrivateKey
+	fetches    atomic.Int32
+}
+
+func newJWKSStandIn(t *testing.T) *jwksStandIn {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standIn := &jwksStandIn{privateKey: privateKey}
+	standIn.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		standIn.fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: testKid,
+			N:   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(standIn.server.Close)
+	return standIn
+}
+
+func (s *jwksStandIn) newValidator() *TokenValidator {
+	return NewTokenValidator(s.server.URL, testAppId, testServiceHosts)
+}
+
+func signTestToken(t *testing.T, privateKey *rsa.PrivateKey, header map[string]string, claims map[string]any) string {
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestClaims(now time.Time) map[string]any {
+	return map[string]any{
+		"iss":        BotFrameworkIssuer,
+		"aud":        testAppId,
+		"exp":        now.Add(time.Hour).Unix(),
+		"nbf":        now.Add(-time.Minute).Unix(),
+		"serviceurl": testServiceURL,
+	}
+}
+
+func TestValidateAuthorization(t *testing.T) {
+	standIn := newJWKSStandIn(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	header := map[string]string{"alg": "RS256", "kid": testKid}
+	withClaim := func(name string, value any) map[string]any {
+		claims := newTestClaims(now)
+		claims[name] = value
+		return claims
+	}
+	testCases := []struct {
+		name          string
+		authorization string
+		wantErr       bool
+	}{
+		{name: "valid token", authorization: "Bearer " + signTestToken(t, standIn.privateKey, header, newTestClaims(now))},
+		{name: "wrong audience", authorization: "Bearer " + signTestToken(t, standIn.privateKey, header, withClaim("aud", "other-app-id")), wantErr: true},
+		{name: "wrong issuer", authorization: "Bearer " + signTestToken(t, standIn.privateKey, header, withClaim("iss", "https://example.com")), wantErr: true},
+		{name: "expired token", authorization: "Bearer " + signTestToken(t, standIn.privateKey, header, withClaim("exp", now.Add(-clockSkew-time.Minute).Unix())), wantErr: true},
+		{name: "token not valid yet", authorization: "Bearer " + signTestToken(t, standIn.privateKey, header, withClaim("nbf", now.Add(clockSkew+time.Minute).Unix())), wantErr: true},
+		{name: "unknown kid", authorization: "Bearer " + signTestToken(t, standIn.privateKey, map[string]string{"alg": "RS256", "kid": "other-kid"}, newTestClaims(now)), wantErr: true},
+		{name: "signed by other key", authorization: "Bearer " + signTestToken(t, otherKey, header, newTestClaims(now)), wantErr: true},
+		{name: "unsupported alg", authorization: "Bearer " + signTestToken(t, standIn.privateKey, map[string]string{"alg": "none", "kid": testKid}, newTestClaims(now)), wantErr: true},
+		{name: "no bearer token", authorization: "Basic dXNlcjpwYXNz", wantErr: true},
+		{name: "malformed token", authorization: "Bearer abc.def", wantErr: true},
+	}
+	validator := standIn.newValidator()
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			claims, err := validator.ValidateAuthorization(testCase.authorization, now)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("ValidateAuthorization() err = %v, wantErr %v", err, testCase.wantErr)
+			}
+			if err == nil && claims.ServiceURL != testServiceURL {
+				t.Errorf("ValidateAuthorization() serviceurl = %s, want %s", claims.ServiceURL, testServiceURL)
+			}
+		})
+	}
+}
+
+func TestGetPublicKeyRefetchLimit(t *testing.T) {
+	standIn := newJWKSStandIn(t)
+	validator := standIn.newValidator()
+	if _, err := validator.getPublicKey(testKid); err != nil {
+		t.Fatalf("getPublicKey() err = %v", err)
+	}
+	// the random kids neither refetch the keys nor break the known kid
+	for i := 0; i < 100; i++ {
+		if _, err := validator.getPublicKey(fmt.Sprintf("random-kid-%d", i)); err == nil {
+			t.Fatal("getPublicKey() of random kid succeeded")
+		}
+	}
+	if _, err := validator.getPublicKey(testKid); err != nil {
+		t.Fatalf("getPublicKey() err = %v", err)
+	}
+	if fetches := standIn.fetches.Load(); fetches != 1 {
+		t.Errorf("jwks fetched %d times, want 1", fetches)
+	}
+	// the unknown kid is refetched once the refetch interval passed, e.g. the keys are rotated
+	validator.lastFetchAt = validator.lastFetchAt.Add(-jwksRefetchInterval)
+	for kid := range validator.unknownKids {
+		validator.unknownKids[kid] = validator.unknownKids[kid].Add(-jwksRefetchInterval)
+	}
+	_, _ = validator.getPublicKey("random-kid-0")
+	_, _ = validator.getPublicKey("random-kid-1")
+	if fetches := standIn.fetches.Load(); fetches != 2 {
+		t.Errorf("jwks fetched %d times, want 2", fetches)
+	}
+}
+
+func TestVerifyServiceURL(t *testing.T) {
+	validator := NewTokenValidator("", testAppId, testServiceHosts)
+	testCases := []struct {
+		name       string
+		claim      string
+		serviceURL string
+		wantErr    bool
+	}{
+		{name: "teams service url", claim: testServiceURL, serviceURL: testServiceURL},
+		{name: "botframework subdomain", claim: "https://webchat.botframework.com/", serviceURL: "https://webchat.botframework.com/"},
+		{name: "no serviceurl claim", claim: "", serviceURL: testServiceURL, wantErr: true},
+		{name: "serviceUrl mismatch", claim: testServiceURL, serviceURL: "https://smba.trafficmanager.net/emea/", wantErr: true},
+		{name: "not allowed host", claim: "https://attacker.example.com/", serviceURL: "https://attacker.example.com/", wantErr: true},
+		{name: "suffix of allowed host", claim: "https://evilbotframework.com/", serviceURL: "https://evilbotframework.com/", wantErr: true},
+		{name: "http scheme", claim: "http://smba.trafficmanager.net/amer/", serviceURL: "http://smba.trafficmanager.net/amer/", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validator.VerifyServiceURL(Claims{ServiceURL: testCase.claim}, testCase.serviceURL)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("VerifyServiceURL() err = %v, wantErr %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
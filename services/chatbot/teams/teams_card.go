@@ -0,0 +1,75 @@
+package teams
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+)
+
+const (
+	AdaptiveCardSchema  = "http://adaptivecards.io/schemas/adaptive-card.json"
+	AdaptiveCardVersion = "1.4"
+)
+
+// AdaptiveCard is a simplified adaptive card, only text blocks and open url actions are used now.
+// See https://adaptivecards.io/explorer/AdaptiveCard.html
+type AdaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []CardElement `json:"body"`
+	Actions []CardAction  `json:"actions,omitempty"`
+	// Msteams is required to render the user mention in the card
+	Msteams *CardMsteams `json:"msteams,omitempty"`
+}
+
+type CardElement struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+}
+
+type CardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type CardMsteams struct {
+	Entities []Entity `json:"entities"`
+}
+
+// CreateDashboardCard renders the suggested dashboards as an adaptive card, each dashboard is
+// listed in the card body and has an open url action.
+func CreateDashboardCard(mention *Entity, dashboards []grafana.Dashboard) *AdaptiveCard {
+	card := AdaptiveCard{
+		Schema:  AdaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: AdaptiveCardVersion,
+	}
+	headline := "Found the following dashboards:"
+	if mention != nil {
+		headline = fmt.Sprintf("%s %s", mention.Text, headline)
+		card.Msteams = &CardMsteams{Entities: []Entity{*mention}}
+	}
+	card.Body = append(card.Body, CardElement{
+		Type:   "TextBlock",
+		Text:   headline,
+		Wrap:   true,
+		Weight: "Bolder",
+	})
+	for _, dashboard := range dashboards {
+		card.Body = append(card.Body, CardElement{
+			Type: "TextBlock",
+			Text: fmt.Sprintf("- [%s](%s)", dashboard.Title, dashboard.URL),
+			Wrap: true,
+		})
+		card.Actions = append(card.Actions, CardAction{
+			Type:  "Action.OpenUrl",
+			Title: dashboard.Title,
+			URL:   dashboard.URL,
+		})
+	}
+	return &card
+}
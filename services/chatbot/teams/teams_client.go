@@ -0,0 +1,141 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout    = time.Second * 10 // 10 seconds
+	DefaultTokenURL   = "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token"
+	botFrameworkScope = "https://api.botframework.com/.default"
+	// tokenRefreshAhead refreshes the access token before it really expires
+	tokenRefreshAhead = time.Minute * 5
+)
+
+type Config struct {
+	Timeout     int    `json:"timeout"`
+	TokenURL    string `json:"tokenURL"`
+	AppId       string `json:"appId"`
+	AppPassword string `json:"appPassword"`
+}
+
+// Client is a bot connector client to reply activities, the access token is cached
+// and refreshed automatically, so the client should be shared.
+type Client struct {
+	httpClient  *http.Client
+	TokenURL    string
+	AppId       string
+	AppPassword string
+
+	tokenLock     sync.Mutex
+	token         string
+	tokenExpireAt time.Time
+}
+
+func NewClient(cfg *Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return NewClientWithHttpClient(cfg, &http.Client{Timeout: timeout})
+}
+
+func NewClientWithHttpClient(cfg *Config, httpClient *http.Client) *Client {
+	tokenURL := cfg.TokenURL
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+	return &Client{
+		httpClient:  httpClient,
+		TokenURL:    tokenURL,
+		AppId:       cfg.AppId,
+		AppPassword: cfg.AppPassword,
+	}
+}
+
+// ReplyToActivity sends the reply activity to the conversation.
+// See https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-api-reference#reply-to-activity
+func (c *Client) ReplyToActivity(serviceURL, conversationId, activityId string, activity *Activity) (data ResourceResponse, err error) {
+	token, err := c.getAccessToken()
+	if err != nil {
+		return
+	}
+	reqBody, mErr := json.Marshal(activity)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	reqURL := fmt.Sprintf("%s/v3/conversations/%s/activities/%s", strings.TrimSuffix(serviceURL, "/"),
+		url.PathEscape(conversationId), url.PathEscape(activityId))
+	req, newErr := http.NewRequest(http.MethodPost, reqURL, bytes.NewReader(reqBody))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	err = c.doRequest(req, &data)
+	return
+}
+
+// getAccessToken returns the cached token or fetches a new one by the client credentials flow.
+// See https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-authentication#bot-to-connector
+func (c *Client) getAccessToken() (token string, err error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpireAt) {
+		token = c.token
+		return
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", c.AppId)
+	form.Set("client_secret", c.AppPassword)
+	form.Set("scope", botFrameworkScope)
+	req, newErr := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var tokenResp TokenResponse
+	if err = c.doRequest(req, &tokenResp); err != nil {
+		err = fmt.Errorf("get access token error, %s", err.Error())
+		return
+	}
+	c.token = tokenResp.AccessToken
+	c.tokenExpireAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - tokenRefreshAhead)
+	token = c.token
+	return
+}
+
+func (c *Client) doRequest(req *http.Request, respBody any) (err error) {
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// check status code, the connector returns 200 or 201 on success
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	decoder := json.NewDecoder(resp.Body)
+	if decErr := decoder.Decode(respBody); decErr != nil && decErr != io.EOF {
+		err = fmt.Errorf("parse response error, %s", decErr.Error())
+		return
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package teams
+
+const (
+	ActivityTypeMessage = "message"
+	EntityTypeMention   = "mention"
+	// ContentTypeAdaptiveCard is the attachment content type of adaptive cards
+	ContentTypeAdaptiveCard = "application/vnd.microsoft.card.adaptive"
+)
+
+// Activity is a simplified bot framework activity, only the fields used by the copilot are kept.
+// See https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-api-reference#activity-object
+type Activity struct {
+	Type         string               `json:"type"`
+	Id           string               `json:"id,omitempty"`
+	Timestamp    string               `json:"timestamp,omitempty"`
+	ServiceURL   string               `json:"serviceUrl,omitempty"`
+	ChannelId    string               `json:"channelId,omitempty"`
+	From         *ChannelAccount      `json:"from,omitempty"`
+	Conversation *ConversationAccount `json:"conversation,omitempty"`
+	Recipient    *ChannelAccount      `json:"recipient,omitempty"`
+	TextFormat   string               `json:"textFormat,omitempty"`
+	Text         string               `json:"text,omitempty"`
+	ReplyToId    string               `json:"replyToId,omitempty"`
+	Entities     []Entity             `json:"entities,omitempty"`
+	Attachments  []Attachment         `json:"attachments,omitempty"`
+}
+
+type ChannelAccount struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type ConversationAccount struct {
+	Id               string `json:"id"`
+	Name             string `json:"name,omitempty"`
+	ConversationType string `json:"conversationType,omitempty"`
+	IsGroup          bool   `json:"isGroup,omitempty"`
+}
+
+type Entity struct {
+	Type      string          `json:"type"`
+	Mentioned *ChannelAccount `json:"mentioned,omitempty"`
+	Text      string          `json:"text,omitempty"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     any    `json:"content"`
+}
+
+type ResourceResponse struct {
+	Id string `json:"id"`
+}
+
+// TokenResponse is the response of the client credentials flow to get the bot connector token
+type TokenResponse struct {
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	AccessToken string `json:"access_token"`
+}
@@ -0,0 +1,96 @@
+package teams
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"regexp"
+)
+
+const PlatformName = "teams"
+
+var mentionPattern = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// Replier replies the copilot results to the activity and mentions the user who asks
+type Replier struct {
+	client   *Client
+	activity *Activity
+}
+
+func NewReplier(client *Client, activity *Activity) *Replier {
+	return &Replier{
+		client:   client,
+		activity: activity,
+	}
+}
+
+// NewUserMessage converts the message activity to the platform neutral user message,
+// the `<at>bot</at>` mentions are stripped from the text.
+func NewUserMessage(activity *Activity, client *Client) *message.UserMessage {
+	command, input := message.ParseCommand(mentionPattern.ReplaceAllString(activity.Text, ""))
+	userMessage := message.UserMessage{
+		Platform: PlatformName,
+		Command:  command,
+		Input:    input,
+		Replier:  NewReplier(client, activity),
+	}
+	if activity.From != nil {
+		userMessage.FromUserId = activity.From.Id
+	}
+	if activity.Conversation != nil {
+		userMessage.ConversationId = activity.Conversation.Id
+	}
+	return &userMessage
+}
+
+func (r *Replier) ReplyText(text string) error {
+	reply := r.newReply()
+	if mention := r.createMention(); mention != nil {
+		reply.Text = fmt.Sprintf("%s %s", mention.Text, text)
+		reply.Entities = []Entity{*mention}
+	} else {
+		reply.Text = text
+	}
+	return r.send(reply)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	reply := r.newReply()
+	reply.Attachments = []Attachment{
+		{
+			ContentType: ContentTypeAdaptiveCard,
+			Content:     CreateDashboardCard(r.createMention(), dashboards),
+		},
+	}
+	return r.send(reply)
+}
+
+func (r *Replier) newReply() *Activity {
+	return &Activity{
+		Type:         ActivityTypeMessage,
+		From:         r.activity.Recipient,
+		Recipient:    r.activity.From,
+		Conversation: r.activity.Conversation,
+		ReplyToId:    r.activity.Id,
+	}
+}
+
+// createMention creates the mention entity of the user who asks, nil if the sender is unknown
+func (r *Replier) createMention() *Entity {
+	if r.activity.From == nil {
+		return nil
+	}
+	return &Entity{
+		Type:      EntityTypeMention,
+		Mentioned: r.activity.From,
+		Text:      fmt.Sprintf("<at>%s</at>", r.activity.From.Name),
+	}
+}
+
+func (r *Replier) send(reply *Activity) (err error) {
+	if r.activity.Conversation == nil {
+		return fmt.Errorf("no conversation in activity")
+	}
+	_, err = r.client.ReplyToActivity(r.activity.ServiceURL, r.activity.Conversation.Id, r.activity.Id, reply)
+	return
+}
@@ -0,0 +1,317 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"github.com/jemygraw/grafana-copilot/services/retrieval"
+	"github.com/tmc/langchaingo/llms"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	GrafanaCmd = "Grafana"
+)
+
+type GrafanaCopilotContext struct {
+	GrafanaDashboards string
+	UserInput         string
+}
+
+// HandleUserInput handles the user message received by any chat adapter and replies
+// the result through the replier bound to the message.
+func HandleUserInput(userMessage *message.UserMessage) {
+	// check whether triggered by slash command
+	userCmd := userMessage.Command
+	if userCmd == "" {
+		// understand the plain language by llm
+		handleNaturalLanguage(userMessage)
+		return
+	}
+	command := FindCommand(userCmd)
+	if command == nil {
+		errMsg := fmt.Sprintf("不支持的命令 /%s，发送 /%s 查看所有可用的命令", userCmd, HelpCmd)
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+		return
+	}
+	command.Handler(context.Background(), userMessage)
+}
+
+// handleGrafanaCommand handles grafana dashboard matching
+func handleGrafanaCommand(ctx context.Context, userMessage *message.UserMessage) {
+	suggestedDashboards, err := handleGrafanaCopilot(ctx, userMessage)
+	if err != nil || len(suggestedDashboards) == 0 {
+		var errMsg string
+		if err != nil {
+			errMsg = fmt.Sprintf("Handle grafana copilot err: %s", err.Error())
+		} else {
+			errMsg = "没有找到匹配的仪表盘，请尝试其他问题"
+		}
+		slog.Error(errMsg)
+		NotifyUserError(userMessage, errMsg)
+	} else {
+		NotifyUserResult(userMessage, suggestedDashboards)
+	}
+}
+
+func handleGrafanaCopilot(ctx context.Context, userMessage *message.UserMessage) (suggestedDashboards []grafana.Dashboard, err error) {
+	return MatchDashboards(ctx, userMessage.Input)
+}
+
+// MatchDashboards lists the grafana dashboards and asks the llm to pick the ones matching the user input.
+// The time range and the variable values in the user input are added to the dashboard URLs.
+// It is shared by the chat adapters and the query api.
+func MatchDashboards(ctx context.Context, userInput string) (suggestedDashboards []grafana.Dashboard, err error) {
+	return matchDashboards(ctx, userInput, true)
+}
+
+func matchDashboards(ctx context.Context, userInput string, withLinkParams bool) (suggestedDashboards []grafana.Dashboard, err error) {
+	if userInput == "" {
+		// notify error
+		err = fmt.Errorf("no user input")
+		return
+	}
+	// list the grafana dashboard metas
+	dashboardMetas, err := grafana.ListCatalogDashboards()
+	if err != nil {
+		// notify error
+		err = fmt.Errorf("list grafana dashboard metas err: %v", err)
+		return
+	}
+	// only search the instances mentioned in the user input, e.g. "in staging"
+	dashboardMetas = filterDashboardsBySource(userInput, dashboardMetas)
+	if conf.AppConfig.IsLexicalMode() {
+		suggestedDashboards = MatchDashboardsLexically(userInput, dashboardMetas)
+		return
+	}
+	// only send the retrieved candidates to the llm for reranking if the index is ready
+	candidateMetas := dashboardMetas
+	if retrieval.IsEnabled() && retrieval.GetIndex().Size() > 0 {
+		candidateMetas = filterRetrievedDashboards(ctx, userInput, dashboardMetas)
+	}
+	dashboardMetaMap := make(map[string]grafana.Dashboard)
+	// covert dashboard metas to markdown table
+	markdownBuf := bytes.NewBuffer(nil)
+	// the folder and tags help to tell the dashboards with generic titles apart, e.g. Overview
+	markdownBuf.WriteString("|Uid|Title|Folder|Tags|\n")
+	markdownBuf.WriteString("|---|---|---|---|\n")
+	for _, dashboardMeta := range candidateMetas {
+		markdownBuf.WriteString("|")
+		markdownBuf.WriteString(dashboardMeta.Key())
+		markdownBuf.WriteString("|")
+		markdownBuf.WriteString(escapeMarkdownCell(dashboardMeta.Title))
+		markdownBuf.WriteString("|")
+		markdownBuf.WriteString(escapeMarkdownCell(dashboardMeta.FolderTitle))
+		markdownBuf.WriteString("|")
+		markdownBuf.WriteString(escapeMarkdownCell(strings.Join(dashboardMeta.Tags, ",")))
+		markdownBuf.WriteString("|")
+		markdownBuf.WriteString("\n")
+		dashboardMetaMap[dashboardMeta.Key()] = dashboardMeta
+	}
+	// prepare render context
+	renderCtx := GrafanaCopilotContext{
+		GrafanaDashboards: markdownBuf.String(),
+		UserInput:         userInput,
+	}
+	// prepare llm input
+	systemMessage, err := RenderTemplate(GetPromptPath("grafana_copilot_prompt.md"), renderCtx)
+	if err != nil {
+		err = fmt.Errorf("render template err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm input:\n %s", systemMessage))
+	// call openai to get resp
+	llmOutput, err := GetLLMResponse(ctx, systemMessage, userInput)
+	if err != nil {
+		// the user still gets some help when the llm fails or times out
+		slog.Error(fmt.Sprintf("%v, fallback to keyword matching", err))
+		suggestedDashboards = MatchDashboardsLexically(userInput, dashboardMetas)
+		for index := range suggestedDashboards {
+			suggestedDashboards[index].Reason = fmt.Sprintf("大模型暂不可用，%s", suggestedDashboards[index].Reason)
+		}
+		err = nil
+		return
+	}
+	// return msg
+	textOutput := ernie.GetResponseTextContent(llmOutput)
+	textLines := strings.Split(textOutput, "\n")
+	suggestedDashboards = make([]grafana.Dashboard, 0, 2)
+	for _, line := range textLines {
+		slog.Debug(fmt.Sprintf("get llm text line, %s", line))
+		// the reason is optional
+		items := strings.SplitN(line, "=", 3)
+		if len(items) < 2 {
+			continue
+		}
+		dashboardKey := strings.TrimSpace(items[0])
+		title := strings.TrimSpace(items[1])
+		var reason string
+		if len(items) == 3 {
+			reason = strings.TrimSpace(items[2])
+		}
+		if dashboard, ok := dashboardMetaMap[dashboardKey]; ok {
+			suggestedDashboards = append(suggestedDashboards, createDashboardSuggestion(dashboard, title, reason))
+		}
+	}
+	if withLinkParams {
+		applyLinkParams(ctx, userInput, suggestedDashboards, fetchDashboardDetails(suggestedDashboards))
+	}
+	return
+}
+
+// createDashboardSuggestion creates the suggested dashboard with the full URL of the instance it belongs to
+func createDashboardSuggestion(dashboard grafana.Dashboard, title, reason string) grafana.Dashboard {
+	return grafana.Dashboard{
+		Uid:         dashboard.Uid,
+		Title:       title,
+		URL:         grafana.CreateDashboardURL(dashboard.Source, dashboard.OrgId, dashboard.URL),
+		Type:        dashboard.Type,
+		Tags:        dashboard.Tags,
+		FolderUid:   dashboard.FolderUid,
+		FolderTitle: dashboard.FolderTitle,
+		Source:      dashboard.Source,
+		OrgId:       dashboard.OrgId,
+		Reason:      reason,
+	}
+}
+
+// filterDashboardsBySource keeps the dashboards of the instances mentioned in the user input,
+// all the dashboards are returned if no instance is mentioned.
+func filterDashboardsBySource(userInput string, dashboardMetas []grafana.Dashboard) []grafana.Dashboard {
+	sources := grafana.MatchInstances(userInput)
+	if len(sources) == 0 {
+		return dashboardMetas
+	}
+	filteredMetas := make([]grafana.Dashboard, 0, len(dashboardMetas))
+	for _, dashboardMeta := range dashboardMetas {
+		if slices.Contains(sources, dashboardMeta.Source) {
+			filteredMetas = append(filteredMetas, dashboardMeta)
+		}
+	}
+	slog.Debug(fmt.Sprintf("search the dashboards of grafana %s", strings.Join(sources, ",")))
+	return filteredMetas
+}
+
+// filterRetrievedDashboards keeps the dashboards retrieved from the embedding index in rank order,
+// all the dashboards are returned if the retrieval fails or finds nothing.
+func filterRetrievedDashboards(ctx context.Context, userInput string, dashboardMetas []grafana.Dashboard) []grafana.Dashboard {
+	dashboardKeys, err := retrieval.RetrieveDashboardKeys(ctx, userInput, conf.AppConfig.CopilotRetrievalTopK)
+	if err != nil {
+		slog.Error(fmt.Sprintf("retrieve dashboards err: %v, fallback to all dashboards", err))
+		return dashboardMetas
+	}
+	dashboardMetaMap := make(map[string]grafana.Dashboard, len(dashboardMetas))
+	for _, dashboardMeta := range dashboardMetas {
+		dashboardMetaMap[dashboardMeta.Key()] = dashboardMeta
+	}
+	candidates := make([]grafana.Dashboard, 0, len(dashboardKeys))
+	for _, dashboardKey := range dashboardKeys {
+		// the index may be stale or the dashboards may be filtered by source, skip the missing ones
+		if dashboardMeta, ok := dashboardMetaMap[dashboardKey]; ok {
+			candidates = append(candidates, dashboardMeta)
+		}
+	}
+	if len(candidates) == 0 {
+		return dashboardMetas
+	}
+	slog.Debug(fmt.Sprintf("retrieved %d candidate dashboards from %d", len(candidates), len(dashboardMetas)))
+	return candidates
+}
+
+// GetLLMResponse calls the llm with the system message and the user input
+func GetLLMResponse(ctx context.Context, systemMessage, userInput string) (llmOutput string, err error) {
+	if conf.AppConfig.IsLexicalMode() {
+		err = fmt.Errorf("llm is disabled in %s search mode", conf.SearchModeLexical)
+		return
+	}
+	if conf.AppConfig.CopilotLLMTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conf.AppConfig.CopilotLLMTimeout)*time.Second)
+		defer cancel()
+	}
+	llmOutput, err = ernie.GetErnieResponse(ctx, conf.AppConfig, []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeSystem,
+			Parts: []llms.ContentPart{
+				llms.TextContent{Text: systemMessage},
+			}},
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.TextContent{Text: userInput},
+			}},
+	})
+	if err != nil {
+		err = fmt.Errorf("get llm response err: %w", err)
+		return
+	}
+	slog.Debug(fmt.Sprintf("llm output:\n %s", llmOutput))
+	return
+}
+
+// GetPromptPath returns the path of the prompt template in the prompts directory
+func GetPromptPath(promptName string) string {
+	return filepath.Join(conf.AppConfig.CopilotPromptsDir, promptName)
+}
+
+func RenderTemplate(promptPath string, renderCtx any) (msg string, err error) {
+	grafanaPromptTemplate, err := os.ReadFile(promptPath)
+	if err != nil {
+		err = fmt.Errorf("read %s err: %w", promptPath, err)
+		return
+	}
+	tpl, err := template.New(promptPath).Parse(string(grafanaPromptTemplate))
+	if err != nil {
+		err = fmt.Errorf("parse %s err: %w", promptPath, err)
+		return
+	}
+	llmInputBuf := bytes.NewBuffer(nil)
+	err = tpl.Execute(llmInputBuf, &renderCtx)
+	if err != nil {
+		err = fmt.Errorf("parse %s err: %w", promptPath, err)
+		return
+	}
+	msg = llmInputBuf.String()
+	return
+}
+
+func NotifyUserText(userMessage *message.UserMessage, outputMsg string) {
+	// send the reply
+	err := userMessage.Replier.ReplyText(outputMsg)
+	if err != nil {
+		slog.Error(fmt.Sprintf("send message to %s error: %v", userMessage.Platform, err))
+	}
+}
+
+// NotifyUserError sends the error message as plain text, it is kept apart from NotifyUserText
+// so that the platforms can render errors differently later.
+func NotifyUserError(userMessage *message.UserMessage, outputMsg string) {
+	NotifyUserText(userMessage, outputMsg)
+}
+
+func NotifyUserResult(userMessage *message.UserMessage, suggestedDashboards []grafana.Dashboard) {
+	// label the dashboards with their grafana instances, e.g. [staging] Kafka Overview
+	labeledDashboards := make([]grafana.Dashboard, 0, len(suggestedDashboards))
+	for _, dashboard := range suggestedDashboards {
+		if dashboard.Source != "" {
+			dashboard.Title = fmt.Sprintf("[%s] %s", dashboard.Source, dashboard.Title)
+		}
+		labeledDashboards = append(labeledDashboards, dashboard)
+	}
+	// send the reply
+	err := userMessage.Replier.ReplyDashboards(labeledDashboards)
+	if err != nil {
+		slog.Error(fmt.Sprintf("send message to %s error: %v", userMessage.Platform, err))
+	}
+}
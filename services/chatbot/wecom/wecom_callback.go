@@ -0,0 +1,79 @@
+package wecom
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"sort"
+	"strings"
+)
+
+// pkcs7BlockSize is the padding block size used by wecom, which is not the aes block size
+const pkcs7BlockSize = 32
+
+// CalcWecomSignature 计算企业微信回调请求的 msg_signature
+// msg_signature = sha1(sort(token, timestamp, nonce, encrypt))
+func CalcWecomSignature(token, timestamp, nonce, encrypt string) string {
+	items := []string{token, timestamp, nonce, encrypt}
+	sort.Strings(items)
+	sum := sha1.Sum([]byte(strings.Join(items, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// DecryptWecomMessage decrypts the echostr or the Encrypt field of the callback.
+// The plain text is composed of random(16B) + msg_len(4B) + msg + receiveid.
+// The receiveid is not checked if it is empty.
+// See https://developer.work.weixin.qq.com/document/path/91144
+func DecryptWecomMessage(encrypt string, encodingAESKey string, receiveId string) ([]byte, error) {
+	aesKey, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil {
+		return nil, fmt.Errorf("invalid aes key, %s", err.Error())
+	}
+	encryptedBytes, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64, %s", err.Error())
+	}
+	if len(encryptedBytes) == 0 || len(encryptedBytes)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted data length %d", len(encryptedBytes))
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher error, %s", err.Error())
+	}
+	plainBytes := make([]byte, len(encryptedBytes))
+	cipher.NewCBCDecrypter(block, aesKey[:aes.BlockSize]).CryptBlocks(plainBytes, encryptedBytes)
+	// remove the pkcs7 padding
+	padding := int(plainBytes[len(plainBytes)-1])
+	if padding == 0 || padding > pkcs7BlockSize || padding > len(plainBytes) {
+		return nil, fmt.Errorf("invalid padding %d", padding)
+	}
+	plainBytes = plainBytes[:len(plainBytes)-padding]
+	if len(plainBytes) < 20 {
+		return nil, fmt.Errorf("invalid plain text length %d", len(plainBytes))
+	}
+	// compare in uint64 so that a garbled length can not overflow the slice bounds
+	msgLen := uint64(binary.BigEndian.Uint32(plainBytes[16:20]))
+	if msgLen > uint64(len(plainBytes)-20) {
+		return nil, fmt.Errorf("invalid message length %d", msgLen)
+	}
+	msg := plainBytes[20 : 20+msgLen]
+	if receiveId != "" && string(plainBytes[20+msgLen:]) != receiveId {
+		return nil, fmt.Errorf("receive id not match")
+	}
+	return msg, nil
+}
+
+// GetUserInput strips the leading @ mentions of the robot and returns the command and user input,
+// e.g. "@RobotA /Grafana kafka lag" returns "Grafana" and "kafka lag"
+func (t CallbackText) GetUserInput() (command, input string) {
+	fields := strings.Fields(t.Content)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		fields = fields[1:]
+	}
+	return message.ParseCommand(strings.Join(fields, " "))
+}
@@ -0,0 +1,87 @@
+package wecom
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// testEncodingAESKey is the base64 of "0123456789abcdef0123456789abcdef" without the trailing "="
+const testEncodingAESKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+
+// encryptWecomPlainBytes encrypts the plain bytes as is, the caller is responsible for the padding
+func encryptWecomPlainBytes(t *testing.T, plainBytes []byte) string {
+	aesKey, _ := base64.StdEncoding.DecodeString(testEncodingAESKey + "=")
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedBytes := make([]byte, len(plainBytes))
+	cipher.NewCBCEncrypter(block, aesKey[:aes.BlockSize]).CryptBlocks(encryptedBytes, plainBytes)
+	return base64.StdEncoding.EncodeToString(encryptedBytes)
+}
+
+// newWecomPlainBytes composes random(16B) + msg_len(4B) + msg + receiveid and pads it to the given size
+func newWecomPlainBytes(msgLen uint32, msg, receiveId string, size int, padding byte) []byte {
+	plainBytes := append(bytes.Repeat([]byte("a"), 16), binary.BigEndian.AppendUint32(nil, msgLen)...)
+	plainBytes = append(plainBytes, msg...)
+	plainBytes = append(plainBytes, receiveId...)
+	for len(plainBytes) < size {
+		plainBytes = append(plainBytes, padding)
+	}
+	return plainBytes
+}
+
+func TestCalcWecomSignature(t *testing.T) {
+	signature := CalcWecomSignature("QDG6eK", "1409659813", "1372623149", "RypEvHKD8QQKFhvQ6QleEB4J58tiPdvo")
+	if want := "65664ba4ebf14da066e746a9e3aa9aac1f19e4ef"; signature != want {
+		t.Errorf("signature = %s, want %s", signature, want)
+	}
+}
+
+func TestDecryptWecomMessage(t *testing.T) {
+	// encrypted by openssl with aes-256-cbc, the plain text is "hello wecom" with the receive id "wwcorp"
+	const knownEncrypt = "x6vyfrfXtFf8dewTSPkTUSnkJIDDS1q3a/k1LPELV/Rzfr5x3DfnTauhUOgN9Cw/PewVrCvKAbhMyRhdtDue2w=="
+	testCases := []struct {
+		name      string
+		encrypt   string
+		receiveId string
+		want      string
+		wantErr   bool
+	}{
+		{name: "known vector", encrypt: knownEncrypt, receiveId: "wwcorp", want: "hello wecom"},
+		{name: "receive id not checked", encrypt: knownEncrypt, want: "hello wecom"},
+		{name: "receive id mismatch", encrypt: knownEncrypt, receiveId: "wwother", wantErr: true},
+		{name: "round trip", encrypt: encryptWecomPlainBytes(t, newWecomPlainBytes(5, "/help", "wwcorp", 32, 1)),
+			receiveId: "wwcorp", want: "/help"},
+		{name: "zero padding", encrypt: encryptWecomPlainBytes(t, newWecomPlainBytes(5, "/help", "wwcorp", 32, 0)),
+			wantErr: true},
+		{name: "padding larger than block", encrypt: encryptWecomPlainBytes(t, newWecomPlainBytes(5, "/help", "wwcorp", 64, 33)),
+			wantErr: true},
+		{name: "short plain text", encrypt: encryptWecomPlainBytes(t, bytes.Repeat([]byte{16}, 16)), wantErr: true},
+		{name: "garbled message length", encrypt: encryptWecomPlainBytes(t, newWecomPlainBytes(0xffffffff, "/help", "", 32, 7)),
+			wantErr: true},
+		{name: "invalid base64", encrypt: "not base64!", wantErr: true},
+		{name: "invalid length", encrypt: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			msg, err := DecryptWecomMessage(testCase.encrypt, testEncodingAESKey, testCase.receiveId)
+			if testCase.wantErr {
+				if err == nil {
+					t.Errorf("want error, got %q", msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decrypt err: %v", err)
+			}
+			if string(msg) != testCase.want {
+				t.Errorf("msg = %q, want %q", msg, testCase.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package wecom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTimeout = time.Second * 10 // 10 seconds
+)
+
+type Config struct {
+	Timeout int `json:"timeout"`
+}
+
+// Client is a robot client to send messages to the webhook url of the group
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return NewClientWithHttpClient(cfg, &http.Client{Timeout: timeout})
+}
+
+func NewClientWithHttpClient(cfg *Config, httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+	}
+}
+
+func (c *Client) SendMessage(webhookUrl string, message *Message) (err error) {
+	reqBody, mErr := json.Marshal(message)
+	if mErr != nil {
+		err = fmt.Errorf("marshal request body error, %s", mErr.Error())
+		return
+	}
+	req, newErr := http.NewRequest(http.MethodPost, webhookUrl, bytes.NewReader(reqBody))
+	if newErr != nil {
+		err = fmt.Errorf("create request error, %s", newErr.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// fire request
+	resp, callErr := c.httpClient.Do(req)
+	if callErr != nil {
+		err = fmt.Errorf("get response error, %s", callErr.Error())
+		return
+	}
+	defer resp.Body.Close()
+	// check status code
+	if resp.StatusCode != http.StatusOK {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("get response error, %s", resp.Status)
+		return
+	}
+	var responseBody ResponseBody
+	decoder := json.NewDecoder(resp.Body)
+	if decErr := decoder.Decode(&responseBody); decErr != nil {
+		err = fmt.Errorf("parse response error, %s", decErr.Error())
+		return
+	}
+	// check logic code
+	if responseBody.ErrorCode != 0 {
+		err = fmt.Errorf("call api error, %d: %s", responseBody.ErrorCode, responseBody.ErrorMessage)
+		return
+	}
+	return
+}
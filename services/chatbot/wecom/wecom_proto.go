@@ -0,0 +1,56 @@
+package wecom
+
+import "encoding/xml"
+
+const (
+	MessageTypeText     = "text"
+	MessageTypeMarkdown = "markdown"
+)
+
+// EncryptedBody is the xml body posted by the wecom robot callback
+type EncryptedBody struct {
+	XMLName    xml.Name `xml:"xml"`
+	ToUserName string   `xml:"ToUserName"`
+	Encrypt    string   `xml:"Encrypt"`
+	AgentId    string   `xml:"AgentID"`
+}
+
+// CallbackBody is the decrypted message of the group robot when it is @ in the group.
+// See https://developer.work.weixin.qq.com/document/path/90930
+type CallbackBody struct {
+	XMLName    xml.Name     `xml:"xml"`
+	WebhookUrl string       `xml:"WebhookUrl"`
+	ChatId     string       `xml:"ChatId"`
+	PostId     string       `xml:"PostId"`
+	ChatType   string       `xml:"ChatType"`
+	From       CallbackFrom `xml:"From"`
+	MsgType    string       `xml:"MsgType"`
+	Text       CallbackText `xml:"Text"`
+	MsgId      string       `xml:"MsgId"`
+}
+
+type CallbackFrom struct {
+	UserId string `xml:"UserId"`
+	Name   string `xml:"Name"`
+	Alias  string `xml:"Alias"`
+}
+
+type CallbackText struct {
+	Content string `xml:"Content"`
+}
+
+// Message is the message sent to the robot webhook.
+// See https://developer.work.weixin.qq.com/document/path/91770
+type Message struct {
+	MsgType  string           `json:"msgtype"`
+	Markdown *MarkdownContent `json:"markdown,omitempty"`
+}
+
+type MarkdownContent struct {
+	Content string `json:"content"`
+}
+
+type ResponseBody struct {
+	ErrorCode    int    `json:"errcode"`
+	ErrorMessage string `json:"errmsg"`
+}
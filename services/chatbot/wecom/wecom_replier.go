@@ -0,0 +1,61 @@
+package wecom
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/services/chatbot/message"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+)
+
+const PlatformName = "wecom"
+
+// Replier sends the copilot replies to the group webhook as markdown and @ the user who asks
+type Replier struct {
+	client     *Client
+	webhookUrl string
+	userId     string
+}
+
+func NewReplier(client *Client, webhookUrl, userId string) *Replier {
+	return &Replier{
+		client:     client,
+		webhookUrl: webhookUrl,
+		userId:     userId,
+	}
+}
+
+// NewUserMessage converts the wecom callback body to the platform neutral user message
+func NewUserMessage(callbackBody *CallbackBody, client *Client) *message.UserMessage {
+	command, input := callbackBody.Text.GetUserInput()
+	return &message.UserMessage{
+		Platform:       PlatformName,
+		Command:        command,
+		Input:          input,
+		FromUserId:     callbackBody.From.UserId,
+		ConversationId: callbackBody.ChatId,
+		Replier:        NewReplier(client, callbackBody.WebhookUrl, callbackBody.From.UserId),
+	}
+}
+
+func (r *Replier) ReplyText(text string) error {
+	return r.sendMarkdown(text)
+}
+
+func (r *Replier) ReplyDashboards(dashboards []grafana.Dashboard) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("为您找到如下看板:\n")
+	for _, dashboard := range dashboards {
+		buf.WriteString(fmt.Sprintf("> [%s](%s)\n", dashboard.Title, dashboard.URL))
+	}
+	return r.sendMarkdown(buf.String())
+}
+
+// sendMarkdown sends the markdown message, the user is @ by the `<@userid>` syntax
+func (r *Replier) sendMarkdown(content string) error {
+	return r.client.SendMessage(r.webhookUrl, &Message{
+		MsgType: MessageTypeMarkdown,
+		Markdown: &MarkdownContent{
+			Content: fmt.Sprintf("<@%s> %s", r.userId, content),
+		},
+	})
+}
@@ -26,3 +26,27 @@ func GetErnieResponse(ctx context.Context, appConfig *conf.Config, messages []ll
 	llmOutput = llmResp.Choices[0].Content
 	return
 }
+
+// GetEmbeddings creates the embeddings of the texts with the embedding model behind the same openai compatible api
+func GetEmbeddings(ctx context.Context, appConfig *conf.Config, texts []string) (embeddings [][]float32, err error) {
+	var client *openai.LLM
+	client, err = openai.New(openai.WithBaseURL(appConfig.OpenAIAPIBase),
+		openai.WithModel(appConfig.OpenAIModel),
+		openai.WithEmbeddingModel(appConfig.OpenAIEmbeddingModel),
+		openai.WithToken(appConfig.OpenAIAPIKey),
+	)
+	if err != nil {
+		err = fmt.Errorf("create openai client err: %v", err)
+		return
+	}
+	embeddings, err = client.CreateEmbedding(ctx, texts)
+	if err != nil {
+		err = fmt.Errorf("call openai embedding err: %v", err)
+		return
+	}
+	if len(embeddings) != len(texts) {
+		err = fmt.Errorf("embedding count %d not match text count %d", len(embeddings), len(texts))
+		return
+	}
+	return
+}
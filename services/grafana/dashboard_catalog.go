@@ -0,0 +1,180 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DashboardCatalog caches the dashboard metas in memory, so that the dashboard matching does not
+// call the grafana search api for every user message. The catalog version is increased whenever
+// any dashboard is added, removed or changed.
+type DashboardCatalog struct {
+	lock         sync.RWMutex
+	refreshLock  sync.Mutex
+	dashboards   []Dashboard
+	fingerprints map[string]string
+	version      int64
+	refreshedAt  time.Time
+	lastError    string
+	lastChanges  CatalogChanges
+}
+
+// CatalogChanges are the keys of the dashboards changed by a refresh, the moved and retagged dashboards
+// are counted as renamed.
+type CatalogChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Renamed []string `json:"renamed"`
+}
+
+type CatalogStatus struct {
+	Size        int            `json:"size"`
+	Version     int64          `json:"version"`
+	RefreshedAt time.Time      `json:"refreshedAt"`
+	AgeSeconds  int64          `json:"ageSeconds"`
+	LastError   string         `json:"lastError,omitempty"`
+	LastChanges CatalogChanges `json:"lastChanges"`
+}
+
+var defaultCatalog = &DashboardCatalog{}
+var catalogEnabled bool
+
+// GetCatalog returns the catalog shared by the whole process
+func GetCatalog() *DashboardCatalog {
+	return defaultCatalog
+}
+
+// StartCatalogRefresher refreshes the catalog in background immediately and then on every interval
+func StartCatalogRefresher(ctx context.Context, interval time.Duration) {
+	catalogEnabled = true
+	go func() {
+		for {
+			if _, err := defaultCatalog.Refresh(); err != nil {
+				slog.Error(fmt.Sprintf("refresh dashboard catalog err: %v", err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// ListCatalogDashboards lists the dashboard metas from the catalog if it is enabled and loaded,
+// otherwise it calls the grafana search api directly, e.g. in the command line client.
+func ListCatalogDashboards() (dashboardList []Dashboard, err error) {
+	if catalogEnabled {
+		if dashboardList, ok := defaultCatalog.Dashboards(); ok {
+			return dashboardList, nil
+		}
+	}
+	return ListDashboardMeta("")
+}
+
+// Refresh lists all the dashboard metas of all the instances and replaces the cached ones, the concurrent
+// refreshes are serialized. The cached dashboards of the failed instances are kept.
+func (c *DashboardCatalog) Refresh() (changes CatalogChanges, err error) {
+	return c.refresh(GetClients())
+}
+
+func (c *DashboardCatalog) refresh(clients []*Client) (changes CatalogChanges, err error) {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	c.lock.RLock()
+	oldDashboards := c.dashboards
+	c.lock.RUnlock()
+	var dashboardList []Dashboard
+	var errs []string
+	for _, client := range clients {
+		result, searchErr := client.SearchAllDashboards("")
+		if searchErr != nil {
+			errs = append(errs, searchErr.Error())
+			for _, dashboard := range oldDashboards {
+				if dashboard.Source == client.Name {
+					dashboardList = append(dashboardList, dashboard)
+				}
+			}
+			continue
+		}
+		slog.Debug(fmt.Sprintf("listed %d dashboards of grafana %s in %d pages", result.Total, client.Name, result.Pages))
+		dashboardList = append(dashboardList, result.Dashboards...)
+	}
+	if len(errs) == len(clients) {
+		err = fmt.Errorf("list dashboards err: %s", strings.Join(errs, "; "))
+		c.lock.Lock()
+		c.lastError = err.Error()
+		c.lock.Unlock()
+		return
+	}
+	fingerprints := make(map[string]string, len(dashboardList))
+	for _, dashboard := range dashboardList {
+		fingerprints[dashboard.Key()] = createFingerprint(dashboard)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for uid, fingerprint := range fingerprints {
+		oldFingerprint, ok := c.fingerprints[uid]
+		if !ok {
+			changes.Added = append(changes.Added, uid)
+		} else if oldFingerprint != fingerprint {
+			changes.Renamed = append(changes.Renamed, uid)
+		}
+	}
+	for uid := range c.fingerprints {
+		if _, ok := fingerprints[uid]; !ok {
+			changes.Removed = append(changes.Removed, uid)
+		}
+	}
+	if len(changes.Added) > 0 || len(changes.Removed) > 0 || len(changes.Renamed) > 0 {
+		c.version++
+		slices.Sort(changes.Added)
+		slices.Sort(changes.Removed)
+		slices.Sort(changes.Renamed)
+		slog.Info(fmt.Sprintf("dashboard catalog changed, version: %d, added: %d, removed: %d, renamed: %d",
+			c.version, len(changes.Added), len(changes.Removed), len(changes.Renamed)))
+	}
+	c.dashboards = dashboardList
+	c.fingerprints = fingerprints
+	c.refreshedAt = time.Now()
+	c.lastError = strings.Join(errs, "; ")
+	c.lastChanges = changes
+	return
+}
+
+// Dashboards returns the cached dashboard metas, it returns false if the catalog is never loaded
+func (c *DashboardCatalog) Dashboards() (dashboardList []Dashboard, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.refreshedAt.IsZero() {
+		return
+	}
+	// the callers may modify the returned list
+	return slices.Clone(c.dashboards), true
+}
+
+func (c *DashboardCatalog) Status() (status CatalogStatus) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	status = CatalogStatus{
+		Size:        len(c.dashboards),
+		Version:     c.version,
+		RefreshedAt: c.refreshedAt,
+		LastError:   c.lastError,
+		LastChanges: c.lastChanges,
+	}
+	if !c.refreshedAt.IsZero() {
+		status.AgeSeconds = int64(time.Since(c.refreshedAt).Seconds())
+	}
+	return
+}
+
+func createFingerprint(dashboard Dashboard) string {
+	return strings.Join([]string{dashboard.Title, dashboard.URL, dashboard.FolderUid, dashboard.FolderTitle,
+		strings.Join(dashboard.Tags, ",")}, "\x00")
+}
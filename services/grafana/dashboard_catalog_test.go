@@ -0,0 +1,107 @@
+package grafana
+
+import (
+	"encoding/json"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeSearchServer serves the dashboards of the first search page, all the requests fail if the dashboards are nil
+type fakeSearchServer struct {
+	server     *httptest.Server
+	dashboards []Dashboard
+}
+
+func newFakeSearchServer(t *testing.T, dashboards []Dashboard) *fakeSearchServer {
+	fake := &fakeSearchServer{dashboards: dashboards}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fake.dashboards == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message": "database is locked"}`))
+			return
+		}
+		dashboardList := make([]Dashboard, 0)
+		if r.URL.Query().Get("page") == "1" {
+			dashboardList = fake.dashboards
+		}
+		_ = json.NewEncoder(w).Encode(dashboardList)
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func TestDashboardCatalogRefresh(t *testing.T) {
+	prod := newFakeSearchServer(t, []Dashboard{
+		{Uid: "order", Title: "Order", URL: "/d/order/order"},
+		{Uid: "pay", Title: "Pay", URL: "/d/pay/pay"},
+		{Uid: "user", Title: "User", URL: "/d/user/user"},
+	})
+	staging := newFakeSearchServer(t, []Dashboard{{Uid: "order", Title: "Order", URL: "/d/order/order"}})
+	clients := []*Client{
+		NewClientWithHttpClient(conf.GrafanaInstance{Name: "prod", Host: prod.server.URL}, prod.server.Client()),
+		NewClientWithHttpClient(conf.GrafanaInstance{Name: "staging", Host: staging.server.URL}, staging.server.Client()),
+	}
+	catalog := &DashboardCatalog{}
+	if _, ok := catalog.Dashboards(); ok {
+		t.Fatalf("catalog should not be loaded before the first refresh")
+	}
+	changes, err := catalog.refresh(clients)
+	if err != nil {
+		t.Fatalf("refresh err: %v", err)
+	}
+	wantChanges := CatalogChanges{Added: []string{"prod/order", "prod/pay", "prod/user", "staging/order"}}
+	if !reflect.DeepEqual(changes, wantChanges) || catalog.Status().Version != 1 {
+		t.Fatalf("changes = %+v, version = %d, want %+v and 1", changes, catalog.Status().Version, wantChanges)
+	}
+
+	// pay is removed, user is renamed, cart is added, and the staging instance fails
+	prod.dashboards = []Dashboard{
+		{Uid: "order", Title: "Order", URL: "/d/order/order"},
+		{Uid: "user", Title: "Users", URL: "/d/user/users"},
+		{Uid: "cart", Title: "Cart", URL: "/d/cart/cart"},
+	}
+	staging.dashboards = nil
+	changes, err = catalog.refresh(clients)
+	if err != nil {
+		t.Fatalf("refresh err: %v", err)
+	}
+	wantChanges = CatalogChanges{Added: []string{"prod/cart"}, Removed: []string{"prod/pay"}, Renamed: []string{"prod/user"}}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("changes = %+v, want %+v", changes, wantChanges)
+	}
+	status := catalog.Status()
+	if status.Version != 2 || status.Size != 4 || status.LastError == "" || !reflect.DeepEqual(status.LastChanges, wantChanges) {
+		t.Errorf("status = %+v, want version 2, size 4 and the error of staging", status)
+	}
+	dashboardList, _ := catalog.Dashboards()
+	var stagingKept bool
+	for _, dashboard := range dashboardList {
+		if dashboard.Key() == "staging/order" {
+			stagingKept = true
+		}
+	}
+	if !stagingKept {
+		t.Errorf("dashboards = %+v, want the cached dashboards of the failed instance kept", dashboardList)
+	}
+
+	// nothing changed
+	staging.dashboards = []Dashboard{{Uid: "order", Title: "Order", URL: "/d/order/order"}}
+	if changes, err = catalog.refresh(clients); err != nil || !reflect.DeepEqual(changes, CatalogChanges{}) {
+		t.Errorf("changes = %+v, err = %v, want no changes", changes, err)
+	}
+	if status = catalog.Status(); status.Version != 2 || status.LastError != "" {
+		t.Errorf("status = %+v, want version 2 without error", status)
+	}
+
+	// all the instances fail, the cached dashboards are kept
+	prod.dashboards, staging.dashboards = nil, nil
+	if _, err = catalog.refresh(clients); err == nil {
+		t.Errorf("want error when all the instances fail")
+	}
+	if status = catalog.Status(); status.Version != 2 || status.Size != 4 || status.LastError == "" {
+		t.Errorf("status = %+v, want the cached dashboards with the error", status)
+	}
+}
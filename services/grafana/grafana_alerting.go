@@ -0,0 +1,191 @@
+package grafana
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AlertStateFiring   = "firing"
+	AlertStatePending  = "pending"
+	AlertStateSilenced = "silenced"
+)
+
+const (
+	// AlertRuleUidLabel is the internal label added by grafana to the alerts sent to the alertmanager
+	AlertRuleUidLabel = "__alert_rule_uid__"
+	AlertNameLabel    = "alertname"
+)
+
+// Alert is an alert instance of the grafana managed alert rules
+type Alert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	// State is firing, pending or silenced
+	State string
+	// ActiveAt is the time when the alert becomes pending or firing
+	ActiveAt time.Time
+	// RuleURL opens the alert rule of the alert
+	RuleURL string
+	Source  string
+	OrgId   int
+}
+
+// GetName returns the alert rule name of the alert
+func (a *Alert) GetName() string {
+	return a.Labels[AlertNameLabel]
+}
+
+// GetSummary returns the summary annotation, or the description if the summary is not set
+func (a *Alert) GetSummary() string {
+	if summary := a.Annotations["summary"]; summary != "" {
+		return summary
+	}
+	return a.Annotations["description"]
+}
+
+// GetLabels returns the labels except the internal ones which start with double underscores
+func (a *Alert) GetLabels() map[string]string {
+	labels := make(map[string]string, len(a.Labels))
+	for name, value := range a.Labels {
+		if !strings.HasPrefix(name, "__") {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// Fingerprint identifies the alert by the sorted labels except the internal ones
+func (a *Alert) Fingerprint() string {
+	labels := a.GetLabels()
+	items := make([]string, 0, len(labels))
+	for name, value := range labels {
+		items = append(items, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Status       struct {
+		// State is unprocessed, active or suppressed
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+type prometheusAlertsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Alerts []prometheusAlert `json:"alerts"`
+	} `json:"data"`
+}
+
+type prometheusAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	// State is Alerting or Pending, the Alerting state may have the reason suffix, e.g. Alerting (NoData)
+	State    string    `json:"state"`
+	ActiveAt time.Time `json:"activeAt"`
+}
+
+// ListAlerts lists the firing and silenced alerts from the alertmanager api and the pending alerts
+// from the prometheus compatible api of the org, the alerts in both apis are deduplicated by labels.
+func (c *Client) ListAlerts(orgId int) (alerts []Alert, err error) {
+	alertmanagerAlerts, err := c.GetAlertmanagerAlerts(orgId)
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, amAlert := range alertmanagerAlerts {
+		state := AlertStateFiring
+		if amAlert.Status.State == "suppressed" {
+			state = AlertStateSilenced
+		}
+		alert := Alert{
+			Labels:      amAlert.Labels,
+			Annotations: amAlert.Annotations,
+			State:       state,
+			ActiveAt:    amAlert.StartsAt,
+			RuleURL:     amAlert.GeneratorURL,
+			Source:      c.Name,
+			OrgId:       orgId,
+		}
+		if ruleUid := amAlert.Labels[AlertRuleUidLabel]; ruleUid != "" {
+			alert.RuleURL = c.CreateURL(fmt.Sprintf("/alerting/grafana/%s/view", url.PathEscape(ruleUid)), orgId)
+		}
+		seen[alert.Fingerprint()] = true
+		alerts = append(alerts, alert)
+	}
+	prometheusAlerts, err := c.GetPrometheusAlerts(orgId)
+	if err != nil {
+		return
+	}
+	for _, promAlert := range prometheusAlerts {
+		state := strings.ToLower(promAlert.State)
+		switch {
+		case strings.HasPrefix(state, "pending"):
+			state = AlertStatePending
+		case strings.HasPrefix(state, "alerting"), strings.HasPrefix(state, "firing"):
+			state = AlertStateFiring
+		default:
+			slog.Debug(fmt.Sprintf("skip the alert in state %s", promAlert.State))
+			continue
+		}
+		alert := Alert{
+			Labels:      promAlert.Labels,
+			Annotations: promAlert.Annotations,
+			State:       state,
+			ActiveAt:    promAlert.ActiveAt,
+			Source:      c.Name,
+			OrgId:       orgId,
+		}
+		if seen[alert.Fingerprint()] {
+			continue
+		}
+		// the prometheus compatible api drops the internal labels, so the rule is searched by name
+		alert.RuleURL = c.CreateURL(fmt.Sprintf("/alerting/list?search=%s", url.QueryEscape(alert.GetName())), orgId)
+		seen[alert.Fingerprint()] = true
+		alerts = append(alerts, alert)
+	}
+	return
+}
+
+// GetAlertmanagerAlerts gets the active alerts including the silenced and inhibited ones from the grafana alertmanager.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/alerting_provisioning/
+func (c *Client) GetAlertmanagerAlerts(orgId int) (alerts []alertmanagerAlert, err error) {
+	reqParams := url.Values{}
+	reqParams.Add("active", "true")
+	reqParams.Add("silenced", "true")
+	reqParams.Add("inhibited", "true")
+	reqURL := fmt.Sprintf("%s/api/alertmanager/grafana/api/v2/alerts?%s", c.Host, reqParams.Encode())
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &alerts); err != nil {
+		err = fmt.Errorf("get alertmanager alerts err: %w", err)
+		return
+	}
+	return
+}
+
+// GetPrometheusAlerts gets the pending and firing alerts of the grafana managed rules from the prometheus compatible api
+func (c *Client) GetPrometheusAlerts(orgId int) (alerts []prometheusAlert, err error) {
+	reqURL := fmt.Sprintf("%s/api/prometheus/grafana/api/v1/alerts", c.Host)
+	var resp prometheusAlertsResponse
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &resp); err != nil {
+		err = fmt.Errorf("get prometheus alerts err: %w", err)
+		return
+	}
+	if resp.Status != "success" {
+		err = fmt.Errorf("get prometheus alerts err, status %s", resp.Status)
+		return
+	}
+	alerts = resp.Data.Alerts
+	return
+}
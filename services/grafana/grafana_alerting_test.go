@@ -0,0 +1,88 @@
+package grafana
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Grafana-Org-Id") != "2" {
+			t.Errorf("org header = %q, want 2", r.Header.Get("X-Grafana-Org-Id"))
+		}
+		switch r.URL.Path {
+		case "/api/alertmanager/grafana/api/v2/alerts":
+			_, _ = fmt.Fprint(w, `[
+				{"labels": {"alertname": "HighLatency", "service": "order", "__alert_rule_uid__": "rule-1"},
+					"annotations": {"summary": "p99 too high"}, "startsAt": "2024-01-01T00:00:00Z", "status": {"state": "active"}},
+				{"labels": {"alertname": "DiskFull", "instance": "node-1", "__alert_rule_uid__": "rule/2"},
+					"startsAt": "2024-01-01T00:00:00Z", "status": {"state": "suppressed"}},
+				{"labels": {"alertname": "NoRuleUid"}, "generatorURL": "http://prom/graph",
+					"startsAt": "2024-01-01T00:00:00Z", "status": {"state": "active"}}
+			]`)
+		case "/api/prometheus/grafana/api/v1/alerts":
+			// the firing alert of the alertmanager api is listed again without the internal labels
+			_, _ = fmt.Fprint(w, `{"status": "success", "data": {"alerts": [
+				{"labels": {"alertname": "HighLatency", "service": "order"}, "state": "Alerting", "activeAt": "2024-01-01T00:00:00Z"},
+				{"labels": {"alertname": "SlowQuery", "db": "mysql"}, "state": "Pending", "activeAt": "2024-01-01T01:00:00Z"},
+				{"labels": {"alertname": "NoData", "job": "api"}, "state": "Alerting (NoData)", "activeAt": "2024-01-01T02:00:00Z"},
+				{"labels": {"alertname": "Recovered"}, "state": "Normal", "activeAt": "2024-01-01T03:00:00Z"},
+				{"labels": {"alertname": "Inactive"}, "state": "inactive", "activeAt": "0001-01-01T00:00:00Z"}
+			]}}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewClientWithHttpClient(conf.GrafanaInstance{Name: "prod", Host: server.URL, BaseURL: "https://grafana.example.com/"},
+		server.Client())
+	alerts, err := client.ListAlerts(2)
+	if err != nil {
+		t.Fatalf("list alerts err: %v", err)
+	}
+	type wantAlert struct {
+		state   string
+		ruleURL string
+	}
+	want := map[string]wantAlert{
+		"HighLatency": {state: AlertStateFiring, ruleURL: "https://grafana.example.com/alerting/grafana/rule-1/view?orgId=2"},
+		"DiskFull":    {state: AlertStateSilenced, ruleURL: "https://grafana.example.com/alerting/grafana/rule%2F2/view?orgId=2"},
+		"NoRuleUid":   {state: AlertStateFiring, ruleURL: "http://prom/graph"},
+		"SlowQuery":   {state: AlertStatePending, ruleURL: "https://grafana.example.com/alerting/list?search=SlowQuery&orgId=2"},
+		"NoData":      {state: AlertStateFiring, ruleURL: "https://grafana.example.com/alerting/list?search=NoData&orgId=2"},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("alerts = %+v, want %d alerts", alerts, len(want))
+	}
+	for _, alert := range alerts {
+		wantAlert, ok := want[alert.GetName()]
+		if !ok {
+			t.Errorf("unexpected alert %s", alert.GetName())
+			continue
+		}
+		if alert.State != wantAlert.state {
+			t.Errorf("state of %s = %s, want %s", alert.GetName(), alert.State, wantAlert.state)
+		}
+		if alert.RuleURL != wantAlert.ruleURL {
+			t.Errorf("rule url of %s = %s, want %s", alert.GetName(), alert.RuleURL, wantAlert.ruleURL)
+		}
+		if alert.Source != "prod" || alert.OrgId != 2 {
+			t.Errorf("source of %s = %s/%d, want prod/2", alert.GetName(), alert.Source, alert.OrgId)
+		}
+	}
+}
+
+func TestAlertFingerprint(t *testing.T) {
+	alert := Alert{Labels: map[string]string{"alertname": "A", "service": "order", "__alert_rule_uid__": "rule-1"}}
+	other := Alert{Labels: map[string]string{"service": "order", "alertname": "A"}}
+	if alert.Fingerprint() != other.Fingerprint() {
+		t.Errorf("fingerprint %s != %s, want the internal labels ignored", alert.Fingerprint(), other.Fingerprint())
+	}
+	if changed := (Alert{Labels: map[string]string{"alertname": "A", "service": "pay"}}); changed.Fingerprint() == alert.Fingerprint() {
+		t.Errorf("fingerprint of different labels should differ")
+	}
+}
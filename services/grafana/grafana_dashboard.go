@@ -0,0 +1,207 @@
+package grafana
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DashboardDetail is the response of the dashboard get api, only the fields used by the copilot are kept.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/dashboard/#get-dashboard-by-uid
+type DashboardDetail struct {
+	Dashboard DashboardModel `json:"dashboard"`
+	Meta      DashboardMeta  `json:"meta"`
+	// Source is the name of the grafana instance, it is empty for the single instance
+	Source string `json:"-"`
+	// OrgId is the org of the dashboard, it is 0 if the orgs are not configured
+	OrgId int `json:"-"`
+}
+
+// Key identifies the dashboard across the grafana instances and orgs, see Dashboard.Key
+func (d *DashboardDetail) Key() string {
+	return CreateDashboardKey(d.Source, d.OrgId, d.Dashboard.Uid)
+}
+
+type DashboardModel struct {
+	Uid         string     `json:"uid"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Version     int        `json:"version"`
+	Panels      []Panel    `json:"panels"`
+	Templating  Templating `json:"templating"`
+}
+
+type Templating struct {
+	List []TemplateVariable `json:"list"`
+}
+
+// TemplateVariable is a dashboard variable, which is set by the var-<name> param in the dashboard URL
+type TemplateVariable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Type is query, custom, textbox, constant, datasource, interval, adhoc and so on
+	Type    string           `json:"type"`
+	Multi   bool             `json:"multi"`
+	Options []VariableOption `json:"options"`
+	// Current is the value saved with the dashboard, which is the only known value of most query variables
+	Current VariableOption `json:"current"`
+}
+
+// VariableOption is an option of the variable, the value is a string or a list of strings
+type VariableOption struct {
+	Text  any `json:"text"`
+	Value any `json:"value"`
+}
+
+type DashboardMeta struct {
+	Slug        string `json:"slug"`
+	URL         string `json:"url"`
+	FolderUid   string `json:"folderUid"`
+	FolderTitle string `json:"folderTitle"`
+}
+
+// Panel is a dashboard panel, the row panel contains its children panels when collapsed
+type Panel struct {
+	Id          int           `json:"id"`
+	Type        string        `json:"type"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Targets     []PanelTarget `json:"targets"`
+	Panels      []Panel       `json:"panels"`
+}
+
+// PanelTarget is the query of the panel, different datasources use different fields for the query
+type PanelTarget struct {
+	RefId string `json:"refId"`
+	// Expr is used by prometheus and loki
+	Expr string `json:"expr"`
+	// Query is used by influxdb, elasticsearch and so on
+	Query string `json:"query"`
+	// RawSql is used by sql datasources
+	RawSql string `json:"rawSql"`
+	// Target is used by graphite
+	Target string `json:"target"`
+}
+
+// GetQuery returns the first non-empty query of the target
+func (t PanelTarget) GetQuery() string {
+	for _, query := range []string{t.Expr, t.Query, t.RawSql, t.Target} {
+		if query != "" {
+			return query
+		}
+	}
+	return ""
+}
+
+// GetQueries returns all the non-empty queries of the panel
+func (p Panel) GetQueries() (queries []string) {
+	for _, target := range p.Targets {
+		if query := target.GetQuery(); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	return
+}
+
+// GetPanels returns all the panels of the dashboard except rows, the panels in the collapsed rows are included
+func (d DashboardModel) GetPanels() (panels []Panel) {
+	for _, panel := range d.Panels {
+		if panel.Type == "row" {
+			panels = append(panels, panel.Panels...)
+			continue
+		}
+		panels = append(panels, panel)
+	}
+	return
+}
+
+// CreatePanelURL creates the deep link which opens the panel in view mode
+func CreatePanelURL(dashboardURL string, panelId int) string {
+	reqParams := url.Values{}
+	reqParams.Add("viewPanel", fmt.Sprintf("%d", panelId))
+	return AddURLParams(dashboardURL, reqParams)
+}
+
+// AddURLParams appends the params to the URL which may already have query params, e.g. orgId
+func AddURLParams(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s%s", rawURL, separator, params.Encode())
+}
+
+// GetVariable returns the variable which can be set in the dashboard URL by name, the constant
+// and adhoc variables are excluded.
+func (d DashboardModel) GetVariable(name string) (variable TemplateVariable, ok bool) {
+	for _, variable = range d.Templating.List {
+		if variable.Type == "constant" || variable.Type == "adhoc" {
+			continue
+		}
+		if strings.EqualFold(variable.Name, name) {
+			return variable, true
+		}
+	}
+	return
+}
+
+// GetOptionValues returns the values of the saved options and the current value. The query variables
+// usually have no options saved in the dashboard since they are refreshed on load.
+func (v TemplateVariable) GetOptionValues() (values []string) {
+	seen := make(map[string]bool)
+	for _, option := range append(v.Options, v.Current) {
+		for _, value := range option.GetValues() {
+			if value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return
+}
+
+// AcceptsAnyValue checks whether the variable accepts any value, e.g. the textbox variables
+func (v TemplateVariable) AcceptsAnyValue() bool {
+	return v.Type == "textbox"
+}
+
+// MatchValue checks the value against the saved options and the current value, and returns the value in its
+// original case. Any value is accepted by the textbox variables, while the values of the other variables
+// without any known value are rejected since they can't be verified.
+func (v TemplateVariable) MatchValue(value string) (matched string, ok bool) {
+	if value == "" {
+		return
+	}
+	if v.AcceptsAnyValue() {
+		return value, true
+	}
+	for _, optionValue := range v.GetOptionValues() {
+		if strings.EqualFold(optionValue, value) {
+			return optionValue, true
+		}
+	}
+	return
+}
+
+// GetValues returns the values of the option, the value is a list of strings for the multi-value variables
+func (o VariableOption) GetValues() (values []string) {
+	switch value := o.Value.(type) {
+	case string:
+		values = append(values, value)
+	case []any:
+		for _, item := range value {
+			values = append(values, fmt.Sprint(item))
+		}
+	}
+	return
+}
+
+var relativeTimePattern = regexp.MustCompile(`^now(-\d+[smhdwMy])?(/[smhdwMy])?$`)
+var absoluteTimePattern = regexp.MustCompile(`^\d{13}$`)
+
+// IsValidTime checks whether the time is a relative time like now-3h, now/d, or an absolute
+// time in epoch milliseconds, which are accepted by the from and to params of the dashboard URL.
+func IsValidTime(value string) bool {
+	return relativeTimePattern.MatchString(value) || absoluteTimePattern.MatchString(value)
+}
@@ -0,0 +1,70 @@
+package grafana
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIsValidTime(t *testing.T) {
+	testCases := []struct {
+		value string
+		want  bool
+	}{
+		{value: "now", want: true},
+		{value: "now-3h", want: true},
+		{value: "now/d", want: true},
+		{value: "now-1d/d", want: true},
+		{value: "now-15m", want: true},
+		{value: "1700000000000", want: true},
+		{value: "", want: false},
+		{value: "now-3", want: false},
+		{value: "now+1h", want: false},
+		{value: "1700000000", want: false},
+		{value: "2024-01-01", want: false},
+		{value: "now-3h&to=now", want: false},
+	}
+	for _, testCase := range testCases {
+		if got := IsValidTime(testCase.value); got != testCase.want {
+			t.Errorf("IsValidTime(%q) = %v, want %v", testCase.value, got, testCase.want)
+		}
+	}
+}
+
+func TestTemplateVariableMatchValue(t *testing.T) {
+	var variables map[string]TemplateVariable
+	// the variables are decoded from the dashboard json model
+	err := json.Unmarshal([]byte(`{
+		"custom": {"name": "env", "type": "custom", "options": [{"text": "Prod", "value": "Prod"}, {"text": "Staging", "value": "staging"}]},
+		"query": {"name": "host", "type": "query", "options": [], "current": {"text": "web-1", "value": "web-1"}},
+		"multi": {"name": "pod", "type": "query", "multi": true, "current": {"text": ["a", "b"], "value": ["a", "b"]}},
+		"unknown": {"name": "service", "type": "query", "options": []},
+		"textbox": {"name": "filter", "type": "textbox", "current": {"text": "", "value": ""}}
+	}`), &variables)
+	if err != nil {
+		t.Fatalf("decode variables err: %v", err)
+	}
+	testCases := []struct {
+		name        string
+		variable    string
+		value       string
+		wantMatched string
+		wantOk      bool
+	}{
+		{name: "option in original case", variable: "custom", value: "prod", wantMatched: "Prod", wantOk: true},
+		{name: "unknown option", variable: "custom", value: "dev", wantOk: false},
+		{name: "current value of query variable", variable: "query", value: "WEB-1", wantMatched: "web-1", wantOk: true},
+		{name: "other value of query variable", variable: "query", value: "web-2", wantOk: false},
+		{name: "multi current value", variable: "multi", value: "b", wantMatched: "b", wantOk: true},
+		{name: "query variable without known values", variable: "unknown", value: "checkout", wantOk: false},
+		{name: "textbox", variable: "textbox", value: "status=500", wantMatched: "status=500", wantOk: true},
+		{name: "empty value", variable: "textbox", value: "", wantOk: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			matched, ok := variables[testCase.variable].MatchValue(testCase.value)
+			if ok != testCase.wantOk || matched != testCase.wantMatched {
+				t.Errorf("MatchValue(%q) = %q, %v, want %q, %v", testCase.value, matched, ok, testCase.wantMatched, testCase.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	DatasourceTypePrometheus = "prometheus"
+	DatasourceTypeLoki       = "loki"
+)
+
+// metricNamesCacheTTL is the time to cache the metric names of each datasource, since listing the names
+// is expensive for the large prometheus
+const metricNamesCacheTTL = 10 * time.Minute
+
+// metricNamesCacheEntry is the cached metric names of a datasource
+type metricNamesCacheEntry struct {
+	MetricNames []string
+	ExpiresAt   time.Time
+}
+
+// Datasource is a datasource of the grafana org
+type Datasource struct {
+	Id        int    `json:"id"`
+	Uid       string `json:"uid"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	IsDefault bool   `json:"isDefault"`
+	Source    string `json:"-"`
+	OrgId     int    `json:"-"`
+}
+
+// MetricMetadata is the type and help text of the prometheus metric
+type MetricMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+type prometheusResponse struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+// QueryResult is a series of the datasource query result, the value is the last value of the series
+type QueryResult struct {
+	Labels map[string]string
+	Value  float64
+	// Valid is false if the value is null, the NaN and Inf values are valid
+	Valid bool
+	// Time is the time of the value
+	Time time.Time
+}
+
+// FormatValue formats the value in 6 significant digits, the null value is formatted as null
+func (r QueryResult) FormatValue() string {
+	if !r.Valid {
+		return "null"
+	}
+	return fmt.Sprintf("%.6g", r.Value)
+}
+
+type dsQueryRequest struct {
+	Queries []dsQuery `json:"queries"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+}
+
+type dsQuery struct {
+	RefId      string       `json:"refId"`
+	Datasource dsQueryRefDs `json:"datasource"`
+	Expr       string       `json:"expr"`
+	Instant    bool         `json:"instant"`
+	Range      bool         `json:"range"`
+	// QueryType and MaxLines are the options of the loki queries
+	QueryType string `json:"queryType,omitempty"`
+	MaxLines  int    `json:"maxLines,omitempty"`
+}
+
+type dsQueryRefDs struct {
+	Uid  string `json:"uid"`
+	Type string `json:"type"`
+}
+
+type dsQueryResponse struct {
+	Results map[string]dsQueryResult `json:"results"`
+}
+
+type dsQueryResult struct {
+	Error  string         `json:"error"`
+	Frames []dsQueryFrame `json:"frames"`
+}
+
+type dsQueryFrame struct {
+	Schema struct {
+		Fields []struct {
+			Name   string            `json:"name"`
+			Type   string            `json:"type"`
+			Labels map[string]string `json:"labels"`
+		} `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values [][]any `json:"values"`
+		// Entities are the special values of each field, which are encoded as null in the values
+		Entities []*dsFieldEntities `json:"entities"`
+	} `json:"data"`
+}
+
+// dsFieldEntities lists the indexes of the NaN and Inf values of a field, since json has no such numbers
+type dsFieldEntities struct {
+	NaN    []int `json:"NaN"`
+	Inf    []int `json:"Inf"`
+	NegInf []int `json:"NegInf"`
+}
+
+// getEntityValue returns the special value of the field at the index if any
+func (f *dsQueryFrame) getEntityValue(fieldIndex, valueIndex int) (value float64, ok bool) {
+	if fieldIndex >= len(f.Data.Entities) || f.Data.Entities[fieldIndex] == nil {
+		return
+	}
+	entities := f.Data.Entities[fieldIndex]
+	switch {
+	case slices.Contains(entities.NaN, valueIndex):
+		return math.NaN(), true
+	case slices.Contains(entities.Inf, valueIndex):
+		return math.Inf(1), true
+	case slices.Contains(entities.NegInf, valueIndex):
+		return math.Inf(-1), true
+	}
+	return
+}
+
+// ListDatasources lists the datasources of the org.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/data_source/
+func (c *Client) ListDatasources(orgId int) (datasources []Datasource, err error) {
+	reqURL := fmt.Sprintf("%s/api/datasources", c.Host)
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &datasources); err != nil {
+		err = fmt.Errorf("list datasources err: %w", err)
+		return
+	}
+	for index := range datasources {
+		datasources[index].Source = c.Name
+		datasources[index].OrgId = orgId
+	}
+	return
+}
+
+// callPrometheusAPI calls the prometheus http api through the grafana datasource proxy and decodes the data,
+// it is also used to call the loki http api which has the same response format.
+// See https://prometheus.io/docs/prometheus/latest/querying/api/
+func (c *Client) callPrometheusAPI(datasource Datasource, apiPath string, reqParams url.Values, data any) (err error) {
+	reqURL := fmt.Sprintf("%s/api/datasources/proxy/uid/%s%s", c.Host, url.PathEscape(datasource.Uid), apiPath)
+	if len(reqParams) > 0 {
+		reqURL = fmt.Sprintf("%s?%s", reqURL, reqParams.Encode())
+	}
+	var resp prometheusResponse
+	if err = c.callGrafanaAPI(datasource.OrgId, http.MethodGet, reqURL, &resp); err != nil {
+		return
+	}
+	if resp.Status != "success" {
+		err = fmt.Errorf("call prometheus api err, %s", resp.Error)
+		return
+	}
+	if err = json.Unmarshal(resp.Data, data); err != nil {
+		err = fmt.Errorf("decode prometheus api data err, %s", err.Error())
+		return
+	}
+	return
+}
+
+// ListMetricNames lists the names of the metrics which have series in the last hour, at most limit names are
+// returned. The names are cached for each datasource, since there may be millions of them in the prometheus.
+func (c *Client) ListMetricNames(datasource Datasource, limit int) (metricNames []string, err error) {
+	cacheKey := fmt.Sprintf("%d/%s/%d", datasource.OrgId, datasource.Uid, limit)
+	c.metricNamesLock.Lock()
+	entry, ok := c.metricNamesCache[cacheKey]
+	c.metricNamesLock.Unlock()
+	if ok && time.Now().Before(entry.ExpiresAt) {
+		return entry.MetricNames, nil
+	}
+	reqParams := createSeriesParams(nil)
+	// the limit param is supported since prometheus 2.51, the names are truncated as well for the older versions
+	reqParams.Set("limit", strconv.Itoa(limit))
+	if err = c.callPrometheusAPI(datasource, "/api/v1/label/__name__/values", reqParams, &metricNames); err != nil {
+		err = fmt.Errorf("list metric names err: %w", err)
+		return
+	}
+	if len(metricNames) > limit {
+		metricNames = metricNames[:limit]
+	}
+	c.metricNamesLock.Lock()
+	if c.metricNamesCache == nil {
+		c.metricNamesCache = make(map[string]metricNamesCacheEntry)
+	}
+	c.metricNamesCache[cacheKey] = metricNamesCacheEntry{
+		MetricNames: metricNames,
+		ExpiresAt:   time.Now().Add(metricNamesCacheTTL),
+	}
+	c.metricNamesLock.Unlock()
+	return
+}
+
+// GetMetricMetadata gets the type and help text of all the metrics of the prometheus datasource
+func (c *Client) GetMetricMetadata(datasource Datasource) (metadata map[string][]MetricMetadata, err error) {
+	if err = c.callPrometheusAPI(datasource, "/api/v1/metadata", nil, &metadata); err != nil {
+		err = fmt.Errorf("get metric metadata err: %w", err)
+		return
+	}
+	return
+}
+
+// ListLabelNames lists the label names of the series of the metrics in the last hour
+func (c *Client) ListLabelNames(datasource Datasource, metricNames []string) (labelNames []string, err error) {
+	reqParams := createSeriesParams(metricNames)
+	if err = c.callPrometheusAPI(datasource, "/api/v1/labels", reqParams, &labelNames); err != nil {
+		err = fmt.Errorf("list label names err: %w", err)
+		return
+	}
+	return
+}
+
+// ListLabelValues lists the values of the label of the series of the metrics in the last hour
+func (c *Client) ListLabelValues(datasource Datasource, labelName string, metricNames []string) (labelValues []string, err error) {
+	reqParams := createSeriesParams(metricNames)
+	apiPath := fmt.Sprintf("/api/v1/label/%s/values", url.PathEscape(labelName))
+	if err = c.callPrometheusAPI(datasource, apiPath, reqParams, &labelValues); err != nil {
+		err = fmt.Errorf("list label values err: %w", err)
+		return
+	}
+	return
+}
+
+func createSeriesParams(metricNames []string) url.Values {
+	reqParams := url.Values{}
+	for _, metricName := range metricNames {
+		reqParams.Add("match[]", metricName)
+	}
+	now := time.Now()
+	reqParams.Set("start", strconv.FormatInt(now.Add(-time.Hour).Unix(), 10))
+	reqParams.Set("end", strconv.FormatInt(now.Unix(), 10))
+	return reqParams
+}
+
+// QueryPrometheus runs the instant query of the promql expression through the grafana query api, the
+// query error such as the syntax error is returned as is.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/data_source/#query-a-data-source
+func (c *Client) QueryPrometheus(datasource Datasource, expr string) (results []QueryResult, err error) {
+	reqURL := fmt.Sprintf("%s/api/ds/query", c.Host)
+	reqBody := dsQueryRequest{
+		Queries: []dsQuery{{
+			RefId:      "A",
+			Datasource: dsQueryRefDs{Uid: datasource.Uid, Type: datasource.Type},
+			Expr:       expr,
+			Instant:    true,
+		}},
+		From: "now-5m",
+		To:   "now",
+	}
+	var resp dsQueryResponse
+	if err = c.callGrafanaAPIWithBody(datasource.OrgId, http.MethodPost, reqURL, &reqBody, &resp); err != nil {
+		err = fmt.Errorf("query datasource err: %w", err)
+		return
+	}
+	result := resp.Results["A"]
+	if result.Error != "" {
+		err = fmt.Errorf("query datasource err, %s", result.Error)
+		return
+	}
+	for _, frame := range result.Frames {
+		results = append(results, frame.toQueryResults()...)
+	}
+	return
+}
+
+// toQueryResults converts the data frame which has a time field and the number fields of the series
+func (f *dsQueryFrame) toQueryResults() (results []QueryResult) {
+	var times []any
+	for index, field := range f.Schema.Fields {
+		if field.Type == "time" && index < len(f.Data.Values) {
+			times = f.Data.Values[index]
+		}
+	}
+	for index, field := range f.Schema.Fields {
+		if field.Type != "number" || index >= len(f.Data.Values) || len(f.Data.Values[index]) == 0 {
+			continue
+		}
+		values := f.Data.Values[index]
+		result := QueryResult{Labels: field.Labels}
+		// the null, NaN and Inf values are all decoded as nil, the latter two are listed in the entities
+		lastIndex := len(values) - 1
+		if result.Value, result.Valid = values[lastIndex].(float64); !result.Valid {
+			result.Value, result.Valid = f.getEntityValue(index, lastIndex)
+		}
+		if len(times) == len(values) {
+			if millis, ok := times[len(times)-1].(float64); ok {
+				result.Time = time.UnixMilli(int64(millis))
+			}
+		}
+		results = append(results, result)
+	}
+	return
+}
+
+// CreateExploreURL creates the grafana explore link of the query expression in the time range
+func (c *Client) CreateExploreURL(datasource Datasource, expr, from, to string) string {
+	panes := map[string]any{
+		"copilot": map[string]any{
+			"datasource": datasource.Uid,
+			"queries": []map[string]any{{
+				"refId":      "A",
+				"expr":       expr,
+				"datasource": dsQueryRefDs{Uid: datasource.Uid, Type: datasource.Type},
+			}},
+			"range": map[string]string{"from": from, "to": to},
+		},
+	}
+	panesData, _ := json.Marshal(panes)
+	reqParams := url.Values{}
+	reqParams.Set("schemaVersion", "1")
+	reqParams.Set("panes", string(panesData))
+	return c.CreateURL(fmt.Sprintf("/explore?%s", reqParams.Encode()), datasource.OrgId)
+}
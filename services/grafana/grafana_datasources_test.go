@@ -0,0 +1,132 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestToQueryResults(t *testing.T) {
+	testCases := []struct {
+		name      string
+		frame     string
+		wantCount int
+		// wantValue is checked only if the result is valid, NaN is compared by math.IsNaN
+		wantValue float64
+		wantValid bool
+		wantTime  time.Time
+	}{
+		{name: "number", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number", "labels": {"job": "api"}}]},
+			"data": {"values": [[1700000000000, 1700000060000], [1, 2.5]]}}`,
+			wantCount: 1, wantValue: 2.5, wantValid: true, wantTime: time.UnixMilli(1700000060000)},
+		{name: "null", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[1700000000000], [null]]}}`,
+			wantCount: 1, wantValid: false, wantTime: time.UnixMilli(1700000000000)},
+		{name: "NaN", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[1700000000000], [null]], "entities": [null, {"NaN": [0]}]}}`,
+			wantCount: 1, wantValue: math.NaN(), wantValid: true, wantTime: time.UnixMilli(1700000000000)},
+		{name: "Inf", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[1, 2], [3, null]], "entities": [null, {"Inf": [1]}]}}`,
+			wantCount: 1, wantValue: math.Inf(1), wantValid: true, wantTime: time.UnixMilli(2)},
+		{name: "NegInf", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[1], [null]], "entities": [null, {"NegInf": [0]}]}}`,
+			wantCount: 1, wantValue: math.Inf(-1), wantValid: true, wantTime: time.UnixMilli(1)},
+		{name: "entity of another index", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[1, 2], [null, null]], "entities": [null, {"NaN": [0]}]}}`,
+			wantCount: 1, wantValid: false, wantTime: time.UnixMilli(2)},
+		{name: "empty field", frame: `{"schema": {"fields": [{"type": "time"}, {"type": "number"}]},
+			"data": {"values": [[], []]}}`, wantCount: 0},
+		{name: "no time field", frame: `{"schema": {"fields": [{"type": "number"}]}, "data": {"values": [[7]]}}`,
+			wantCount: 1, wantValue: 7, wantValid: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var frame dsQueryFrame
+			if err := json.Unmarshal([]byte(testCase.frame), &frame); err != nil {
+				t.Fatalf("decode frame err: %v", err)
+			}
+			results := frame.toQueryResults()
+			if len(results) != testCase.wantCount {
+				t.Fatalf("results = %+v, want %d", results, testCase.wantCount)
+			}
+			if testCase.wantCount == 0 {
+				return
+			}
+			result := results[0]
+			if result.Valid != testCase.wantValid {
+				t.Errorf("valid = %v, want %v", result.Valid, testCase.wantValid)
+			}
+			if result.Valid && result.Value != testCase.wantValue && !(math.IsNaN(result.Value) && math.IsNaN(testCase.wantValue)) {
+				t.Errorf("value = %v, want %v", result.Value, testCase.wantValue)
+			}
+			if !result.Time.Equal(testCase.wantTime) {
+				t.Errorf("time = %v, want %v", result.Time, testCase.wantTime)
+			}
+		})
+	}
+}
+
+func TestQueryResultFormatValue(t *testing.T) {
+	testCases := []struct {
+		result QueryResult
+		want   string
+	}{
+		{result: QueryResult{Value: 0.123456789, Valid: true}, want: "0.123457"},
+		{result: QueryResult{Value: 0, Valid: true}, want: "0"},
+		{result: QueryResult{}, want: "null"},
+		{result: QueryResult{Value: math.NaN(), Valid: true}, want: "NaN"},
+		{result: QueryResult{Value: math.Inf(1), Valid: true}, want: "+Inf"},
+		{result: QueryResult{Value: math.Inf(-1), Valid: true}, want: "-Inf"},
+	}
+	for _, testCase := range testCases {
+		if got := testCase.result.FormatValue(); got != testCase.want {
+			t.Errorf("FormatValue(%+v) = %q, want %q", testCase.result, got, testCase.want)
+		}
+	}
+}
+
+func TestListMetricNames(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if r.URL.Path != "/api/datasources/proxy/uid/prom/api/v1/label/__name__/values" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		start, startErr := strconv.ParseInt(query.Get("start"), 10, 64)
+		end, endErr := strconv.ParseInt(query.Get("end"), 10, 64)
+		if startErr != nil || endErr != nil || end-start != int64(time.Hour/time.Second) {
+			t.Errorf("start = %q, end = %q, want the last hour", query.Get("start"), query.Get("end"))
+		}
+		if query.Get("limit") != "2" {
+			t.Errorf("limit = %q, want 2", query.Get("limit"))
+		}
+		// the old prometheus ignores the limit param
+		_, _ = fmt.Fprint(w, `{"status": "success", "data": ["a", "b", "c"]}`)
+	}))
+	defer server.Close()
+	client := NewClientWithHttpClient(conf.GrafanaInstance{Host: server.URL}, server.Client())
+	datasource := Datasource{Uid: "prom", Type: DatasourceTypePrometheus}
+	for attempt := 0; attempt < 2; attempt++ {
+		metricNames, err := client.ListMetricNames(datasource, 2)
+		if err != nil {
+			t.Fatalf("list metric names err: %v", err)
+		}
+		if len(metricNames) != 2 || metricNames[0] != "a" || metricNames[1] != "b" {
+			t.Errorf("metric names = %v, want the first 2 names", metricNames)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want the names cached", requests)
+	}
+	// the names of another datasource are not shared
+	if _, err := client.ListMetricNames(Datasource{Uid: "prom", OrgId: 2}, 2); err != nil || requests != 2 {
+		t.Errorf("requests = %d, err = %v, want the names of the other org listed", requests, err)
+	}
+}
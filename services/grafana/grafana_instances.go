@@ -0,0 +1,240 @@
+package grafana
+
+import (
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var clients []*Client
+var clientsOnce sync.Once
+
+// GetClients returns the clients of all the configured grafana instances in the configured order
+func GetClients() []*Client {
+	clientsOnce.Do(func() {
+		for _, instance := range conf.AppConfig.GrafanaInstances {
+			clients = append(clients, NewClient(instance))
+		}
+	})
+	return clients
+}
+
+// GetClient returns the client of the named instance, it returns nil if the instance is not configured
+func GetClient(name string) *Client {
+	for _, client := range GetClients() {
+		if client.Name == name {
+			return client
+		}
+	}
+	return nil
+}
+
+// ListDashboardMeta lists all the dashboards matching the query of all the instances. The failed
+// instances are skipped, and the error is returned only when all the instances fail.
+func ListDashboardMeta(query string) (dashboardList []Dashboard, err error) {
+	var errs []string
+	for _, client := range GetClients() {
+		result, searchErr := client.SearchAllDashboards(query)
+		if searchErr != nil {
+			slog.Error(fmt.Sprintf("list dashboards of grafana %s err: %v", client.Name, searchErr))
+			errs = append(errs, searchErr.Error())
+			continue
+		}
+		dashboardList = append(dashboardList, result.Dashboards...)
+	}
+	if len(errs) == len(GetClients()) {
+		err = fmt.Errorf("list dashboards err: %s", strings.Join(errs, "; "))
+	}
+	return
+}
+
+// ListAllAlerts lists the alerts of all the orgs of the instances, all the instances are listed if no
+// source is given. The failed instances or orgs are skipped, and the error is returned only when all fail.
+func ListAllAlerts(sources []string) (alerts []Alert, err error) {
+	var errs []string
+	var total int
+	for _, client := range GetClients() {
+		if len(sources) > 0 && !slices.Contains(sources, client.Name) {
+			continue
+		}
+		for _, orgId := range client.GetOrgIds() {
+			total++
+			orgAlerts, listErr := client.ListAlerts(orgId)
+			if listErr != nil {
+				slog.Error(fmt.Sprintf("list alerts of grafana %s org %d err: %v", client.Name, orgId, listErr))
+				errs = append(errs, listErr.Error())
+				continue
+			}
+			alerts = append(alerts, orgAlerts...)
+		}
+	}
+	if total > 0 && len(errs) == total {
+		err = fmt.Errorf("list alerts err: %s", strings.Join(errs, "; "))
+	}
+	return
+}
+
+// ListAllSilences lists the silences of all the orgs of the instances, all the instances are listed if no
+// source is given. The failed instances or orgs are skipped, and the error is returned only when all fail.
+func ListAllSilences(sources []string) (silences []Silence, err error) {
+	var errs []string
+	var total int
+	for _, client := range GetClients() {
+		if len(sources) > 0 && !slices.Contains(sources, client.Name) {
+			continue
+		}
+		for _, orgId := range client.GetOrgIds() {
+			total++
+			orgSilences, listErr := client.ListSilences(orgId)
+			if listErr != nil {
+				slog.Error(fmt.Sprintf("list silences of grafana %s org %d err: %v", client.Name, orgId, listErr))
+				errs = append(errs, listErr.Error())
+				continue
+			}
+			silences = append(silences, orgSilences...)
+		}
+	}
+	if total > 0 && len(errs) == total {
+		err = fmt.Errorf("list silences err: %s", strings.Join(errs, "; "))
+	}
+	return
+}
+
+// CreateSilence creates the silence in the instance and org of the silence
+func CreateSilence(silence Silence) (silenceId string, err error) {
+	client := GetClient(silence.Source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", silence.Source)
+		return
+	}
+	return client.CreateSilence(silence.OrgId, silence)
+}
+
+// ExpireSilence expires the silence in the instance and org of the silence
+func ExpireSilence(silence Silence) (err error) {
+	client := GetClient(silence.Source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", silence.Source)
+		return
+	}
+	return client.ExpireSilence(silence.OrgId, silence.Id)
+}
+
+// ListDatasourcesByType lists the datasources of the type of all the orgs of the instances, e.g. prometheus, all the
+// instances are listed if no source is given. The failed instances or orgs are skipped, and the error is returned
+// only when all fail.
+func ListDatasourcesByType(sources []string, datasourceType string) (datasources []Datasource, err error) {
+	var errs []string
+	var total int
+	for _, client := range GetClients() {
+		if len(sources) > 0 && !slices.Contains(sources, client.Name) {
+			continue
+		}
+		for _, orgId := range client.GetOrgIds() {
+			total++
+			orgDatasources, listErr := client.ListDatasources(orgId)
+			if listErr != nil {
+				slog.Error(fmt.Sprintf("list datasources of grafana %s org %d err: %v", client.Name, orgId, listErr))
+				errs = append(errs, listErr.Error())
+				continue
+			}
+			for _, datasource := range orgDatasources {
+				if datasource.Type == datasourceType {
+					datasources = append(datasources, datasource)
+				}
+			}
+		}
+	}
+	if total > 0 && len(errs) == total {
+		err = fmt.Errorf("list datasources err: %s", strings.Join(errs, "; "))
+	}
+	return
+}
+
+// GetDashboard gets the dashboard json model from the instance which the dashboard belongs to
+func GetDashboard(source string, orgId int, uid string) (dashboardDetail DashboardDetail, err error) {
+	client := GetClient(source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", source)
+		return
+	}
+	return client.GetDashboardByUid(orgId, uid)
+}
+
+// GetDashboardVersion gets the latest version of the dashboard from the instance which the dashboard belongs to
+func GetDashboardVersion(source string, orgId int, uid string) (version int, err error) {
+	client := GetClient(source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", source)
+		return
+	}
+	return client.GetDashboardVersion(orgId, uid)
+}
+
+// CreateDashboardURL creates the full access URL with the base URL of the instance and the org which
+// the dashboard belongs to
+func CreateDashboardURL(source string, orgId int, relativeURL string) string {
+	client := GetClient(source)
+	if client == nil {
+		return relativeURL
+	}
+	return client.CreateURL(relativeURL, orgId)
+}
+
+// RenderPanel renders the panel deep link into a png image by the instance which the panel belongs to
+func RenderPanel(source string, orgId int, panelURL string, width, height int) (image []byte, err error) {
+	client := GetClient(source)
+	if client == nil {
+		err = fmt.Errorf("grafana instance %q not configured", source)
+		return
+	}
+	return client.RenderPanel(orgId, panelURL, width, height)
+}
+
+// MatchInstances returns the names of the instances mentioned by name or alias in the text, e.g. "in staging"
+func MatchInstances(text string) (names []string) {
+	text = strings.ToLower(text)
+	for _, client := range GetClients() {
+		if client.Name == "" {
+			continue
+		}
+		for _, word := range append([]string{client.Name}, client.Aliases...) {
+			if containsWord(text, strings.ToLower(word)) {
+				names = append(names, client.Name)
+				break
+			}
+		}
+	}
+	return
+}
+
+// containsWord checks whether the word is in the text and not part of a longer latin word,
+// e.g. prod is not found in production. The chinese words are matched as substrings.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for offset := 0; ; {
+		index := strings.Index(text[offset:], word)
+		if index < 0 {
+			return false
+		}
+		start := offset + index
+		end := start + len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && !unicode.Is(unicode.Han, r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
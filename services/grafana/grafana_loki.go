@@ -0,0 +1,118 @@
+package grafana
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// LogLine is a log line of the loki query result
+type LogLine struct {
+	Time   time.Time
+	Line   string
+	Labels map[string]string
+}
+
+// ListStreamLabelNames lists the stream label names of the loki datasource in the last hour.
+// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-labels
+func (c *Client) ListStreamLabelNames(datasource Datasource) (labelNames []string, err error) {
+	if err = c.callPrometheusAPI(datasource, "/loki/api/v1/labels", createLokiTimeParams(), &labelNames); err != nil {
+		err = fmt.Errorf("list stream label names err: %w", err)
+		return
+	}
+	return
+}
+
+// ListStreamLabelValues lists the values of the stream label of the loki datasource in the last hour
+func (c *Client) ListStreamLabelValues(datasource Datasource, labelName string) (labelValues []string, err error) {
+	apiPath := fmt.Sprintf("/loki/api/v1/label/%s/values", url.PathEscape(labelName))
+	if err = c.callPrometheusAPI(datasource, apiPath, createLokiTimeParams(), &labelValues); err != nil {
+		err = fmt.Errorf("list stream label values err: %w", err)
+		return
+	}
+	return
+}
+
+func createLokiTimeParams() url.Values {
+	reqParams := url.Values{}
+	now := time.Now()
+	reqParams.Set("start", now.Add(-time.Hour).Format(time.RFC3339))
+	reqParams.Set("end", now.Format(time.RFC3339))
+	return reqParams
+}
+
+// QueryLoki runs the logql log query in the time range through the grafana query api, the time range
+// is in the grafana time format, e.g. now-15m. The newest log lines are returned first.
+func (c *Client) QueryLoki(datasource Datasource, expr, from, to string, maxLines int) (logLines []LogLine, err error) {
+	reqURL := fmt.Sprintf("%s/api/ds/query", c.Host)
+	reqBody := dsQueryRequest{
+		Queries: []dsQuery{{
+			RefId:      "A",
+			Datasource: dsQueryRefDs{Uid: datasource.Uid, Type: datasource.Type},
+			Expr:       expr,
+			Range:      true,
+			QueryType:  "range",
+			MaxLines:   maxLines,
+		}},
+		From: from,
+		To:   to,
+	}
+	var resp dsQueryResponse
+	if err = c.callGrafanaAPIWithBody(datasource.OrgId, http.MethodPost, reqURL, &reqBody, &resp); err != nil {
+		err = fmt.Errorf("query datasource err: %w", err)
+		return
+	}
+	result := resp.Results["A"]
+	if result.Error != "" {
+		err = fmt.Errorf("query datasource err, %s", result.Error)
+		return
+	}
+	for _, frame := range result.Frames {
+		logLines = append(logLines, frame.toLogLines()...)
+	}
+	sort.SliceStable(logLines, func(i, j int) bool {
+		return logLines[i].Time.After(logLines[j].Time)
+	})
+	if len(logLines) > maxLines {
+		logLines = logLines[:maxLines]
+	}
+	return
+}
+
+// toLogLines converts the loki data frame which has the labels, Time and Line fields
+func (f *dsQueryFrame) toLogLines() (logLines []LogLine) {
+	var times, lines, labels []any
+	for index, field := range f.Schema.Fields {
+		if index >= len(f.Data.Values) {
+			break
+		}
+		switch {
+		case field.Type == "time":
+			times = f.Data.Values[index]
+		case field.Name == "Line" || field.Name == "body":
+			lines = f.Data.Values[index]
+		case field.Name == "labels":
+			labels = f.Data.Values[index]
+		}
+	}
+	for index, line := range lines {
+		logLine := LogLine{Labels: make(map[string]string)}
+		logLine.Line, _ = line.(string)
+		if index < len(times) {
+			if millis, ok := times[index].(float64); ok {
+				logLine.Time = time.UnixMilli(int64(millis))
+			}
+		}
+		if index < len(labels) {
+			if labelMap, ok := labels[index].(map[string]any); ok {
+				for name, value := range labelMap {
+					logLine.Labels[name] = fmt.Sprint(value)
+				}
+			}
+		}
+		logLines = append(logLines, logLine)
+	}
+	return
+}
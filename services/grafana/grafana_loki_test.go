@@ -0,0 +1,71 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToLogLines(t *testing.T) {
+	testCases := []struct {
+		name  string
+		frame string
+		want  []LogLine
+	}{
+		{name: "loki frame", frame: `{"schema": {"fields": [{"name": "labels", "type": "other"}, {"name": "Time", "type": "time"},
+			{"name": "Line", "type": "string"}]}, "data": {"values": [[{"app": "gateway", "code": 500}], [1700000000000], ["error"]]}}`,
+			want: []LogLine{{Time: time.UnixMilli(1700000000000), Line: "error", Labels: map[string]string{"app": "gateway", "code": "500"}}}},
+		{name: "body field of the new loki frame", frame: `{"schema": {"fields": [{"name": "timestamp", "type": "time"},
+			{"name": "body", "type": "string"}]}, "data": {"values": [[1, 2], ["first", "second"]]}}`,
+			want: []LogLine{{Time: time.UnixMilli(1), Line: "first", Labels: map[string]string{}},
+				{Time: time.UnixMilli(2), Line: "second", Labels: map[string]string{}}}},
+		{name: "missing times and labels", frame: `{"schema": {"fields": [{"name": "Line", "type": "string"}]},
+			"data": {"values": [["only line"]]}}`,
+			want: []LogLine{{Line: "only line", Labels: map[string]string{}}}},
+		{name: "fields without values", frame: `{"schema": {"fields": [{"name": "Time", "type": "time"}, {"name": "Line", "type": "string"}]},
+			"data": {"values": [[1]]}}`, want: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var frame dsQueryFrame
+			if err := json.Unmarshal([]byte(testCase.frame), &frame); err != nil {
+				t.Fatalf("decode frame err: %v", err)
+			}
+			if got := frame.toLogLines(); !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("toLogLines = %+v, want %+v", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestQueryLoki(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody dsQueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil || len(reqBody.Queries) != 1 {
+			t.Errorf("decode request err: %v", err)
+			return
+		}
+		if query := reqBody.Queries[0]; query.MaxLines != 2 || query.QueryType != "range" || reqBody.From != "now-15m" {
+			t.Errorf("unexpected query %+v", reqBody)
+		}
+		// the streams are returned in separate frames, the lines are merged newest first
+		_, _ = fmt.Fprint(w, `{"results": {"A": {"frames": [
+			{"schema": {"fields": [{"name": "Time", "type": "time"}, {"name": "Line", "type": "string"}]}, "data": {"values": [[1, 3], ["a1", "a3"]]}},
+			{"schema": {"fields": [{"name": "Time", "type": "time"}, {"name": "Line", "type": "string"}]}, "data": {"values": [[2], ["b2"]]}}
+		]}}}`)
+	}))
+	defer server.Close()
+	client := NewClientWithHttpClient(conf.GrafanaInstance{Host: server.URL}, server.Client())
+	logLines, err := client.QueryLoki(Datasource{Uid: "loki", Type: DatasourceTypeLoki}, `{app="gateway"}`, "now-15m", "now", 2)
+	if err != nil {
+		t.Fatalf("query loki err: %v", err)
+	}
+	if len(logLines) != 2 || logLines[0].Line != "a3" || logLines[1].Line != "b2" {
+		t.Errorf("log lines = %+v, want the newest 2 lines", logLines)
+	}
+}
@@ -1,11 +1,16 @@
 package grafana
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/jemygraw/grafana-copilot/conf"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,30 +22,317 @@ type Dashboard struct {
 	Uid   string `json:"uid"`
 	Title string `json:"title"`
 	URL   string `json:"url"`
+	// Type is dash-db for dashboards and dash-folder for folders
+	Type        string   `json:"type,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	FolderUid   string   `json:"folderUid,omitempty"`
+	FolderTitle string   `json:"folderTitle,omitempty"`
+	// Source is the name of the grafana instance, it is empty for the single instance
+	Source string `json:"source,omitempty"`
+	// OrgId is the org of the dashboard, it is 0 if the orgs are not configured
+	OrgId int `json:"orgId,omitempty"`
+	// Reason is filled by the copilot to explain why the dashboard matches the user input
+	Reason string `json:"reason,omitempty"`
 }
 
-// ListDashboardMeta list dashboards using grafana dashboard query api.
+// Key identifies the dashboard across the grafana instances and orgs, e.g. staging/abc123 or staging/2/abc123
+func (d *Dashboard) Key() string {
+	return CreateDashboardKey(d.Source, d.OrgId, d.Uid)
+}
+
+func CreateDashboardKey(source string, orgId int, uid string) string {
+	items := make([]string, 0, 3)
+	if source != "" {
+		items = append(items, source)
+	}
+	if orgId > 0 {
+		items = append(items, strconv.Itoa(orgId))
+	}
+	return strings.Join(append(items, uid), "/")
+}
+
+// searchPageLimit is the max page size of the grafana search api
+const searchPageLimit = 5000
+
+// DashboardSearchResult is the complete result of all the search pages
+type DashboardSearchResult struct {
+	Dashboards []Dashboard
+	// Total is the number of the dashboards matching the query
+	Total int
+	Pages int
+}
+
+// Client calls the openapi of one grafana instance
+type Client struct {
+	httpClient *http.Client
+	// renderHttpClient is used to render the panels which is much slower than the other apis
+	renderHttpClient *http.Client
+	// Name is the source label of the instance, it is empty for the single instance
+	Name    string
+	Host    string
+	BaseURL string
+	Token   string
+	Aliases []string
+	Orgs    []conf.GrafanaOrg
+	// metricNamesCache caches the metric names of the prometheus datasources by the org and uid
+	metricNamesCache map[string]metricNamesCacheEntry
+	metricNamesLock  sync.Mutex
+}
+
+func NewClient(instance conf.GrafanaInstance) *Client {
+	client := NewClientWithHttpClient(instance, &grafanaClient)
+	client.renderHttpClient = &grafanaRenderClient
+	return client
+}
+
+func NewClientWithHttpClient(instance conf.GrafanaInstance, httpClient *http.Client) *Client {
+	baseURL := instance.BaseURL
+	if baseURL == "" {
+		baseURL = instance.Host
+	}
+	return &Client{
+		httpClient:       httpClient,
+		renderHttpClient: httpClient,
+		Name:             instance.Name,
+		Host:             strings.TrimSuffix(instance.Host, "/"),
+		BaseURL:          strings.TrimSuffix(baseURL, "/"),
+		Token:            instance.Token,
+		Aliases:          instance.Aliases,
+		Orgs:             instance.Orgs,
+	}
+}
+
+// CreateURL creates the full access URL from the relative URL returned by the grafana api, the org id
+// is added so that the link opens in the right org
+func (c *Client) CreateURL(relativeURL string, orgId int) string {
+	fullURL := fmt.Sprintf("%s%s", c.BaseURL, relativeURL)
+	if orgId > 0 {
+		fullURL = AddURLParams(fullURL, url.Values{"orgId": {strconv.Itoa(orgId)}})
+	}
+	return fullURL
+}
+
+// GetOrgIds returns the configured org ids, the org id 0 means the default org of the token
+func (c *Client) GetOrgIds() (orgIds []int) {
+	if len(c.Orgs) == 0 {
+		return []int{0}
+	}
+	for _, org := range c.Orgs {
+		orgIds = append(orgIds, org.Id)
+	}
+	return
+}
+
+// SearchAllDashboards pages through the grafana dashboard query api of all the orgs until the last page,
+// since the api returns at most 1000 dashboards by default. The server may clamp the requested page size,
+// so the size of the first page is taken as the effective page size, and the paging stops on an empty
+// or a shorter page. The dashboards shifted between pages by concurrent changes are deduplicated by uid.
+func (c *Client) SearchAllDashboards(query string) (result DashboardSearchResult, err error) {
+	for _, orgId := range c.GetOrgIds() {
+		seen := make(map[string]bool)
+		var pageSize int
+		for page := 1; ; page++ {
+			var dashboardList []Dashboard
+			dashboardList, err = c.SearchDashboards(orgId, query, page, searchPageLimit)
+			if err != nil {
+				err = fmt.Errorf("search dashboards of org %d page %d err: %w", orgId, page, err)
+				return
+			}
+			result.Pages++
+			if len(dashboardList) == 0 {
+				break
+			}
+			var added int
+			for _, dashboard := range dashboardList {
+				if seen[dashboard.Uid] {
+					continue
+				}
+				seen[dashboard.Uid] = true
+				result.Dashboards = append(result.Dashboards, dashboard)
+				added++
+			}
+			if page == 1 {
+				pageSize = len(dashboardList)
+			}
+			// no new dashboards means the server ignores the page param
+			if len(dashboardList) < pageSize || added == 0 {
+				break
+			}
+		}
+	}
+	result.Total = len(result.Dashboards)
+	return
+}
+
+// SearchDashboards searches one page of the dashboards of the org using grafana dashboard query api, the page starts from 1.
 // See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/folder_dashboard_search/
-func ListDashboardMeta(query string) (dashboardList []Dashboard, err error) {
+func (c *Client) SearchDashboards(orgId int, query string, page, limit int) (dashboardList []Dashboard, err error) {
 	reqParams := url.Values{}
 	reqParams.Add("query", query)
 	reqParams.Add("type", "dash-db")
-	reqURL := fmt.Sprintf("%s/api/search?%s", conf.AppConfig.GrafanaHost, reqParams.Encode())
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	reqParams.Add("limit", strconv.Itoa(limit))
+	reqParams.Add("page", strconv.Itoa(page))
+	reqURL := fmt.Sprintf("%s/api/search?%s", c.Host, reqParams.Encode())
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &dashboardList); err != nil {
+		return
+	}
+	for index := range dashboardList {
+		dashboardList[index].Source = c.Name
+		dashboardList[index].OrgId = orgId
+	}
+	return
+}
+
+// GetDashboardByUid gets the dashboard json model by uid.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/dashboard/#get-dashboard-by-uid
+func (c *Client) GetDashboardByUid(orgId int, uid string) (dashboardDetail DashboardDetail, err error) {
+	reqURL := fmt.Sprintf("%s/api/dashboards/uid/%s", c.Host, url.PathEscape(uid))
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &dashboardDetail); err != nil {
+		return
+	}
+	dashboardDetail.Source = c.Name
+	dashboardDetail.OrgId = orgId
+	return
+}
+
+// DashboardVersion is an item of the dashboard versions api
+type DashboardVersion struct {
+	Version int    `json:"version"`
+	Created string `json:"created"`
+}
+
+// GetDashboardVersion gets the latest version of the dashboard, which is much lighter than the json model
+// and used to skip the unchanged dashboards. The api returns a list before grafana 11 and an object
+// with the versions and the continue token since grafana 11.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/dashboard_versions/
+func (c *Client) GetDashboardVersion(orgId int, uid string) (version int, err error) {
+	reqURL := fmt.Sprintf("%s/api/dashboards/uid/%s/versions?limit=1", c.Host, url.PathEscape(uid))
+	var respBody json.RawMessage
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &respBody); err != nil {
+		return
+	}
+	var versions []DashboardVersion
+	if trimmed := bytes.TrimSpace(respBody); len(trimmed) > 0 && trimmed[0] == '{' {
+		var versionList struct {
+			Versions []DashboardVersion `json:"versions"`
+		}
+		err = json.Unmarshal(trimmed, &versionList)
+		versions = versionList.Versions
+	} else {
+		err = json.Unmarshal(trimmed, &versions)
+	}
 	if err != nil {
-		err = fmt.Errorf("new grafana request err: %v", err)
+		err = fmt.Errorf("decode grafana dashboard versions err, %s", err.Error())
+		return
+	}
+	if len(versions) == 0 {
+		err = fmt.Errorf("no versions of dashboard %s", uid)
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", conf.AppConfig.GrafanaToken))
-	resp, err := grafanaClient.Do(req)
+	version = versions[0].Version
+	return
+}
+
+// maxErrorBodySize limits the error response body to read
+const maxErrorBodySize = 4096
+
+type grafanaErrorResponse struct {
+	Message string `json:"message"`
+	// Results are the query results of /api/ds/query, the query errors are returned in the results
+	Results map[string]struct {
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+// GetMessage returns the error message or the first query error
+func (r *grafanaErrorResponse) GetMessage() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	for _, result := range r.Results {
+		if result.Error != "" {
+			return result.Error
+		}
+	}
+	return ""
+}
+
+// callGrafanaAPI calls the api in the org and decodes the json response body
+func (c *Client) callGrafanaAPI(orgId int, method, reqURL string, respBody any) (err error) {
+	return c.callGrafanaAPIWithBody(orgId, method, reqURL, nil, respBody)
+}
+
+// callGrafanaAPIWithBody sends the request body in json and decodes the json response body if respBody is not nil
+func (c *Client) callGrafanaAPIWithBody(orgId int, method, reqURL string, reqBody, respBody any) (err error) {
+	resp, err := c.doGrafanaRequest(c.httpClient, orgId, method, reqURL, reqBody)
 	if err != nil {
-		err = fmt.Errorf("call grafana api err, %s", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if respBody == nil {
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
 		return
 	}
 	decoder := json.NewDecoder(resp.Body)
-	if err = decoder.Decode(&dashboardList); err != nil {
+	if err = decoder.Decode(respBody); err != nil {
 		err = fmt.Errorf("decode grafana api resp err, %s", err.Error())
 		return
 	}
 	return
 }
+
+// doGrafanaRequest sends the request in the org, the org token is used if configured, otherwise the
+// X-Grafana-Org-Id header is set to switch the org of the instance token. The response body should
+// be closed by the caller if no error is returned.
+func (c *Client) doGrafanaRequest(httpClient *http.Client, orgId int, method, reqURL string, reqBody any) (resp *http.Response, err error) {
+	var bodyReader io.Reader
+	if reqBody != nil {
+		var reqData []byte
+		if reqData, err = json.Marshal(reqBody); err != nil {
+			err = fmt.Errorf("encode grafana request body err: %v", err)
+			return
+		}
+		bodyReader = bytes.NewReader(reqData)
+	}
+	req, err := http.NewRequest(method, reqURL, bodyReader)
+	if err != nil {
+		err = fmt.Errorf("new grafana request err: %v", err)
+		return
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token := c.Token
+	if orgId > 0 {
+		req.Header.Set("X-Grafana-Org-Id", strconv.Itoa(orgId))
+		for _, org := range c.Orgs {
+			if org.Id == orgId && org.Token != "" {
+				token = org.Token
+			}
+		}
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("call grafana api err, %s", err.Error())
+		return
+	}
+	// the write apis may return 202, e.g. creating the alertmanager silences
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		// grafana returns the error message in json, e.g. {"message": "invalid API key"}
+		var errorResp grafanaErrorResponse
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodySize)).Decode(&errorResp)
+		// discard response body to reuse underline tcp connections
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if errorMessage := errorResp.GetMessage(); errorMessage != "" {
+			err = fmt.Errorf("call grafana api err, %s, %s", resp.Status, errorMessage)
+		} else {
+			err = fmt.Errorf("call grafana api err, %s", resp.Status)
+		}
+		resp = nil
+		return
+	}
+	return
+}
@@ -0,0 +1,108 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGetDashboardVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		body        string
+		wantVersion int
+		wantErr     bool
+	}{
+		{name: "list before grafana 11", status: http.StatusOK, body: `[{"version": 12, "created": "2024-01-01T00:00:00Z"}]`, wantVersion: 12},
+		{name: "object since grafana 11", status: http.StatusOK, body: `{"continueToken": "", "versions": [{"version": 3}]}`, wantVersion: 3},
+		{name: "no versions", status: http.StatusOK, body: `[]`, wantErr: true},
+		{name: "api error", status: http.StatusNotFound, body: `{"message": "Dashboard not found"}`, wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/dashboards/uid/abc/versions" || r.URL.Query().Get("limit") != "1" {
+					t.Errorf("unexpected request %s", r.URL.String())
+				}
+				w.WriteHeader(testCase.status)
+				_, _ = fmt.Fprint(w, testCase.body)
+			}))
+			defer server.Close()
+			client := NewClientWithHttpClient(conf.GrafanaInstance{Host: server.URL}, server.Client())
+			version, err := client.GetDashboardVersion(0, "abc")
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, testCase.wantErr)
+			}
+			if version != testCase.wantVersion {
+				t.Errorf("version = %d, want %d", version, testCase.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSearchAllDashboards(t *testing.T) {
+	testCases := []struct {
+		name string
+		// maxLimit is the page size clamped by the server, 0 means the requested limit is honoured
+		maxLimit  int
+		total     int
+		wantPages int
+	}{
+		{name: "single short page", total: 3, wantPages: 2},
+		{name: "empty", total: 0, wantPages: 1},
+		{name: "clamped page size", maxLimit: 1000, total: 2500, wantPages: 3},
+		{name: "clamped page size with exact pages", maxLimit: 1000, total: 2000, wantPages: 3},
+		{name: "full page", total: searchPageLimit, wantPages: 2},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				if testCase.maxLimit > 0 {
+					limit = min(limit, testCase.maxLimit)
+				}
+				dashboards := make([]Dashboard, 0)
+				for index := (page - 1) * limit; index < min(page*limit, testCase.total); index++ {
+					dashboards = append(dashboards, Dashboard{Uid: strconv.Itoa(index)})
+				}
+				_ = json.NewEncoder(w).Encode(dashboards)
+			}))
+			defer server.Close()
+			client := NewClientWithHttpClient(conf.GrafanaInstance{Host: server.URL}, server.Client())
+			result, err := client.SearchAllDashboards("")
+			if err != nil {
+				t.Fatalf("search all dashboards err: %v", err)
+			}
+			if result.Total != testCase.total || len(result.Dashboards) != testCase.total {
+				t.Errorf("total = %d, want %d", result.Total, testCase.total)
+			}
+			if result.Pages != testCase.wantPages {
+				t.Errorf("pages = %d, want %d", result.Pages, testCase.wantPages)
+			}
+		})
+	}
+}
+
+func TestSearchAllDashboardsIgnoredPage(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// the same page is returned whatever the page param is
+		_ = json.NewEncoder(w).Encode([]Dashboard{{Uid: "a"}, {Uid: "b"}})
+	}))
+	defer server.Close()
+	client := NewClientWithHttpClient(conf.GrafanaInstance{Host: server.URL}, server.Client())
+	result, err := client.SearchAllDashboards("")
+	if err != nil {
+		t.Fatalf("search all dashboards err: %v", err)
+	}
+	if result.Total != 2 || requests != 2 {
+		t.Errorf("total = %d, requests = %d, want 2 dashboards in 2 requests", result.Total, requests)
+	}
+}
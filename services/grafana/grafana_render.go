@@ -0,0 +1,77 @@
+package grafana
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRenderedImageSize limits the rendered image to read
+const maxRenderedImageSize = 20 * 1024 * 1024
+
+var grafanaRenderClient = http.Client{
+	Timeout: time.Second * 60,
+}
+
+// RenderPanel renders the panel opened by the panel deep link into a png image with the grafana image
+// renderer plugin. The time range and variables in the link are kept, e.g. https://grafana.example.com/d/abc/kafka?orgId=2&viewPanel=3&from=now-3h
+// is rendered by /render/d-solo/abc/kafka?orgId=2&panelId=3&from=now-3h.
+// See https://grafana.com/docs/grafana/latest/setup-grafana/image-rendering/
+func (c *Client) RenderPanel(orgId int, panelURL string, width, height int) (image []byte, err error) {
+	renderURL, err := c.createRenderURL(panelURL, width, height)
+	if err != nil {
+		return
+	}
+	resp, err := c.doGrafanaRequest(c.renderHttpClient, orgId, http.MethodGet, renderURL, nil)
+	if err != nil {
+		err = fmt.Errorf("render panel err, %w", err)
+		return
+	}
+	defer resp.Body.Close()
+	// grafana returns the login page with 200 if the renderer fails to authenticate
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/") {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err = fmt.Errorf("render panel err, unexpected content type %q, check the image renderer plugin", contentType)
+		return
+	}
+	image, err = io.ReadAll(io.LimitReader(resp.Body, maxRenderedImageSize))
+	if err != nil {
+		err = fmt.Errorf("read rendered image err, %s", err.Error())
+		return
+	}
+	return
+}
+
+func (c *Client) createRenderURL(panelURL string, width, height int) (renderURL string, err error) {
+	parsedURL, err := url.Parse(panelURL)
+	if err != nil {
+		err = fmt.Errorf("parse panel url err, %s", err.Error())
+		return
+	}
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		err = fmt.Errorf("parse grafana base url err, %s", err.Error())
+		return
+	}
+	dashboardPath, ok := strings.CutPrefix(strings.TrimPrefix(parsedURL.Path, baseURL.Path), "/d/")
+	if !ok {
+		err = fmt.Errorf("invalid panel url %s", panelURL)
+		return
+	}
+	reqParams := parsedURL.Query()
+	panelId := reqParams.Get("viewPanel")
+	if panelId == "" {
+		err = fmt.Errorf("no panel id in panel url %s", panelURL)
+		return
+	}
+	reqParams.Del("viewPanel")
+	reqParams.Set("panelId", panelId)
+	reqParams.Set("width", strconv.Itoa(width))
+	reqParams.Set("height", strconv.Itoa(height))
+	renderURL = fmt.Sprintf("%s/render/d-solo/%s?%s", c.Host, dashboardPath, reqParams.Encode())
+	return
+}
@@ -0,0 +1,54 @@
+package grafana
+
+import (
+	"github.com/jemygraw/grafana-copilot/conf"
+	"testing"
+)
+
+func TestCreateRenderURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		instance conf.GrafanaInstance
+		panelURL string
+		want     string
+		wantErr  bool
+	}{
+		{name: "same host", instance: conf.GrafanaInstance{Host: "https://grafana.example.com"},
+			panelURL: "https://grafana.example.com/d/abc/kafka?viewPanel=3",
+			want:     "https://grafana.example.com/render/d-solo/abc/kafka?height=500&panelId=3&width=1000"},
+		{name: "sub path base url with bare host", instance: conf.GrafanaInstance{Host: "http://grafana:3000", BaseURL: "https://x/grafana"},
+			panelURL: "https://x/grafana/d/abc/kafka?viewPanel=3",
+			want:     "http://grafana:3000/render/d-solo/abc/kafka?height=500&panelId=3&width=1000"},
+		{name: "sub path host", instance: conf.GrafanaInstance{Host: "http://grafana:3000/grafana", BaseURL: "https://x/grafana/"},
+			panelURL: "https://x/grafana/d/abc/kafka?viewPanel=3",
+			want:     "http://grafana:3000/grafana/render/d-solo/abc/kafka?height=500&panelId=3&width=1000"},
+		{name: "extra params kept", instance: conf.GrafanaInstance{Host: "http://grafana:3000", BaseURL: "https://x/grafana"},
+			panelURL: "https://x/grafana/d/abc/kafka?orgId=2&from=now-3h&to=now&var-env=prod&var-host=a&var-host=b&viewPanel=3",
+			want: "http://grafana:3000/render/d-solo/abc/kafka?from=now-3h&height=500&orgId=2&panelId=3&to=now" +
+				"&var-env=prod&var-host=a&var-host=b&width=1000"},
+		{name: "missing viewPanel", instance: conf.GrafanaInstance{Host: "https://grafana.example.com"},
+			panelURL: "https://grafana.example.com/d/abc/kafka?orgId=2", wantErr: true},
+		{name: "not a dashboard url", instance: conf.GrafanaInstance{Host: "https://grafana.example.com"},
+			panelURL: "https://grafana.example.com/explore?viewPanel=3", wantErr: true},
+		{name: "invalid url", instance: conf.GrafanaInstance{Host: "https://grafana.example.com"},
+			panelURL: "https://grafana.example.com/d/abc/%zz?viewPanel=3", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := NewClientWithHttpClient(testCase.instance, nil)
+			renderURL, err := client.createRenderURL(testCase.panelURL, 1000, 500)
+			if testCase.wantErr {
+				if err == nil {
+					t.Errorf("want error, got %s", renderURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create render url err: %v", err)
+			}
+			if renderURL != testCase.want {
+				t.Errorf("render url = %s, want %s", renderURL, testCase.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package grafana
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SilenceStateActive  = "active"
+	SilenceStatePending = "pending"
+	SilenceStateExpired = "expired"
+)
+
+// Silence is a silence of the grafana alertmanager
+type Silence struct {
+	Id        string           `json:"id,omitempty"`
+	Matchers  []SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+	Status    *SilenceStatus   `json:"status,omitempty"`
+	Source    string           `json:"-"`
+	OrgId     int              `json:"-"`
+}
+
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+func (m *SilenceMatcher) String() string {
+	var operator string
+	switch {
+	case m.IsEqual && !m.IsRegex:
+		operator = "="
+	case !m.IsEqual && !m.IsRegex:
+		operator = "!="
+	case m.IsEqual && m.IsRegex:
+		operator = "=~"
+	default:
+		operator = "!~"
+	}
+	return fmt.Sprintf("%s%s%s", m.Name, operator, m.Value)
+}
+
+type SilenceStatus struct {
+	// State is active, pending or expired
+	State string `json:"state"`
+}
+
+// GetState returns the state of the silence, it is empty for the silences not created yet
+func (s *Silence) GetState() string {
+	if s.Status == nil {
+		return ""
+	}
+	return s.Status.State
+}
+
+// FormatMatchers joins the matchers of the silence, e.g. service=order-service, severity!=info
+func (s *Silence) FormatMatchers() string {
+	items := make([]string, 0, len(s.Matchers))
+	for _, matcher := range s.Matchers {
+		items = append(items, matcher.String())
+	}
+	return strings.Join(items, ", ")
+}
+
+// Key identifies the silence across the grafana instances and orgs, e.g. staging/2/<silence id>
+func (s *Silence) Key() string {
+	return CreateDashboardKey(s.Source, s.OrgId, s.Id)
+}
+
+type createSilenceResponse struct {
+	SilenceId string `json:"silenceID"`
+}
+
+// ListSilences lists the silences of the org including the expired ones.
+// See https://grafana.com/docs/grafana/latest/developer-resources/api-reference/http-api/alerting_alertmanager/
+func (c *Client) ListSilences(orgId int) (silences []Silence, err error) {
+	reqURL := fmt.Sprintf("%s/api/alertmanager/grafana/api/v2/silences", c.Host)
+	if err = c.callGrafanaAPI(orgId, http.MethodGet, reqURL, &silences); err != nil {
+		err = fmt.Errorf("list silences err: %w", err)
+		return
+	}
+	for index := range silences {
+		silences[index].Source = c.Name
+		silences[index].OrgId = orgId
+	}
+	return
+}
+
+// CreateSilence creates the silence in the org and returns the silence id
+func (c *Client) CreateSilence(orgId int, silence Silence) (silenceId string, err error) {
+	reqURL := fmt.Sprintf("%s/api/alertmanager/grafana/api/v2/silences", c.Host)
+	var resp createSilenceResponse
+	if err = c.callGrafanaAPIWithBody(orgId, http.MethodPost, reqURL, &silence, &resp); err != nil {
+		err = fmt.Errorf("create silence err: %w", err)
+		return
+	}
+	silenceId = resp.SilenceId
+	return
+}
+
+// ExpireSilence expires the silence in the org, the expired silence is kept by the alertmanager for a while
+func (c *Client) ExpireSilence(orgId int, silenceId string) (err error) {
+	reqURL := fmt.Sprintf("%s/api/alertmanager/grafana/api/v2/silence/%s", c.Host, url.PathEscape(silenceId))
+	if err = c.callGrafanaAPIWithBody(orgId, http.MethodDelete, reqURL, nil, nil); err != nil {
+		err = fmt.Errorf("expire silence err: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,61 @@
+package grafana
+
+import (
+	"time"
+)
+
+const (
+	WebhookStatusFiring   = "firing"
+	WebhookStatusResolved = "resolved"
+)
+
+// WebhookPayload is the notification sent by the grafana webhook contact point.
+// See https://grafana.com/docs/grafana/latest/alerting/configure-notifications/manage-contact-points/integrations/webhook-notifier/
+type WebhookPayload struct {
+	Receiver string `json:"receiver"`
+	// Status is firing or resolved
+	Status            string            `json:"status"`
+	OrgId             int               `json:"orgId"`
+	Alerts            []WebhookAlert    `json:"alerts"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	GroupKey          string            `json:"groupKey"`
+	// TruncatedAlerts is the number of the alerts dropped by the max alerts setting of the contact point
+	TruncatedAlerts int    `json:"truncatedAlerts"`
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+// WebhookAlert is an alert in the grafana webhook notification
+type WebhookAlert struct {
+	// Status is firing or resolved
+	Status       string             `json:"status"`
+	Labels       map[string]string  `json:"labels"`
+	Annotations  map[string]string  `json:"annotations"`
+	StartsAt     time.Time          `json:"startsAt"`
+	EndsAt       time.Time          `json:"endsAt"`
+	GeneratorURL string             `json:"generatorURL"`
+	Fingerprint  string             `json:"fingerprint"`
+	SilenceURL   string             `json:"silenceURL"`
+	DashboardURL string             `json:"dashboardURL"`
+	PanelURL     string             `json:"panelURL"`
+	Values       map[string]float64 `json:"values"`
+	ValueString  string             `json:"valueString"`
+	// Dashboards is filled by the copilot with the dashboards relevant to the alert
+	Dashboards []Dashboard `json:"-"`
+}
+
+// GetName returns the alert rule name of the alert
+func (a *WebhookAlert) GetName() string {
+	return a.Labels[AlertNameLabel]
+}
+
+// GetSummary returns the summary annotation, or the description if the summary is not set
+func (a *WebhookAlert) GetSummary() string {
+	if summary := a.Annotations["summary"]; summary != "" {
+		return summary
+	}
+	return a.Annotations["description"]
+}
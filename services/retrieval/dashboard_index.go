@@ -0,0 +1,321 @@
+package retrieval
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// embeddingBatchSize is the max number of texts sent in one embedding request
+const embeddingBatchSize = 64
+
+// EmbedFunc creates the embeddings of the texts, one vector per text
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Document is an indexed dashboard or panel, the panel id is 0 for the dashboard document
+type Document struct {
+	Id string `json:"id"`
+	// DashboardUid is the dashboard key prefixed by the grafana instance name for the multiple instances
+	DashboardUid string    `json:"dashboardUid"`
+	PanelId      int       `json:"panelId,omitempty"`
+	Text         string    `json:"text"`
+	Hash         string    `json:"hash"`
+	Vector       []float32 `json:"vector"`
+	// Version is the version of the dashboard json model which the document is built from, it is 0 if unknown
+	Version int `json:"version,omitempty"`
+	// Title is the panel title which is weighted higher in the keyword matching, it is empty for the dashboard
+	Title string `json:"title,omitempty"`
+}
+
+type SearchResult struct {
+	Document *Document
+	Score    float64
+}
+
+// Index is a local vector index of the dashboard and panel metadata persisted in a json file
+type Index struct {
+	lock      sync.RWMutex
+	path      string
+	model     string
+	documents map[string]*Document
+	// panelIndex is the keyword index of the panel documents, it is rebuilt when the documents change
+	panelIndex *LexicalIndex
+	updatedAt  time.Time
+}
+
+type indexFile struct {
+	Model     string      `json:"model"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Documents []*Document `json:"documents"`
+}
+
+func NewIndex(path, model string) *Index {
+	return &Index{
+		path:       path,
+		model:      model,
+		documents:  make(map[string]*Document),
+		panelIndex: NewLexicalIndex(nil),
+	}
+}
+
+// NewDocument creates the document with the content hash which is used to skip the unchanged documents
+func NewDocument(dashboardUid string, panelId int, text string) Document {
+	id := dashboardUid
+	if panelId > 0 {
+		id = fmt.Sprintf("%s/%d", dashboardUid, panelId)
+	}
+	sum := sha1.Sum([]byte(text))
+	return Document{
+		Id:           id,
+		DashboardUid: dashboardUid,
+		PanelId:      panelId,
+		Text:         text,
+		Hash:         hex.EncodeToString(sum[:]),
+	}
+}
+
+// Load loads the index from disk, it is not an error if the file does not exist.
+// The persisted documents are dropped if they are created by another embedding model.
+func (idx *Index) Load() (err error) {
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	var file indexFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		err = fmt.Errorf("parse index file err: %w", err)
+		return
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if file.Model != idx.model {
+		return
+	}
+	idx.documents = make(map[string]*Document, len(file.Documents))
+	for _, document := range file.Documents {
+		idx.documents[document.Id] = document
+	}
+	idx.panelIndex = createPanelIndex(idx.documents)
+	idx.updatedAt = file.UpdatedAt
+	return
+}
+
+// Save writes the index to a temp file and renames it, so that a crash never leaves a broken index
+func (idx *Index) Save() (err error) {
+	idx.lock.RLock()
+	file := indexFile{
+		Model:     idx.model,
+		UpdatedAt: idx.updatedAt,
+		Documents: make([]*Document, 0, len(idx.documents)),
+	}
+	for _, document := range idx.documents {
+		file.Documents = append(file.Documents, document)
+	}
+	idx.lock.RUnlock()
+	sort.Slice(file.Documents, func(i, j int) bool {
+		return file.Documents[i].Id < file.Documents[j].Id
+	})
+	data, err := json.Marshal(&file)
+	if err != nil {
+		err = fmt.Errorf("marshal index err: %w", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		err = fmt.Errorf("create index dir err: %w", err)
+		return
+	}
+	tmpPath := idx.path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		err = fmt.Errorf("write index file err: %w", err)
+		return
+	}
+	if err = os.Rename(tmpPath, idx.path); err != nil {
+		err = fmt.Errorf("rename index file err: %w", err)
+		return
+	}
+	return
+}
+
+// Size returns the number of the indexed documents
+func (idx *Index) Size() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return len(idx.documents)
+}
+
+// DashboardDocuments returns the indexed dashboard documents by the dashboard keys, the vectors are omitted
+func (idx *Index) DashboardDocuments() map[string]Document {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	documents := make(map[string]Document)
+	for _, document := range idx.documents {
+		if document.PanelId == 0 {
+			dashboardDocument := *document
+			dashboardDocument.Vector = nil
+			documents[document.DashboardUid] = dashboardDocument
+		}
+	}
+	return documents
+}
+
+func (idx *Index) UpdatedAt() time.Time {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return idx.updatedAt
+}
+
+// Update replaces the indexed documents with the given ones incrementally, only the new and changed
+// documents are embedded. The existing documents of the keepDashboardUids are kept if they are not
+// given, which is used when the dashboard json model fails to be fetched.
+func (idx *Index) Update(ctx context.Context, documents []Document, keepDashboardUids map[string]bool,
+	embed EmbedFunc) (embedded, removed int, err error) {
+	idx.lock.RLock()
+	newDocuments := make(map[string]*Document, len(documents))
+	toEmbed := make([]*Document, 0)
+	for index := range documents {
+		document := documents[index]
+		if existing, ok := idx.documents[document.Id]; ok && existing.Hash == document.Hash {
+			// keep the vector, the other fields may be missing in the documents persisted by the old versions
+			document.Vector = existing.Vector
+			newDocuments[document.Id] = &document
+			continue
+		}
+		newDocuments[document.Id] = &document
+		toEmbed = append(toEmbed, &document)
+	}
+	for id, existing := range idx.documents {
+		if _, ok := newDocuments[id]; ok {
+			continue
+		}
+		if keepDashboardUids[existing.DashboardUid] {
+			newDocuments[id] = existing
+			continue
+		}
+		removed++
+	}
+	idx.lock.RUnlock()
+	// embed the new and changed documents in batches
+	for start := 0; start < len(toEmbed); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(toEmbed))
+		texts := make([]string, 0, end-start)
+		for _, document := range toEmbed[start:end] {
+			texts = append(texts, document.Text)
+		}
+		vectors, embedErr := embed(ctx, texts)
+		if embedErr != nil {
+			err = fmt.Errorf("embed documents err: %w", embedErr)
+			return
+		}
+		for index, document := range toEmbed[start:end] {
+			document.Vector = normalize(vectors[index])
+		}
+	}
+	embedded = len(toEmbed)
+	panelIndex := createPanelIndex(newDocuments)
+	idx.lock.Lock()
+	idx.documents = newDocuments
+	idx.panelIndex = panelIndex
+	idx.updatedAt = time.Now()
+	idx.lock.Unlock()
+	return
+}
+
+// Search returns the topK documents most similar to the vector by cosine similarity
+func (idx *Index) Search(vector []float32, topK int) (results []SearchResult) {
+	return idx.search(vector, topK, false)
+}
+
+// SearchPanels is the same as Search but only the panel documents are returned
+func (idx *Index) SearchPanels(vector []float32, topK int) (results []SearchResult) {
+	return idx.search(vector, topK, true)
+}
+
+// SearchPanelsLexically returns the topK panel documents matching the keywords of the query by BM25
+func (idx *Index) SearchPanelsLexically(query string, topK int) (results []SearchResult) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	for _, result := range idx.panelIndex.Search(query, topK) {
+		if document, ok := idx.documents[result.Id]; ok {
+			results = append(results, SearchResult{Document: document, Score: result.Score})
+		}
+	}
+	return
+}
+
+func (idx *Index) search(vector []float32, topK int, panelOnly bool) (results []SearchResult) {
+	vector = normalize(vector)
+	idx.lock.RLock()
+	results = make([]SearchResult, 0, len(idx.documents))
+	for _, document := range idx.documents {
+		if len(document.Vector) != len(vector) || (panelOnly && document.PanelId == 0) {
+			continue
+		}
+		results = append(results, SearchResult{Document: document, Score: dot(vector, document.Vector)})
+	}
+	idx.lock.RUnlock()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return
+}
+
+// createPanelIndex indexes the panel documents by keywords, the panel title is weighted higher than
+// the other fields in the text, e.g. the dashboard title and the queries
+func createPanelIndex(documents map[string]*Document) *LexicalIndex {
+	lexicalDocuments := make([]LexicalDocument, 0, len(documents))
+	for _, document := range documents {
+		if document.PanelId == 0 {
+			continue
+		}
+		lexicalDocuments = append(lexicalDocuments, LexicalDocument{
+			Id: document.Id,
+			Fields: []LexicalField{
+				{Text: document.Title, Weight: 2},
+				{Text: document.Text, Weight: 1},
+			},
+		})
+	}
+	// sort the documents so that the results of the same score are in a stable order
+	sort.Slice(lexicalDocuments, func(i, j int) bool {
+		return lexicalDocuments[i].Id < lexicalDocuments[j].Id
+	})
+	return NewLexicalIndex(lexicalDocuments)
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	normalized := make([]float32, len(vector))
+	for index, v := range vector {
+		normalized[index] = v / norm
+	}
+	return normalized
+}
+
+func dot(a, b []float32) (sum float64) {
+	for index := range a {
+		sum += float64(a[index]) * float64(b[index])
+	}
+	return
+}
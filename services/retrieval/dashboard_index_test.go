@@ -0,0 +1,109 @@
+package retrieval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexUpdate(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "index.json"), "test")
+	var embeddedTexts []string
+	embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+		embeddedTexts = append(embeddedTexts, texts...)
+		return noEmbed(ctx, texts)
+	}
+	initial := []Document{
+		NewDocument("a", 0, "dashboard a"),
+		NewDocument("a", 1, "panel a1"),
+		NewDocument("b", 0, "dashboard b"),
+		NewDocument("b", 1, "panel b1"),
+	}
+	if _, _, err := idx.Update(context.Background(), initial, nil, embed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	testCases := []struct {
+		name         string
+		documents    []Document
+		keep         map[string]bool
+		wantEmbedded []string
+		wantRemoved  int
+		wantIds      []string
+	}{
+		{
+			name: "unchanged documents are not embedded",
+			documents: []Document{NewDocument("a", 0, "dashboard a"), NewDocument("a", 1, "panel a1 changed"),
+				NewDocument("b", 0, "dashboard b"), NewDocument("b", 1, "panel b1")},
+			wantEmbedded: []string{"panel a1 changed"},
+			wantIds:      []string{"a", "a/1", "b", "b/1"},
+		},
+		{
+			name:        "kept dashboards keep their panels",
+			documents:   []Document{NewDocument("a", 0, "dashboard a"), NewDocument("b", 0, "dashboard b")},
+			keep:        map[string]bool{"b": true},
+			wantRemoved: 1,
+			wantIds:     []string{"a", "b", "b/1"},
+		},
+		{
+			name:        "removed dashboards",
+			documents:   []Document{NewDocument("a", 0, "dashboard a")},
+			wantRemoved: 2,
+			wantIds:     []string{"a"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			embeddedTexts = nil
+			embedded, removed, err := idx.Update(context.Background(), testCase.documents, testCase.keep, embed)
+			if err != nil {
+				t.Fatalf("update index err: %v", err)
+			}
+			if embedded != len(testCase.wantEmbedded) || len(embeddedTexts) != len(testCase.wantEmbedded) {
+				t.Errorf("embedded = %v, want %v", embeddedTexts, testCase.wantEmbedded)
+			}
+			for index, text := range testCase.wantEmbedded {
+				if index < len(embeddedTexts) && embeddedTexts[index] != text {
+					t.Errorf("embedded text %d = %q, want %q", index, embeddedTexts[index], text)
+				}
+			}
+			if removed != testCase.wantRemoved {
+				t.Errorf("removed = %d, want %d", removed, testCase.wantRemoved)
+			}
+			if idx.Size() != len(testCase.wantIds) {
+				t.Errorf("size = %d, want %d", idx.Size(), len(testCase.wantIds))
+			}
+			for _, id := range testCase.wantIds {
+				if document, ok := idx.documents[id]; !ok || len(document.Vector) == 0 {
+					t.Errorf("document %s missing or not embedded", id)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexDashboardDocuments(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "index.json"), "test")
+	dashboard := NewDocument("a", 0, "dashboard a")
+	dashboard.Version = 7
+	panel := NewDocument("a", 1, "panel a1")
+	panel.Version = 6
+	if _, _, err := idx.Update(context.Background(), []Document{dashboard, panel, NewDocument("b", 0, "dashboard b")},
+		nil, noEmbed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	documents := idx.DashboardDocuments()
+	if len(documents) != 2 || documents["a"].Version != 7 || documents["a"].Hash != dashboard.Hash {
+		t.Errorf("documents = %v, want the dashboard documents", documents)
+	}
+	if documents["a"].Vector != nil {
+		t.Errorf("vector of the dashboard document is not omitted")
+	}
+	// the version is updated even if the text is unchanged
+	dashboard.Version = 8
+	if _, _, err := idx.Update(context.Background(), []Document{dashboard}, map[string]bool{"a": true}, noEmbed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	if documents = idx.DashboardDocuments(); documents["a"].Version != 8 {
+		t.Errorf("version = %d, want the updated version", documents["a"].Version)
+	}
+}
@@ -0,0 +1,237 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	ernie "github.com/jemygraw/grafana-copilot/services/ernine"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fetchConcurrency limits the concurrent requests to fetch the dashboard json models
+	fetchConcurrency = 8
+	// rrfRankConstant is the common constant of the reciprocal rank fusion, which damps the top ranks
+	rrfRankConstant = 60
+)
+
+var defaultIndex *Index
+var defaultIndexOnce sync.Once
+
+// rebuildLock prevents the concurrent rebuilds of the default index
+var rebuildLock sync.Mutex
+
+// IsEnabled checks whether the embedding based retrieval is enabled
+func IsEnabled() bool {
+	return conf.AppConfig.OpenAIEmbeddingModel != ""
+}
+
+// GetIndex returns the default index, which is loaded from disk on first use
+func GetIndex() *Index {
+	defaultIndexOnce.Do(func() {
+		defaultIndex = NewIndex(conf.AppConfig.CopilotIndexPath, conf.AppConfig.OpenAIEmbeddingModel)
+		if err := defaultIndex.Load(); err != nil {
+			slog.Error(fmt.Sprintf("load dashboard index err: %v", err))
+		}
+	})
+	return defaultIndex
+}
+
+// StartIndexer rebuilds the default index in background immediately and then on every refresh interval
+func StartIndexer(ctx context.Context) {
+	interval := time.Duration(conf.AppConfig.CopilotIndexRefreshInterval) * time.Second
+	go func() {
+		for {
+			if err := RebuildIndex(ctx); err != nil {
+				slog.Error(fmt.Sprintf("rebuild dashboard index err: %v", err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// RebuildIndex lists all the dashboards and their panels, and updates the default index incrementally
+func RebuildIndex(ctx context.Context) (err error) {
+	rebuildLock.Lock()
+	defer rebuildLock.Unlock()
+	startTime := time.Now()
+	dashboards, err := grafana.ListCatalogDashboards()
+	if err != nil {
+		err = fmt.Errorf("list grafana dashboard metas err: %v", err)
+		return
+	}
+	index := GetIndex()
+	documents, failedUids, unchangedUids := BuildDocuments(dashboards, index.DashboardDocuments())
+	keepUids := make(map[string]bool, len(failedUids)+len(unchangedUids))
+	for _, uids := range []map[string]bool{failedUids, unchangedUids} {
+		for uid := range uids {
+			keepUids[uid] = true
+		}
+	}
+	embedded, removed, err := index.Update(ctx, documents, keepUids, func(ctx context.Context, texts []string) ([][]float32, error) {
+		return ernie.GetEmbeddings(ctx, conf.AppConfig, texts)
+	})
+	if err != nil {
+		return
+	}
+	if err = index.Save(); err != nil {
+		return
+	}
+	slog.Info(fmt.Sprintf("dashboard index rebuilt, size: %d, embedded: %d, removed: %d, unchanged: %d, failed: %d, cost: %s",
+		index.Size(), embedded, removed, len(unchangedUids), len(failedUids), time.Since(startTime)))
+	return
+}
+
+// BuildDocuments creates one document for each dashboard and each of its panels. The dashboards whose
+// versions and search metas are the same as the indexed ones are not fetched again, only their dashboard
+// documents are created. Both the unchanged dashboards and the ones whose json models fail to be fetched are returned,
+// so that their panels are kept in the index.
+func BuildDocuments(dashboards []grafana.Dashboard, indexedDocuments map[string]Document) (documents []Document,
+	failedUids, unchangedUids map[string]bool) {
+	failedUids = make(map[string]bool)
+	unchangedUids = make(map[string]bool)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, fetchConcurrency)
+	for _, dashboard := range dashboards {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(dashboard grafana.Dashboard) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			dashboardDocument := NewDocument(dashboard.Key(), 0, createDashboardText(dashboard))
+			// the panel documents contain the dashboard title and folder, which may change without a new version
+			indexedDocument := indexedDocuments[dashboard.Key()]
+			if indexedDocument.Hash == dashboardDocument.Hash {
+				dashboardDocument.Version = indexedDocument.Version
+			}
+			if dashboardDocument.Version > 0 {
+				version, err := grafana.GetDashboardVersion(dashboard.Source, dashboard.OrgId, dashboard.Uid)
+				if err != nil {
+					// the versions api may be disabled, fallback to fetch the json model
+					slog.Debug(fmt.Sprintf("get grafana dashboard %s version err: %v", dashboard.Key(), err))
+				} else if version == dashboardDocument.Version {
+					lock.Lock()
+					unchangedUids[dashboard.Key()] = true
+					documents = append(documents, dashboardDocument)
+					lock.Unlock()
+					return
+				}
+			}
+			dashboardDetail, err := grafana.GetDashboard(dashboard.Source, dashboard.OrgId, dashboard.Uid)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				slog.Error(fmt.Sprintf("get grafana dashboard %s err: %v", dashboard.Key(), err))
+				failedUids[dashboard.Key()] = true
+				documents = append(documents, dashboardDocument)
+				return
+			}
+			dashboardDocument.Version = dashboardDetail.Dashboard.Version
+			documents = append(documents, dashboardDocument)
+			for _, panel := range dashboardDetail.Dashboard.GetPanels() {
+				panelDocument := createPanelDocument(dashboard, panel)
+				panelDocument.Version = dashboardDetail.Dashboard.Version
+				documents = append(documents, panelDocument)
+			}
+		}(dashboard)
+	}
+	wg.Wait()
+	return
+}
+
+// RetrieveDashboardKeys retrieves the topK documents of the query and returns their dashboard keys in rank order
+func RetrieveDashboardKeys(ctx context.Context, query string, topK int) (dashboardKeys []string, err error) {
+	vectors, err := ernie.GetEmbeddings(ctx, conf.AppConfig, []string{query})
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, result := range GetIndex().Search(vectors[0], topK) {
+		if seen[result.Document.DashboardUid] {
+			continue
+		}
+		seen[result.Document.DashboardUid] = true
+		dashboardKeys = append(dashboardKeys, result.Document.DashboardUid)
+	}
+	return
+}
+
+// RetrievePanels ranks the indexed panels of the query by keywords and by the embeddings, the two rankings
+// are merged by the reciprocal rank fusion, so that the panels found by both are ranked first. The index is
+// only built when the embedding model is set, so the lexical mode never gets here and matches the panels
+// of the dashboards found by keywords instead.
+func RetrievePanels(ctx context.Context, query string, topK int) (results []SearchResult, err error) {
+	vectors, err := ernie.GetEmbeddings(ctx, conf.AppConfig, []string{query})
+	if err != nil {
+		return
+	}
+	index := GetIndex()
+	results = fuseRankings([][]SearchResult{index.SearchPanelsLexically(query, topK), index.SearchPanels(vectors[0], topK)}, topK)
+	return
+}
+
+// fuseRankings merges the rankings by the reciprocal rank fusion, the score of a document is the sum
+// of 1/(k+rank) of all the rankings which it appears in
+func fuseRankings(rankings [][]SearchResult, topK int) (results []SearchResult) {
+	resultMap := make(map[string]*SearchResult)
+	for _, ranking := range rankings {
+		for rank, result := range ranking {
+			fused, ok := resultMap[result.Document.Id]
+			if !ok {
+				fused = &SearchResult{Document: result.Document}
+				resultMap[result.Document.Id] = fused
+			}
+			fused.Score += 1 / float64(rrfRankConstant+rank+1)
+		}
+	}
+	results = make([]SearchResult, 0, len(resultMap))
+	for _, result := range resultMap {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Document.Id < results[j].Document.Id
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return
+}
+
+func createPanelDocument(dashboard grafana.Dashboard, panel grafana.Panel) Document {
+	document := NewDocument(dashboard.Key(), panel.Id, createPanelText(dashboard, panel))
+	document.Title = panel.Title
+	return document
+}
+
+func createDashboardText(dashboard grafana.Dashboard) string {
+	return fmt.Sprintf("%sDashboard: %s\nFolder: %s\nTags: %s", createSourceText(dashboard), dashboard.Title,
+		dashboard.FolderTitle, strings.Join(dashboard.Tags, ","))
+}
+
+func createPanelText(dashboard grafana.Dashboard, panel grafana.Panel) string {
+	return fmt.Sprintf("%sDashboard: %s\nFolder: %s\nPanel: %s\nDescription: %s\nQueries: %s", createSourceText(dashboard),
+		dashboard.Title, dashboard.FolderTitle, panel.Title, panel.Description, strings.Join(panel.GetQueries(), "; "))
+}
+
+// createSourceText keeps the text of the single instance unchanged, so that it is not embedded again
+func createSourceText(dashboard grafana.Dashboard) string {
+	if dashboard.Source == "" {
+		return ""
+	}
+	return fmt.Sprintf("Source: %s\n", dashboard.Source)
+}
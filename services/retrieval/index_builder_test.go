@@ -0,0 +1,108 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jemygraw/grafana-copilot/conf"
+	"github.com/jemygraw/grafana-copilot/services/grafana"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBuildDocuments(t *testing.T) {
+	// the current versions of the dashboards, the json model of "broken" fails to be fetched
+	versions := map[string]int{"same": 3, "changed": 2, "new": 1, "broken": 5}
+	var lock sync.Mutex
+	fetched := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")
+		if uid, ok := strings.CutSuffix(path, "/versions"); ok {
+			// grafana 11 wraps the versions in an object
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"versions": []grafana.DashboardVersion{{Version: versions[uid]}},
+			})
+			return
+		}
+		lock.Lock()
+		fetched[path]++
+		lock.Unlock()
+		if path == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"dashboard": map[string]any{
+				"uid":     path,
+				"title":   path,
+				"version": versions[path],
+				"panels":  []map[string]any{{"id": 1, "title": fmt.Sprintf("%s panel", path)}},
+			},
+		})
+	}))
+	defer server.Close()
+	conf.AppConfig = &conf.Config{GrafanaInstances: []conf.GrafanaInstance{{Host: server.URL}}}
+	dashboards := []grafana.Dashboard{{Uid: "same", Title: "same"}, {Uid: "changed", Title: "changed"},
+		{Uid: "new", Title: "new"}, {Uid: "broken", Title: "broken"}, {Uid: "moved", Title: "moved", FolderTitle: "new folder"}}
+	indexedDocuments := make(map[string]Document)
+	for uid, version := range map[string]int{"same": 3, "changed": 1, "broken": 4} {
+		document := NewDocument(uid, 0, createDashboardText(grafana.Dashboard{Uid: uid, Title: uid}))
+		document.Version = version
+		indexedDocuments[uid] = document
+	}
+	// the version is unchanged but the dashboard is moved to another folder
+	versions["moved"] = 6
+	movedDocument := NewDocument("moved", 0, createDashboardText(grafana.Dashboard{Uid: "moved", Title: "moved"}))
+	movedDocument.Version = 6
+	indexedDocuments["moved"] = movedDocument
+	documents, failedUids, unchangedUids := BuildDocuments(dashboards, indexedDocuments)
+	if fetched["same"] != 0 {
+		t.Errorf("unchanged dashboard fetched %d times", fetched["same"])
+	}
+	for _, uid := range []string{"changed", "new", "broken", "moved"} {
+		if fetched[uid] != 1 {
+			t.Errorf("dashboard %s fetched %d times, want 1", uid, fetched[uid])
+		}
+	}
+	if !unchangedUids["same"] || len(unchangedUids) != 1 {
+		t.Errorf("unchangedUids = %v, want same", unchangedUids)
+	}
+	if !failedUids["broken"] || len(failedUids) != 1 {
+		t.Errorf("failedUids = %v, want broken", failedUids)
+	}
+	documentMap := make(map[string]Document)
+	for _, document := range documents {
+		documentMap[document.Id] = document
+	}
+	testCases := []struct {
+		id          string
+		wantVersion int
+		wantExists  bool
+	}{
+		{id: "same", wantVersion: 3, wantExists: true},
+		{id: "same/1", wantExists: false},
+		{id: "changed", wantVersion: 2, wantExists: true},
+		{id: "changed/1", wantVersion: 2, wantExists: true},
+		{id: "new", wantVersion: 1, wantExists: true},
+		{id: "new/1", wantVersion: 1, wantExists: true},
+		// the indexed version is kept, so that the dashboard is fetched again next time
+		{id: "broken", wantVersion: 4, wantExists: true},
+		{id: "broken/1", wantExists: false},
+		{id: "moved/1", wantVersion: 6, wantExists: true},
+	}
+	for _, testCase := range testCases {
+		document, ok := documentMap[testCase.id]
+		if ok != testCase.wantExists {
+			t.Errorf("document %s exists = %v, want %v", testCase.id, ok, testCase.wantExists)
+			continue
+		}
+		if ok && document.Version != testCase.wantVersion {
+			t.Errorf("document %s version = %d, want %d", testCase.id, document.Version, testCase.wantVersion)
+		}
+	}
+	if documentMap["changed/1"].Title != "changed panel" {
+		t.Errorf("panel title = %q, want the panel title", documentMap["changed/1"].Title)
+	}
+}
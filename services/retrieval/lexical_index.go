@@ -0,0 +1,251 @@
+package retrieval
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// bm25K1 and bm25B are the common BM25 parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// fuzzyTermWeight discounts the terms matched by prefix or edit distance instead of exactly
+	fuzzyTermWeight = 0.6
+	// minFuzzyTermLength is the min length of the query term to do the fuzzy matching
+	minFuzzyTermLength = 3
+)
+
+// LexicalDocument is a document to be matched by keywords, the weight of each field is the
+// times its terms are counted, e.g. the title is weighted higher than the folder.
+type LexicalDocument struct {
+	Id     string
+	Fields []LexicalField
+}
+
+type LexicalField struct {
+	Text   string
+	Weight int
+}
+
+type LexicalResult struct {
+	Id    string
+	Score float64
+	// MatchedTerms are the document terms matched by the query
+	MatchedTerms []string
+}
+
+// LexicalIndex is an in-memory BM25 index with fuzzy term matching, which works without any llm
+type LexicalIndex struct {
+	ids           []string
+	termFreqs     []map[string]int
+	docLengths    []int
+	avgDocLength  float64
+	docFreqs      map[string]int
+	sortedVocabs  []string
+	documentCount int
+}
+
+type weightedTerm struct {
+	term   string
+	weight float64
+}
+
+func NewLexicalIndex(documents []LexicalDocument) *LexicalIndex {
+	idx := &LexicalIndex{
+		ids:           make([]string, 0, len(documents)),
+		termFreqs:     make([]map[string]int, 0, len(documents)),
+		docLengths:    make([]int, 0, len(documents)),
+		docFreqs:      make(map[string]int),
+		documentCount: len(documents),
+	}
+	var totalLength int
+	for _, document := range documents {
+		termFreq := make(map[string]int)
+		var docLength int
+		for _, field := range document.Fields {
+			weight := max(field.Weight, 1)
+			for _, term := range Tokenize(field.Text) {
+				termFreq[term] += weight
+				docLength += weight
+			}
+		}
+		for term := range termFreq {
+			idx.docFreqs[term]++
+		}
+		idx.ids = append(idx.ids, document.Id)
+		idx.termFreqs = append(idx.termFreqs, termFreq)
+		idx.docLengths = append(idx.docLengths, docLength)
+		totalLength += docLength
+	}
+	if len(documents) > 0 {
+		idx.avgDocLength = float64(totalLength) / float64(len(documents))
+	}
+	idx.sortedVocabs = make([]string, 0, len(idx.docFreqs))
+	for term := range idx.docFreqs {
+		idx.sortedVocabs = append(idx.sortedVocabs, term)
+	}
+	sort.Strings(idx.sortedVocabs)
+	return idx
+}
+
+// Search scores the documents by BM25, the query terms not in the index are matched to the
+// similar terms by prefix or edit distance, so that the typos and abbreviations still work.
+func (idx *LexicalIndex) Search(query string, topK int) (results []LexicalResult) {
+	if idx.documentCount == 0 || idx.avgDocLength == 0 {
+		return
+	}
+	queryTerms := make([]weightedTerm, 0)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		queryTerms = append(queryTerms, idx.expandTerm(term)...)
+	}
+	for docIndex, termFreq := range idx.termFreqs {
+		var score float64
+		var matchedTerms []string
+		for _, queryTerm := range queryTerms {
+			tf, ok := termFreq[queryTerm.term]
+			if !ok {
+				continue
+			}
+			docFreq := float64(idx.docFreqs[queryTerm.term])
+			idf := math.Log(1 + (float64(idx.documentCount)-docFreq+0.5)/(docFreq+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(idx.docLengths[docIndex])/idx.avgDocLength)
+			score += queryTerm.weight * idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+			matchedTerms = append(matchedTerms, queryTerm.term)
+		}
+		if score > 0 {
+			results = append(results, LexicalResult{
+				Id:           idx.ids[docIndex],
+				Score:        score,
+				MatchedTerms: matchedTerms,
+			})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return
+}
+
+// expandTerm returns the term itself if it is indexed, otherwise the indexed terms with the
+// term as prefix or within the max edit distance are returned with a discounted weight.
+func (idx *LexicalIndex) expandTerm(term string) (terms []weightedTerm) {
+	if _, ok := idx.docFreqs[term]; ok {
+		return []weightedTerm{{term: term, weight: 1}}
+	}
+	runes := []rune(term)
+	if len(runes) < minFuzzyTermLength || isHanTerm(runes) {
+		return
+	}
+	maxDistance := 1
+	if len(runes) >= 8 {
+		maxDistance = 2
+	}
+	// the vocabs are sorted, so that the ones with the prefix are adjacent
+	start := sort.SearchStrings(idx.sortedVocabs, term)
+	for _, vocab := range idx.sortedVocabs[start:] {
+		if !strings.HasPrefix(vocab, term) {
+			break
+		}
+		terms = append(terms, weightedTerm{term: vocab, weight: fuzzyTermWeight})
+	}
+	if len(terms) > 0 {
+		return
+	}
+	for _, vocab := range idx.sortedVocabs {
+		vocabRunes := []rune(vocab)
+		if isHanTerm(vocabRunes) || abs(len(vocabRunes)-len(runes)) > maxDistance {
+			continue
+		}
+		if editDistance(runes, vocabRunes) <= maxDistance {
+			terms = append(terms, weightedTerm{term: vocab, weight: fuzzyTermWeight})
+		}
+	}
+	return
+}
+
+// Tokenize splits the text into lower case terms. The latin letters and digits are split into words
+// by the other characters, and the chinese characters are split into unigrams and bigrams since
+// there are no spaces between the chinese words, e.g. "订单延迟" is split into 订, 单, 延, 迟, 订单,
+// 单延 and 延迟, so that both "订单" and "延迟" match it.
+func Tokenize(text string) (terms []string) {
+	var word []rune
+	var hanRun []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushHanRun := func() {
+		for index := range hanRun {
+			terms = append(terms, string(hanRun[index]))
+			if index+1 < len(hanRun) {
+				terms = append(terms, string(hanRun[index:index+2]))
+			}
+		}
+		hanRun = hanRun[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			hanRun = append(hanRun, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHanRun()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHanRun()
+		}
+	}
+	flushWord()
+	flushHanRun()
+	return
+}
+
+func isHanTerm(runes []rune) bool {
+	return len(runes) > 0 && unicode.Is(unicode.Han, runes[0])
+}
+
+// editDistance returns the optimal string alignment distance of the two terms, which counts
+// the transposition of two adjacent characters as one edit, e.g. "kafak" to "kafka"
+func editDistance(a, b []rune) int {
+	distances := make([][]int, len(a)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(b)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(a)][len(b)]
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+package retrieval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "latin words", text: "Kafka Consumer-Lag", want: []string{"kafka", "consumer", "lag"}},
+		{name: "digits", text: "p99 latency", want: []string{"p99", "latency"}},
+		{name: "chinese bigrams", text: "订单延迟", want: []string{"订", "订单", "单", "单延", "延", "延迟", "迟"}},
+		{name: "mixed", text: "gateway错误率", want: []string{"gateway", "错", "错误", "误", "误率", "率"}},
+		{name: "punctuations only", text: " -_/ ", want: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Tokenize(testCase.text); !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", testCase.text, got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{a: "kafka", b: "kafka", want: 0},
+		{a: "kafak", b: "kafka", want: 1},
+		{a: "kafk", b: "kafka", want: 1},
+		{a: "redis", b: "mysql", want: 5},
+		{a: "", b: "abc", want: 3},
+	}
+	for _, testCase := range testCases {
+		if got := editDistance([]rune(testCase.a), []rune(testCase.b)); got != testCase.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", testCase.a, testCase.b, got, testCase.want)
+		}
+	}
+}
+
+func TestLexicalIndexSearch(t *testing.T) {
+	idx := NewLexicalIndex([]LexicalDocument{
+		{Id: "kafka", Fields: []LexicalField{{Text: "Kafka Overview", Weight: 3}, {Text: "Middleware", Weight: 1}}},
+		{Id: "redis", Fields: []LexicalField{{Text: "Redis Overview", Weight: 3}, {Text: "Middleware", Weight: 1}}},
+		{Id: "order", Fields: []LexicalField{{Text: "订单服务延迟", Weight: 3}, {Text: "Business", Weight: 1}}},
+		// the folder is weighted lower than the title
+		{Id: "folder", Fields: []LexicalField{{Text: "Node", Weight: 3}, {Text: "Kafka", Weight: 1}}},
+	})
+	testCases := []struct {
+		name      string
+		query     string
+		topK      int
+		wantIds   []string
+		wantTerms []string
+	}{
+		{name: "exact term", query: "kafka", topK: 10, wantIds: []string{"kafka", "folder"}, wantTerms: []string{"kafka"}},
+		{name: "typo", query: "kafak lag", topK: 10, wantIds: []string{"kafka", "folder"}, wantTerms: []string{"kafka"}},
+		{name: "prefix", query: "red", topK: 10, wantIds: []string{"redis"}, wantTerms: []string{"redis"}},
+		{name: "chinese", query: "订单延迟", topK: 10, wantIds: []string{"order"}},
+		{name: "topK", query: "overview", topK: 1, wantIds: []string{"kafka"}},
+		{name: "short terms are not fuzzy matched", query: "ka", topK: 10, wantIds: nil},
+		{name: "no match", query: "mysql", topK: 10, wantIds: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			results := idx.Search(testCase.query, testCase.topK)
+			ids := make([]string, 0, len(results))
+			for _, result := range results {
+				ids = append(ids, result.Id)
+			}
+			if len(ids) != len(testCase.wantIds) || (len(ids) > 0 && !reflect.DeepEqual(ids, testCase.wantIds)) {
+				t.Fatalf("Search(%q) = %v, want %v", testCase.query, ids, testCase.wantIds)
+			}
+			if testCase.wantTerms != nil && !reflect.DeepEqual(results[0].MatchedTerms, testCase.wantTerms) {
+				t.Errorf("matched terms = %v, want %v", results[0].MatchedTerms, testCase.wantTerms)
+			}
+		})
+	}
+}
+
+func TestLexicalIndexSearchEmpty(t *testing.T) {
+	if results := NewLexicalIndex(nil).Search("kafka", 10); len(results) != 0 {
+		t.Errorf("results of the empty index = %v", results)
+	}
+}
@@ -0,0 +1,155 @@
+package retrieval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func newPanelDocument(dashboardUid string, panelId int, title, text string) Document {
+	document := NewDocument(dashboardUid, panelId, text)
+	document.Title = title
+	return document
+}
+
+func noEmbed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for index := range vectors {
+		vectors[index] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+func TestSearchPanelsLexically(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "index.json"), "test")
+	documents := []Document{
+		NewDocument("checkout", 0, "Dashboard: Checkout"),
+		newPanelDocument("checkout", 1, "Requests", "Dashboard: Checkout\nPanel: Requests\nQueries: sum(rate(http_requests_total[5m]))"),
+		newPanelDocument("checkout", 2, "P99 Latency", "Dashboard: Checkout\nPanel: P99 Latency\nQueries: histogram_quantile(0.99, http_duration_bucket)"),
+		// the panel of a dashboard whose title does not mention the latency
+		newPanelDocument("gateway", 7, "Upstream Latency", "Dashboard: Gateway\nPanel: Upstream Latency\nDescription: p99 of the upstream"),
+		newPanelDocument("node", 3, "CPU Usage", "Dashboard: Node Exporter\nPanel: CPU Usage"),
+	}
+	if _, _, err := idx.Update(context.Background(), documents, nil, noEmbed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	testCases := []struct {
+		name      string
+		query     string
+		wantFirst string
+		wantIds   []string
+	}{
+		{name: "panel title", query: "cpu usage", wantFirst: "node/3", wantIds: []string{"node/3"}},
+		{name: "across dashboards", query: "p99 latency", wantFirst: "checkout/2", wantIds: []string{"checkout/2", "gateway/7"}},
+		{name: "query expression", query: "http_requests_total", wantFirst: "checkout/1", wantIds: []string{"checkout/1"}},
+		{name: "no match", query: "disk", wantIds: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			results := idx.SearchPanelsLexically(testCase.query, 10)
+			ids := make(map[string]bool)
+			for _, result := range results {
+				if result.Document.PanelId == 0 {
+					t.Errorf("dashboard document %s returned", result.Document.Id)
+				}
+				ids[result.Document.Id] = true
+			}
+			for _, id := range testCase.wantIds {
+				if !ids[id] {
+					t.Errorf("panel %s not found in %v", id, ids)
+				}
+			}
+			if testCase.wantFirst != "" && (len(results) == 0 || results[0].Document.Id != testCase.wantFirst) {
+				t.Errorf("first panel = %v, want %s", results, testCase.wantFirst)
+			}
+			if len(testCase.wantIds) == 0 && len(results) > 0 {
+				t.Errorf("unexpected results %v", results)
+			}
+		})
+	}
+}
+
+func TestSearchPanelsLexicallyAfterLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	idx := NewIndex(path, "test")
+	documents := []Document{newPanelDocument("checkout", 2, "P99 Latency", "Dashboard: Checkout\nPanel: P99 Latency")}
+	if _, _, err := idx.Update(context.Background(), documents, nil, noEmbed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatalf("save index err: %v", err)
+	}
+	loaded := NewIndex(path, "test")
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("load index err: %v", err)
+	}
+	if results := loaded.SearchPanelsLexically("latency", 10); len(results) != 1 {
+		t.Errorf("results = %v, want the loaded panel", results)
+	}
+}
+
+func TestSearchPanels(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "index.json"), "test")
+	documents := []Document{
+		NewDocument("checkout", 0, "dashboard"),
+		NewDocument("checkout", 1, "panel 1"),
+		NewDocument("checkout", 2, "panel 2"),
+	}
+	vectors := map[string][]float32{
+		"dashboard": {1, 0},
+		"panel 1":   {0.8, 0.2},
+		"panel 2":   {0, 1},
+	}
+	embed := func(_ context.Context, texts []string) ([][]float32, error) {
+		result := make([][]float32, 0, len(texts))
+		for _, text := range texts {
+			result = append(result, vectors[text])
+		}
+		return result, nil
+	}
+	if _, _, err := idx.Update(context.Background(), documents, nil, embed); err != nil {
+		t.Fatalf("update index err: %v", err)
+	}
+	results := idx.SearchPanels([]float32{1, 0}, 10)
+	if len(results) != 2 || results[0].Document.Id != "checkout/1" {
+		t.Errorf("results = %v, want the panels ranked by similarity", results)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	documents := make(map[string]*Document)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		documents[id] = &Document{Id: id}
+	}
+	ranking := func(ids ...string) (results []SearchResult) {
+		for _, id := range ids {
+			results = append(results, SearchResult{Document: documents[id]})
+		}
+		return
+	}
+	testCases := []struct {
+		name     string
+		rankings [][]SearchResult
+		topK     int
+		want     []string
+	}{
+		{name: "single ranking", rankings: [][]SearchResult{ranking("a", "b", "c")}, topK: 10, want: []string{"a", "b", "c"}},
+		{name: "found by both first", rankings: [][]SearchResult{ranking("a", "b", "c"), ranking("d", "c")}, topK: 10,
+			want: []string{"c", "a", "d", "b"}},
+		{name: "topK", rankings: [][]SearchResult{ranking("a", "b"), ranking("b", "a")}, topK: 1, want: []string{"a"}},
+		{name: "empty", rankings: [][]SearchResult{nil, nil}, topK: 10, want: nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			results := fuseRankings(testCase.rankings, testCase.topK)
+			if len(results) != len(testCase.want) {
+				t.Fatalf("results = %v, want %v", results, testCase.want)
+			}
+			for index, result := range results {
+				if result.Document.Id != testCase.want[index] {
+					t.Errorf("result %d = %s, want %s", index, result.Document.Id, testCase.want[index])
+				}
+			}
+		})
+	}
+}